@@ -13,6 +13,7 @@ import (
 	"github.com/tonytcb/party-invite/pkg/infrastructure/config"
 	"github.com/tonytcb/party-invite/pkg/infrastructure/customerfile"
 	"github.com/tonytcb/party-invite/pkg/infrastructure/customernotify"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/datasetstore"
 	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
 	"github.com/tonytcb/party-invite/pkg/usecase"
 )
@@ -31,15 +32,43 @@ func main() {
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
+	datasetRepository, err := newDatasetRepository(cfg)
+	if err != nil {
+		log.Fatalf("error to build datasets repository: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	responsesCache, err := newResponsesCache(ctx, log, cfg)
+	if err != nil {
+		log.Fatalf("error to build responses cache: %v", err)
+	}
+
 	var (
-		filterCustomers = http.NewFilterCustomersHandler(
+		parsers = http.CustomersFileParsers{
+			http.TXTFileExtension:     customerfile.NewCustomersFileParser(),
+			http.CSVFileExtension:     customerfile.NewCSVCustomersFileParser(),
+			http.GeoJSONFileExtension: customerfile.NewGeoJSONCustomersFileParser(),
+		}
+		filterCustomersUsecase = usecase.NewFilterCustomers(log, customernotify.NewStdOutNotifier(log), cfg.FilterWorkers)
+		queryDataset           = usecase.NewQueryDataset(log, datasetRepository, cfg.DatasetMaxIndexes)
+		filterCustomers        = http.NewFilterCustomersHandler(
 			log,
 			cfg,
-			customerfile.NewCustomersFileParser(),
-			usecase.NewFilterCustomers(log, customernotify.NewStdOutNotifier(log)),
-			cache.NewInMemoryFilterCustomersCache(log),
+			parsers,
+			filterCustomersUsecase,
+			responsesCache,
 		)
-		httpServer = http.NewServer(log, filterCustomers)
+		datasets = http.NewDatasetsHandler(
+			log,
+			cfg,
+			parsers,
+			usecase.NewDatasets(log, datasetRepository, queryDataset),
+			queryDataset,
+			filterCustomersUsecase,
+		)
+		httpServer = http.NewServer(log, filterCustomers, datasets)
 	)
 
 	if err = httpServer.Start(cfg.HTTPPort); err != nil {
@@ -55,6 +84,52 @@ func main() {
 	log.Infof("Shutting down application %s", cfg.AppName)
 }
 
+// newDatasetRepository stores the datasets as JSON files on DATASETS_DIR, or in memory when it's not defined.
+func newDatasetRepository(cfg *config.Config) (usecase.DatasetRepository, error) {
+	if cfg.DatasetsDir == "" {
+		return datasetstore.NewInMemoryDatasetRepository(), nil
+	}
+
+	return datasetstore.NewJSONFileDatasetRepository(cfg.DatasetsDir)
+}
+
+// newResponsesCache stores the responses on the Redis server of CACHE_REDIS_ADDR, under keys telling apart the
+// instances configured differently, as files on CACHE_DIR, or in memory when neither is defined. The local caches drop their expired responses every CACHE_JANITOR_INTERVAL until the
+// context is done, while the Redis server expires them on its own.
+func newResponsesCache(ctx context.Context, log logger.Logger, cfg *config.Config) (http.FilterCustomersCache, error) {
+	if cfg.CacheRedisAddr != "" {
+		return cache.NewRedisFilterCustomersCache(
+			log,
+			cfg.CacheRedisAddr,
+			cfg.AppName+":"+cfg.ResponsesFingerprint(),
+			cfg.CacheTTL,
+			cfg.CacheRedisTimeout,
+		), nil
+	}
+
+	if cfg.CacheDir == "" {
+		responsesCache := cache.NewInMemoryFilterCustomersCache(log, cfg.CacheMaxEntries, cfg.CacheMaxBytes, cfg.CacheTTL)
+		responsesCache.StartJanitor(ctx, cfg.CacheJanitorInterval)
+
+		return responsesCache, nil
+	}
+
+	responsesCache, err := cache.NewFileSystemFilterCustomersCache(
+		log,
+		cfg.CacheDir,
+		cfg.CacheMaxEntries,
+		cfg.CacheMaxBytes,
+		cfg.CacheTTL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	responsesCache.StartJanitor(ctx, cfg.CacheJanitorInterval)
+
+	return responsesCache, nil
+}
+
 func loadConfig() (*config.Config, error) {
 	currentDir, err := os.Getwd()
 	if err != nil {
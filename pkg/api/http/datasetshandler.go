@@ -0,0 +1,296 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/config"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+const (
+	datasetsPath       = "/datasets"
+	datasetNearbyRoute = "nearby"
+)
+
+type DatasetsUsecase interface {
+	Create(context.Context, *domain.Dataset) error
+	Get(context.Context, string) (*domain.Dataset, error)
+	Delete(context.Context, string) error
+}
+
+type QueryDatasetUsecase interface {
+	Candidates(
+		ctx context.Context,
+		datasetID string,
+		baseLocation *domain.Coordinate,
+		radius decimal.Decimal,
+		nearest int,
+		duplicatePolicy domain.DuplicatePolicy,
+	) (domain.Customers, []int, error)
+}
+
+// DatasetsHandler serves the customers lists uploaded once to be queried many times:
+//
+//	POST   /datasets              uploads and parses a file, answering its metadata
+//	GET    /datasets/{id}         answers the metadata of a dataset
+//	DELETE /datasets/{id}         deletes a dataset
+//	GET    /datasets/{id}/nearby  filters the customers of a dataset, like /filter-customers
+//
+// The datasets are stored by the usecase, which answers domain.ErrNotFound for unknown IDs. Only the customers the
+// query may accept, as told by the spatial index of the dataset, are filtered.
+type DatasetsHandler struct {
+	log logger.Logger
+	cfg *config.Config
+
+	parsers  CustomersFileParsers
+	datasets DatasetsUsecase
+	query    QueryDatasetUsecase
+	filter   FilterCustomersUsecase
+}
+
+func NewDatasetsHandler(
+	log logger.Logger,
+	cfg *config.Config,
+	parsers CustomersFileParsers,
+	datasets DatasetsUsecase,
+	query QueryDatasetUsecase,
+	filter FilterCustomersUsecase,
+) *DatasetsHandler {
+	return &DatasetsHandler{log: log, cfg: cfg, parsers: parsers, datasets: datasets, query: query, filter: filter}
+}
+
+// Handle routes the request by its path and method.
+func (h *DatasetsHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", jsonContentType)
+
+	var (
+		correlationID = uuid.NewString()
+		ctx           = context.WithValue(r.Context(), config.CorrelationIDKeyName, correlationID)
+		segments      = strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, datasetsPath), "/"), "/")
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, timeoutDefault)
+	defer cancel()
+
+	switch {
+	case len(segments) == 1 && segments[0] == "":
+		h.allowMethod(w, r, http.MethodPost, func() { h.create(ctx, w, r) })
+
+	case len(segments) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			h.get(ctx, w, segments[0])
+		case http.MethodDelete:
+			h.delete(ctx, w, segments[0])
+		default:
+			newHTTPError(nil, "", http.StatusMethodNotAllowed).empty(w)
+		}
+
+	case len(segments) == 2 && segments[1] == datasetNearbyRoute: //nolint:gomnd // id and route
+		h.allowMethod(w, r, http.MethodGet, func() { h.nearby(ctx, w, r, segments[0]) })
+
+	default:
+		newHTTPError(nil, "", http.StatusNotFound).empty(w)
+	}
+}
+
+func (h *DatasetsHandler) allowMethod(w http.ResponseWriter, r *http.Request, method string, handle func()) {
+	if r.Method != method {
+		newHTTPError(nil, "", http.StatusMethodNotAllowed).empty(w)
+		return
+	}
+
+	handle()
+}
+
+// create parses the uploaded file on the informed mode, strict by default, storing all its customers. Duplicated
+// customers are kept, to be resolved by the policy of each query.
+func (h *DatasetsHandler) create(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var log = h.log.FromContext(ctx)
+
+	if err := r.ParseMultipartForm(maximumFileUploadSize); err != nil {
+		newHTTPError(err, "error to set max upload file", http.StatusInternalServerError).json(w)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		newHTTPError(err, "error to read uploaded file", http.StatusBadRequest).json(w)
+		return
+	}
+	defer func() {
+		if err = file.Close(); err != nil {
+			log.Errorf("Error to close uploaded file, err=%v", err)
+		}
+	}()
+
+	log.Infof("Creating dataset, filename=%s filesize=%d", header.Filename, header.Size)
+
+	parser, ok := h.parsers.parserFor(header)
+	if !ok {
+		newHTTPError(nil, "invalid '"+filepath.Ext(header.Filename)+"' file extension", http.StatusBadRequest).json(w)
+		return
+	}
+
+	var parseMode = domain.ParseModeStrict
+
+	if mode := strings.TrimSpace(r.FormValue(modeParam)); mode != "" {
+		if parseMode, err = domain.NewParseMode(mode); err != nil {
+			newHTTPError(err, "invalid request parameters", errToStatusCode(err)).json(w)
+			return
+		}
+	}
+
+	var (
+		customers = make(chan domain.Customer)
+		dataset   = &domain.Dataset{FileName: header.Filename, Customers: make(domain.Customers, 0)}
+		parseErr  = make(chan error, 1)
+	)
+
+	go func() {
+		rejected, err := parser.Stream(ctx, file, parseMode, customers)
+		if parseMode == domain.ParseModeLenient {
+			dataset.Rejected = append(domain.RejectedLines{}, rejected...)
+		}
+
+		parseErr <- err
+	}()
+
+	for customer := range customers {
+		dataset.Customers = append(dataset.Customers, customer)
+	}
+
+	if err = <-parseErr; err != nil {
+		newHTTPError(err, "error to parse input file", errToStatusCode(err)).json(w)
+		return
+	}
+
+	if err = h.datasets.Create(ctx, dataset); err != nil {
+		newHTTPError(err, "error to create dataset", errToStatusCode(err)).json(w)
+		return
+	}
+
+	h.writeDataset(w, dataset, http.StatusCreated)
+}
+
+func (h *DatasetsHandler) get(ctx context.Context, w http.ResponseWriter, id string) {
+	dataset, err := h.datasets.Get(ctx, id)
+	if err != nil {
+		newHTTPError(err, "error to get dataset", errToStatusCode(err)).json(w)
+		return
+	}
+
+	h.writeDataset(w, dataset, http.StatusOK)
+}
+
+func (h *DatasetsHandler) delete(ctx context.Context, w http.ResponseWriter, id string) {
+	if err := h.datasets.Delete(ctx, id); err != nil {
+		newHTTPError(err, "error to delete dataset", errToStatusCode(err)).json(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// nearby filters the customers of the dataset by the same query parameters of /filter-customers, except the parse
+// mode, as the dataset was already parsed. Only the candidates to the query are filtered, the ones within the radius,
+// or the outer band, among the nearest ones, or every customer on geofence mode, while the duplicated IDs are the
+// ones of the whole dataset.
+func (h *DatasetsHandler) nearby(ctx context.Context, w http.ResponseWriter, r *http.Request, id string) {
+	var log = h.log.FromContext(ctx)
+
+	params, err := parseFilterCustomersParams(r, h.cfg)
+	if err != nil {
+		newHTTPError(err, "invalid request parameters", errToStatusCode(err)).json(w)
+		return
+	}
+
+	var radius = params.radius
+	if params.bands != nil {
+		radius = params.bands.Outer()
+	}
+
+	candidates, duplicatedIDs, err := h.query.Candidates(
+		ctx,
+		id,
+		params.baseLocation,
+		radius,
+		params.nearest,
+		params.duplicatePolicy,
+	)
+	if err != nil {
+		newHTTPError(err, "error to get dataset", errToStatusCode(err)).json(w)
+		return
+	}
+
+	var calculator = params.distanceAlgorithm.Calculator()
+	if h.cfg.UseFastDistance(len(candidates)) {
+		log.Infof("Using the fast distance calculation, customers=%d", len(candidates))
+		calculator = params.distanceAlgorithm.FastCalculator()
+	}
+
+	output, err := filterByParams(ctx, h.filter, streamDatasetCustomers(ctx, candidates), params, calculator)
+	if err != nil {
+		newHTTPError(err, "error to filter customers by location", errToStatusCode(err)).json(w)
+		return
+	}
+
+	h.filter.Notify(ctx, output.customers)
+
+	output.duplicatedIDs = duplicatedIDs
+	output.parseMode = domain.ParseModeStrict
+	output.distancePrecision = h.cfg.DistancePrecision
+
+	response, err := params.encoder.Encode(output)
+	if err != nil {
+		newHTTPError(err, "error to build response output", http.StatusServiceUnavailable).json(w)
+		return
+	}
+
+	log.Infof("Filtered dataset customers, dataset-id=%s duplicated=%d output=%d", id, len(output.duplicatedIDs), len(output.customers))
+
+	if err = writeCustomersResponse(w, params.encoder.ContentType(), output.reportHeaders(), response); err != nil {
+		newHTTPError(err, "", http.StatusInternalServerError).empty(w)
+	}
+}
+
+func (h *DatasetsHandler) writeDataset(w http.ResponseWriter, dataset *domain.Dataset, code int) {
+	response, err := datasetToJSONOutput(dataset)
+	if err != nil {
+		newHTTPError(err, "error to build response output", http.StatusServiceUnavailable).json(w)
+		return
+	}
+
+	w.WriteHeader(code)
+
+	if _, err = w.Write(response); err != nil {
+		h.log.Errorf("Error to write response, err=%v", err)
+	}
+}
+
+// streamDatasetCustomers sends the customers through the returned channel, closing it once they're all sent or the
+// context is done.
+func streamDatasetCustomers(ctx context.Context, customers domain.Customers) <-chan domain.Customer {
+	var ch = make(chan domain.Customer)
+
+	go func() {
+		defer close(ch)
+
+		for _, customer := range customers {
+			select {
+			case ch <- customer:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
@@ -0,0 +1,204 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/customerfile"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/customernotify"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/datasetstore"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+	"github.com/tonytcb/party-invite/pkg/usecase"
+)
+
+// TestDatasetsAPI start up the http handler with real dependencies to assert a dataset lifecycle.
+func TestDatasetsAPI(t *testing.T) {
+	t.Parallel()
+
+	var log = logger.NewEmptyLogger()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("error to load configuration: %v", err)
+	}
+
+	var (
+		repository      = datasetstore.NewInMemoryDatasetRepository()
+		queryDataset    = usecase.NewQueryDataset(log, repository, 16)
+		datasetsHandler = NewDatasetsHandler(
+			log,
+			cfg,
+			CustomersFileParsers{
+				TXTFileExtension:     customerfile.NewCustomersFileParser(),
+				CSVFileExtension:     customerfile.NewCSVCustomersFileParser(),
+				GeoJSONFileExtension: customerfile.NewGeoJSONCustomersFileParser(),
+			},
+			usecase.NewDatasets(log, repository, queryDataset),
+			queryDataset,
+			usecase.NewFilterCustomers(log, customernotify.NewStdOutNotifier(log), cfg.FilterWorkers),
+		)
+	)
+
+	serve := func(r *http.Request) (int, string) {
+		w := httptest.NewRecorder()
+
+		datasetsHandler.Handle(w, r)
+
+		httpResponse := w.Result()
+		defer httpResponse.Body.Close()
+
+		bytesResponse, err := io.ReadAll(httpResponse.Body)
+		if err != nil {
+			t.Fatal("failed to read buffer response body")
+		}
+
+		return httpResponse.StatusCode, string(bytesResponse)
+	}
+
+	for _, fileName := range []string{"customers.txt", "customers.csv", "customers.geojson"} {
+		fileName := fileName
+
+		t.Run(fileName, func(t *testing.T) {
+			postRequest, err := newRequestWithFile(http.MethodPost, "/datasets", "file", fileName)
+			if err != nil {
+				t.Fatal("failed to create valid request")
+			}
+
+			statusCode, body := serve(postRequest)
+			assert.Equal(t, http.StatusCreated, statusCode, body)
+
+			var created datasetMetadata
+			if err = json.Unmarshal([]byte(body), &created); err != nil {
+				t.Fatalf("failed to decode response body: %s", body)
+			}
+
+			assert.NotEmpty(t, created.ID)
+			assert.Equal(t, fileName, created.FileName)
+			assert.Equal(t, 32, created.Customers)
+			assert.Nil(t, created.Rejected)
+
+			statusCode, body = serve(httptest.NewRequest(http.MethodGet, "/datasets/"+created.ID, nil))
+			assert.Equal(t, http.StatusOK, statusCode)
+			assert.Contains(t, body, `"id":"`+created.ID+`"`)
+
+			statusCode, body = serve(httptest.NewRequest(http.MethodGet, "/datasets/"+created.ID+"/nearby?office=dublin&radius_km=50", nil))
+			assert.Equal(t, http.StatusOK, statusCode)
+			assert.Equal(t, `[{"id":4,"name":"Ian Kehoe","distance_km":10.567},{"id":5,"name":"Nora Dempsey","distance_km":23.287},{"id":6,"name":"Theresa Enright","distance_km":24.085},{"id":11,"name":"Richard Finnegan","distance_km":38.138},{"id":12,"name":"Christina McArdle","distance_km":41.769},{"id":15,"name":"Michael Ahearn","distance_km":43.722},{"id":31,"name":"Alan Behan","distance_km":44.291},{"id":39,"name":"Lisa Ahearn","distance_km":38.358}]`, body)
+
+			statusCode, body = serve(httptest.NewRequest(http.MethodGet, "/datasets/"+created.ID+"/nearby?office=dublin&nearest=3", nil))
+			assert.Equal(t, http.StatusOK, statusCode)
+			assert.Equal(t, `[{"id":4,"name":"Ian Kehoe","distance_km":10.567},{"id":5,"name":"Nora Dempsey","distance_km":23.287},{"id":6,"name":"Theresa Enright","distance_km":24.085}]`, body)
+
+			var geofence = url.QueryEscape("POLYGON ((-6.5 53.0, -6.0 53.0, -6.0 53.5, -6.5 53.5, -6.5 53.0))")
+
+			statusCode, body = serve(httptest.NewRequest(http.MethodGet, "/datasets/"+created.ID+"/nearby?geofence="+geofence, nil))
+			assert.Equal(t, http.StatusOK, statusCode)
+			assert.Equal(t, `[{"id":4,"name":"Ian Kehoe","distance_km":10.567},{"id":5,"name":"Nora Dempsey","distance_km":23.287},{"id":6,"name":"Theresa Enright","distance_km":24.085},{"id":11,"name":"Richard Finnegan","distance_km":38.138},{"id":39,"name":"Lisa Ahearn","distance_km":38.358}]`, body)
+
+			statusCode, body = serve(httptest.NewRequest(http.MethodGet, "/datasets/"+created.ID+"/nearby?bands=15,25,40&order_by=distance", nil))
+			assert.Equal(t, http.StatusOK, statusCode)
+			assert.Equal(t, `{"bands":[`+
+				`{"from_km":0,"to_km":15,"count":1,"customers":[{"id":4,"name":"Ian Kehoe","distance_km":10.567}]},`+
+				`{"from_km":15,"to_km":25,"count":2,"customers":[{"id":5,"name":"Nora Dempsey","distance_km":23.287},{"id":6,"name":"Theresa Enright","distance_km":24.085}]},`+
+				`{"from_km":25,"to_km":40,"count":2,"customers":[{"id":11,"name":"Richard Finnegan","distance_km":38.138},{"id":39,"name":"Lisa Ahearn","distance_km":38.358}]}]}`, body)
+
+			statusCode, _ = serve(httptest.NewRequest(http.MethodDelete, "/datasets/"+created.ID, nil))
+			assert.Equal(t, http.StatusNoContent, statusCode)
+
+			statusCode, body = serve(httptest.NewRequest(http.MethodGet, "/datasets/"+created.ID+"/nearby", nil))
+			assert.Equal(t, http.StatusNotFound, statusCode)
+			assert.Equal(t, `{"error":"error to get dataset: error to read dataset: dataset '`+created.ID+`' not found"}`, body)
+		})
+	}
+
+	t.Run("should resolve the duplicates of the whole dataset", func(t *testing.T) {
+		var (
+			near = func(latitude, longitude string) *domain.Coordinate {
+				location, err := domain.NewCoordinate(latitude, longitude)
+				if err != nil {
+					t.Fatal("failed to build coordinate")
+				}
+
+				return location
+			}
+			cork    = near("51.897233", "-8.470456")
+			dataset = &domain.Dataset{
+				ID: "duplicated",
+				Customers: domain.Customers{
+					domain.NewCustomer(1, "Alice Near", near("53.35", "-6.25")),
+					domain.NewCustomer(2, "Bob Near", near("53.4", "-6.3")),
+					domain.NewCustomer(3, "Carol Cork", cork),
+					domain.NewCustomer(1, "Alice Cork", cork),
+					domain.NewCustomer(3, "Carol Near", near("53.3", "-6.2")),
+				},
+			}
+		)
+
+		if err := repository.Save(context.Background(), dataset); err != nil {
+			t.Fatal("failed to save dataset")
+		}
+
+		for _, tt := range []struct {
+			duplicates string
+			want       []string
+		}{
+			{duplicates: "keep_first", want: []string{"Alice Near", "Bob Near"}},
+			{duplicates: "keep_last", want: []string{"Bob Near", "Carol Near"}},
+			{duplicates: "keep_nearest", want: []string{"Alice Near", "Bob Near", "Carol Near"}},
+		} {
+			for _, query := range []string{"radius_km=50", "nearest=3&radius_km=50"} {
+				var w = httptest.NewRecorder()
+
+				datasetsHandler.Handle(w, httptest.NewRequest(
+					http.MethodGet,
+					"/datasets/duplicated/nearby?office=dublin&order_by=name&"+query+"&duplicates="+tt.duplicates,
+					nil,
+				))
+
+				var got []struct {
+					Name string `json:"name"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("failed to decode response body: %s", w.Body.String())
+				}
+
+				var names = make([]string, 0, len(got))
+				for _, customer := range got {
+					names = append(names, customer.Name)
+				}
+
+				assert.Equal(t, http.StatusOK, w.Code, tt.duplicates)
+				assert.Equal(t, tt.want, names, tt.duplicates)
+				assert.Equal(t, "1,3", w.Header().Get("X-Duplicated-User-Ids"), tt.duplicates)
+			}
+		}
+
+		var w = httptest.NewRecorder()
+
+		datasetsHandler.Handle(w, httptest.NewRequest(
+			http.MethodGet, "/datasets/duplicated/nearby?office=dublin&radius_km=50&duplicates=reject", nil,
+		))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "user_id 1, 3 found more than once")
+	})
+
+	t.Run("should reject unknown routes and methods", func(t *testing.T) {
+		statusCode, _ := serve(httptest.NewRequest(http.MethodGet, "/datasets", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, statusCode)
+
+		statusCode, _ = serve(httptest.NewRequest(http.MethodPut, "/datasets/some-id", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, statusCode)
+
+		statusCode, _ = serve(httptest.NewRequest(http.MethodGet, "/datasets/some-id/unknown", nil))
+		assert.Equal(t, http.StatusNotFound, statusCode)
+	})
+}
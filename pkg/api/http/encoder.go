@@ -0,0 +1,264 @@
+package http
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+const (
+	jsonContentType      = "application/json"
+	jsonLinesContentType = "application/x-ndjson"
+	csvContentType       = "text/csv"
+	textContentType      = "text/plain"
+	geoJSONContentType   = "application/geo+json"
+
+	anyMediaType = "*/*"
+
+	duplicatedUserIDsHeader      = "X-Duplicated-User-Ids"
+	duplicatedUserIDsCountHeader = "X-Duplicated-User-Ids-Count"
+	rejectedLinesHeader          = "X-Rejected-Lines"
+	rejectedLinesCountHeader     = "X-Rejected-Lines-Count"
+
+	// maxReportedHeaderValues is how many duplicated IDs or rejected line numbers are listed on a report header, so
+	// it stays within the header size limits of clients and proxies. The count headers still tell them all, while
+	// JSON reports list them all.
+	maxReportedHeaderValues = 100
+)
+
+var errNotAcceptable = errors.New("none of the accepted content types is supported")
+
+// filterCustomersOutput holds everything a response may present about a filter customers request.
+type filterCustomersOutput struct {
+	customers         domain.NearCustomers
+	bands             domain.DistanceBands     // nil unless on bands mode, when customers holds the ones of every band
+	offices           []domain.OfficeCustomers // nil unless on offices mode, when customers holds the ones of every office
+	parseMode         domain.ParseMode
+	report            bool // whether JSON responses are reports even on strict parse mode
+	rejected          domain.RejectedLines
+	duplicatedIDs     []int
+	baseLocation      *domain.Coordinate
+	officeName        string
+	radius            decimal.Decimal
+	distancePrecision int32
+}
+
+// lenientRejected returns the rejected lines on lenient parse mode, never nil, or nil on strict mode.
+func (o *filterCustomersOutput) lenientRejected() domain.RejectedLines {
+	if o.parseMode != domain.ParseModeLenient {
+		return nil
+	}
+
+	return append(domain.RejectedLines{}, o.rejected...)
+}
+
+// reportHeaders presents what was found while parsing the input file as response headers, so it's reported whatever
+// the content type, as not every one has room for it: the duplicated IDs, and the rejected line numbers on lenient
+// parse mode.
+func (o *filterCustomersOutput) reportHeaders() http.Header {
+	var header = http.Header{}
+
+	if len(o.duplicatedIDs) > 0 {
+		header.Set(duplicatedUserIDsHeader, joinReportedValues(o.duplicatedIDs))
+		header.Set(duplicatedUserIDsCountHeader, strconv.Itoa(len(o.duplicatedIDs)))
+	}
+
+	if rejected := o.lenientRejected(); len(rejected) > 0 {
+		var lines = make([]int, 0, len(rejected))
+		for _, line := range rejected {
+			lines = append(lines, line.Line)
+		}
+
+		header.Set(rejectedLinesHeader, joinReportedValues(lines))
+		header.Set(rejectedLinesCountHeader, strconv.Itoa(len(rejected)))
+	}
+
+	return header
+}
+
+// joinReportedValues joins up to maxReportedHeaderValues values by commas.
+func joinReportedValues(values []int) string {
+	if len(values) > maxReportedHeaderValues {
+		values = values[:maxReportedHeaderValues]
+	}
+
+	var joined = make([]string, 0, len(values))
+	for _, value := range values {
+		joined = append(joined, strconv.Itoa(value))
+	}
+
+	return strings.Join(joined, ",")
+}
+
+// customersEncoder encodes the filter customers output into a response body of a specific content type.
+type customersEncoder interface {
+	ContentType() string
+	Encode(output *filterCustomersOutput) ([]byte, error)
+}
+
+// customersEncoders lists the supported encoders, the first one being the default.
+var customersEncoders = []customersEncoder{
+	jsonEncoder{},
+	jsonLinesEncoder{},
+	csvEncoder{},
+	textEncoder{},
+	geoJSONEncoder{},
+}
+
+// bandsEncoders lists the encoders supporting the distance bands, the first one being the default.
+var bandsEncoders = []customersEncoder{
+	jsonEncoder{},
+}
+
+// officesEncoders lists the encoders supporting the customers grouped by office, the first one being the default.
+var officesEncoders = []customersEncoder{
+	jsonEncoder{},
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string {
+	return jsonContentType
+}
+
+// Encode presents only the customers list, unless on lenient parse mode or when the report is requested, when the
+// rejected lines, on lenient mode, and the warnings are reported too. The shape of the response depends only on the
+// request parameters, never on the uploaded file. On bands mode the bands are always reported, and so are the offices
+// on offices mode.
+func (jsonEncoder) Encode(output *filterCustomersOutput) ([]byte, error) {
+	rejected := output.lenientRejected()
+
+	if output.bands != nil {
+		return distanceBandsToJSONOutput(output.bands, rejected, output.duplicatedIDs, output.distancePrecision)
+	}
+
+	if output.offices != nil {
+		return officesToJSONOutput(output.offices, rejected, output.duplicatedIDs, output.distancePrecision)
+	}
+
+	if rejected == nil && !output.report {
+		return customersToJSONOutput(output.customers, output.distancePrecision)
+	}
+
+	return customersReportToJSONOutput(output.customers, rejected, output.duplicatedIDs, output.distancePrecision)
+}
+
+type jsonLinesEncoder struct{}
+
+func (jsonLinesEncoder) ContentType() string {
+	return jsonLinesContentType
+}
+
+func (jsonLinesEncoder) Encode(output *filterCustomersOutput) ([]byte, error) {
+	return customersToJSONLinesOutput(output.customers, output.distancePrecision)
+}
+
+type csvEncoder struct{}
+
+func (csvEncoder) ContentType() string {
+	return csvContentType
+}
+
+func (csvEncoder) Encode(output *filterCustomersOutput) ([]byte, error) {
+	return customersToCSVOutput(output.customers, output.distancePrecision)
+}
+
+type textEncoder struct{}
+
+func (textEncoder) ContentType() string {
+	return textContentType
+}
+
+func (textEncoder) Encode(output *filterCustomersOutput) ([]byte, error) {
+	return customersToTextOutput(output.customers, output.distancePrecision)
+}
+
+type geoJSONEncoder struct{}
+
+func (geoJSONEncoder) ContentType() string {
+	return geoJSONContentType
+}
+
+func (geoJSONEncoder) Encode(output *filterCustomersOutput) ([]byte, error) {
+	return customersToGeoJSONOutput(
+		output.customers,
+		output.lenientRejected(),
+		output.duplicatedIDs,
+		output.baseLocation,
+		output.officeName,
+		output.radius,
+		output.distancePrecision,
+	)
+}
+
+// negotiateEncoder chooses among the encoders honouring the Accept header media ranges and their quality values.
+// An empty Accept header means any content type is accepted, so the default encoder, the first one, is used.
+func negotiateEncoder(accept string, encoders []customersEncoder) (customersEncoder, error) {
+	if strings.TrimSpace(accept) == "" {
+		return encoders[0], nil
+	}
+
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+
+	var (
+		mediaRanges = make([]mediaRange, 0)
+		rejected    = make(map[string]bool)
+	)
+
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(value)
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		if quality <= 0 {
+			rejected[mediaType] = true // explicitly not acceptable
+			continue
+		}
+
+		mediaRanges = append(mediaRanges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+
+	sort.SliceStable(mediaRanges, func(i, j int) bool {
+		return mediaRanges[i].quality > mediaRanges[j].quality
+	})
+
+	for _, r := range mediaRanges {
+		for _, encoder := range encoders {
+			if !rejected[encoder.ContentType()] && mediaTypeMatches(r.mediaType, encoder.ContentType()) {
+				return encoder, nil
+			}
+		}
+	}
+
+	return nil, errNotAcceptable
+}
+
+// mediaTypeMatches reports whether a media range, like "text/*", matches the given content type.
+func mediaTypeMatches(mediaRange string, contentType string) bool {
+	if mediaRange == anyMediaType || mediaRange == contentType {
+		return true
+	}
+
+	rangeType, rangeSubtype, _ := strings.Cut(mediaRange, "/")
+	contentTypeType, _, _ := strings.Cut(contentType, "/")
+
+	return rangeSubtype == "*" && rangeType == contentTypeType
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+func Test_negotiateEncoder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		accept  string
+		want    customersEncoder
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "should default to json when Accept is empty",
+			accept:  "",
+			want:    jsonEncoder{},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should default to json when any content type is accepted",
+			accept:  "*/*",
+			want:    jsonEncoder{},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should choose csv",
+			accept:  "text/csv",
+			want:    csvEncoder{},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should choose json lines",
+			accept:  "application/x-ndjson",
+			want:    jsonLinesEncoder{},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should choose the supported content type with the highest quality",
+			accept:  "text/html, application/json;q=0.5, text/plain;q=0.8",
+			want:    textEncoder{},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should choose the first supported content type matching a wildcard subtype",
+			accept:  "text/*",
+			want:    csvEncoder{},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should ignore content types with zero quality",
+			accept:  "application/json;q=0, */*;q=0.1",
+			want:    jsonLinesEncoder{},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "should error when no content type is supported",
+			accept: "text/html, application/xml",
+			want:   nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, errNotAcceptable)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := negotiateEncoder(tt.accept, customersEncoders)
+
+			tt.wantErr(t, err)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_jsonEncoder_Encode(t *testing.T) {
+	t.Parallel()
+
+	var customers = domain.NearCustomers{
+		domain.NewNearCustomer(domain.NewCustomer(100, "Tony Tester", domain.DublinLocation), decimal.RequireFromString("5.12345")),
+	}
+
+	tests := []struct {
+		name   string
+		output *filterCustomersOutput
+		want   string
+	}{
+		{
+			name:   "should present a list on strict mode",
+			output: &filterCustomersOutput{customers: customers, parseMode: domain.ParseModeStrict},
+			want:   `[{"id":100,"name":"Tony Tester","distance_km":5.123}]`,
+		},
+		{
+			name: "should present a list on strict mode even when duplicated customers were found",
+			output: &filterCustomersOutput{
+				customers:     customers,
+				parseMode:     domain.ParseModeStrict,
+				duplicatedIDs: []int{100},
+			},
+			want: `[{"id":100,"name":"Tony Tester","distance_km":5.123}]`,
+		},
+		{
+			name: "should present a report with every duplicated ID on strict mode when requested",
+			output: &filterCustomersOutput{
+				customers:     customers,
+				parseMode:     domain.ParseModeStrict,
+				report:        true,
+				duplicatedIDs: []int{100, 101},
+			},
+			want: `{"customers":[{"id":100,"name":"Tony Tester","distance_km":5.123}],"warnings":{"duplicated_user_ids":[100,101]}}`,
+		},
+		{
+			name:   "should present a report on lenient mode even when nothing was found",
+			output: &filterCustomersOutput{customers: customers, parseMode: domain.ParseModeLenient},
+			want:   `{"customers":[{"id":100,"name":"Tony Tester","distance_km":5.123}],"rejected":[]}`,
+		},
+		{
+			name: "should present a report with the warnings on lenient mode",
+			output: &filterCustomersOutput{
+				customers:     customers,
+				parseMode:     domain.ParseModeLenient,
+				duplicatedIDs: []int{100},
+			},
+			want: `{"customers":[{"id":100,"name":"Tony Tester","distance_km":5.123}],"rejected":[],"warnings":{"duplicated_user_ids":[100]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			tt.output.distancePrecision = 3
+
+			got, err := jsonEncoder{}.Encode(tt.output)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_filterCustomersOutput_reportHeaders(t *testing.T) {
+	t.Parallel()
+
+	var manyIDs = make([]int, 0, maxReportedHeaderValues+1)
+	for id := 1; id <= maxReportedHeaderValues+1; id++ {
+		manyIDs = append(manyIDs, id)
+	}
+
+	tests := []struct {
+		name   string
+		output *filterCustomersOutput
+		want   http.Header
+	}{
+		{
+			name:   "should report nothing when nothing was found",
+			output: &filterCustomersOutput{parseMode: domain.ParseModeLenient},
+			want:   http.Header{},
+		},
+		{
+			name: "should report the duplicated IDs and the rejected lines on lenient mode",
+			output: &filterCustomersOutput{
+				parseMode:     domain.ParseModeLenient,
+				rejected:      domain.RejectedLines{{Line: 3}, {Line: 8}},
+				duplicatedIDs: []int{100, 200},
+			},
+			want: http.Header{
+				duplicatedUserIDsHeader:      {"100,200"},
+				duplicatedUserIDsCountHeader: {"2"},
+				rejectedLinesHeader:          {"3,8"},
+				rejectedLinesCountHeader:     {"2"},
+			},
+		},
+		{
+			name: "should not report the rejected lines on strict mode",
+			output: &filterCustomersOutput{
+				parseMode: domain.ParseModeStrict,
+				rejected:  domain.RejectedLines{{Line: 3}},
+			},
+			want: http.Header{},
+		},
+		{
+			name:   "should list only the first values, while counting them all",
+			output: &filterCustomersOutput{duplicatedIDs: manyIDs},
+			want: http.Header{
+				duplicatedUserIDsHeader:      {joinReportedValues(manyIDs[:maxReportedHeaderValues])},
+				duplicatedUserIDsCountHeader: {"101"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.output.reportHeaders())
+		})
+	}
+}
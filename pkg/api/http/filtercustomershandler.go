@@ -1,14 +1,21 @@
 package http
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/md5"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 
 	"github.com/tonytcb/party-invite/pkg/domain"
@@ -18,53 +25,109 @@ import (
 
 const (
 	timeoutDefault        = 30 * time.Second
-	txtFileExtension      = ".txt"
 	maximumFileUploadSize = 10 << 20 // 10mb
+
+	TXTFileExtension     = ".txt"
+	CSVFileExtension     = ".csv"
+	GeoJSONFileExtension = ".geojson"
 )
 
+// contentTypeFileExtensions maps the Content-Type of uploaded files to the extension of their format, used when
+// the file name has no known extension.
+var contentTypeFileExtensions = map[string]string{
+	"text/plain":           TXTFileExtension,
+	"application/jsonl":    TXTFileExtension,
+	"application/x-ndjson": TXTFileExtension,
+	"text/csv":             CSVFileExtension,
+	geoJSONContentType:     GeoJSONFileExtension,
+}
+
 //go:generate mockgen -source=filtercustomershandler.go -destination=mock_filtercustomers_test.go -package=http CustomersFileParser,FilterCustomersUsecase,FilterCustomersCache
 
+// CustomersFileParser streams the customers of a file through the channel, closing it once done.
 type CustomersFileParser interface {
-	Parse(context.Context, io.Reader) (domain.Customers, error)
+	Stream(context.Context, io.Reader, domain.ParseMode, chan<- domain.Customer) (domain.RejectedLines, error)
 }
 
 type FilterCustomersUsecase interface {
 	ByNearLocation(
 		ctx context.Context,
-		customers domain.Customers,
+		customers <-chan domain.Customer,
 		baseLocation *domain.Coordinate,
 		nearDistanceFilter decimal.Decimal,
 		orderBy domain.OrderBy,
-	) (domain.Customers, error)
+		duplicatePolicy domain.DuplicatePolicy,
+		calculator domain.DistanceCalculator,
+	) (domain.NearCustomers, []int, error)
+	WithinGeofence(
+		ctx context.Context,
+		customers <-chan domain.Customer,
+		geofence *domain.Geofence,
+		baseLocation *domain.Coordinate,
+		orderBy domain.OrderBy,
+		duplicatePolicy domain.DuplicatePolicy,
+		calculator domain.DistanceCalculator,
+	) (domain.NearCustomers, []int, error)
+	NearestToLocation(
+		ctx context.Context,
+		customers <-chan domain.Customer,
+		baseLocation *domain.Coordinate,
+		n int,
+		maxDistance decimal.Decimal,
+		orderBy domain.OrderBy,
+		duplicatePolicy domain.DuplicatePolicy,
+		calculator domain.DistanceCalculator,
+	) (domain.NearCustomers, []int, error)
+	ByDistanceBands(
+		ctx context.Context,
+		customers <-chan domain.Customer,
+		baseLocation *domain.Coordinate,
+		bands domain.DistanceBands,
+		orderBy domain.OrderBy,
+		duplicatePolicy domain.DuplicatePolicy,
+		calculator domain.DistanceCalculator,
+	) (domain.DistanceBands, []int, error)
+	ByNearestOffice(
+		ctx context.Context,
+		customers <-chan domain.Customer,
+		offices []domain.Office,
+		orderBy domain.OrderBy,
+		duplicatePolicy domain.DuplicatePolicy,
+		calculator domain.DistanceCalculator,
+	) ([]domain.OfficeCustomers, []int, error)
+	Notify(ctx context.Context, customers domain.NearCustomers)
 }
 
+// CustomersFileParsers maps a file extension to the parser able to read files in that format.
+type CustomersFileParsers map[string]CustomersFileParser
+
 type FilterCustomersCache interface {
-	Get(context.Context, []byte) ([]byte, error)
-	Save(context.Context, []byte, []byte) error
+	Get(context.Context, string) ([]byte, error)
+	Save(context.Context, string, []byte) error
 }
 
 type FilterCustomersHandler struct {
 	log logger.Logger
 	cfg *config.Config
 
-	parser CustomersFileParser
-	filter FilterCustomersUsecase
-	cache  FilterCustomersCache
+	parsers CustomersFileParsers
+	filter  FilterCustomersUsecase
+	cache   FilterCustomersCache
 }
 
 func NewFilterCustomersHandler(
 	log logger.Logger,
 	cfg *config.Config,
-	parser CustomersFileParser,
+	parsers CustomersFileParsers,
 	filter FilterCustomersUsecase,
 	cache FilterCustomersCache,
 ) *FilterCustomersHandler {
-	return &FilterCustomersHandler{log: log, cfg: cfg, parser: parser, filter: filter, cache: cache}
+	return &FilterCustomersHandler{log: log, cfg: cfg, parsers: parsers, filter: filter, cache: cache}
 }
 
 // Handle filters a list of customer given the input file.
 func (h *FilterCustomersHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	w.Header().Add("Content-Type", "application/json")
+	w.Header().Set("Content-Type", jsonContentType)
 
 	var (
 		correlationID = uuid.NewString()
@@ -98,60 +161,338 @@ func (h *FilterCustomersHandler) Handle(w http.ResponseWriter, r *http.Request)
 
 	log.Infof("Filtering customers, filename=%s filesize=%d", header.Filename, header.Size)
 
-	if ext := filepath.Ext(header.Filename); ext != txtFileExtension {
-		newHTTPError(nil, "invalid '"+ext+"' file extension", http.StatusBadRequest).json(w)
+	parser, ok := h.parsers.parserFor(header)
+	if !ok {
+		newHTTPError(nil, "invalid '"+filepath.Ext(header.Filename)+"' file extension", http.StatusBadRequest).json(w)
 		return
 	}
 
-	// to read the file twice, we need to duplicate it since it's a buffer
-	var tempBuf = &bytes.Buffer{}
-	var tempFile = io.TeeReader(file, tempBuf)
+	params, err := parseFilterCustomersParams(r, h.cfg)
+	if err != nil {
+		newHTTPError(err, "invalid request parameters", errToStatusCode(err)).json(w)
+		return
+	}
+
+	// the file is read twice, first to identify it and then to parse it, never holding it all in memory
+	fileHash, fileLines, err := hashFile(file)
+	if err != nil {
+		newHTTPError(err, "error to read uploaded file", http.StatusInternalServerError).json(w)
+		return
+	}
 
-	fileContents, _ := io.ReadAll(tempBuf)
-	//fileReader := io.NopCloser(bytes.NewReader(fileContents))
+	// the number of customers is only known once the file is parsed, so it's estimated by its number of lines
+	var calculator = params.distanceAlgorithm.Calculator()
+	if h.cfg.UseFastDistance(fileLines) {
+		log.Infof("Using the fast distance calculation, lines=%d", fileLines)
+		calculator = params.distanceAlgorithm.FastCalculator()
+	}
 
-	cachedResponse, err := h.cache.Get(ctx, fileContents)
+	var cacheKey = params.cacheKey(fileHash, calculator, h.cfg.DistancePrecision)
+
+	cachedResponse, err := h.cache.Get(ctx, cacheKey)
 	if err != nil {
 		newHTTPError(err, "error to load cache", errToStatusCode(err)).json(w)
 		return
 	}
 
 	if cachedResponse != nil {
-		w.Write(cachedResponse) //nolint:errcheck
+		cachedHeader, body, err := decodeCachedResponse(cachedResponse)
+		if err == nil {
+			writeCustomersResponse(w, params.encoder.ContentType(), cachedHeader, body) //nolint:errcheck
+			return
+		}
+
+		log.Errorf("Error to decode cached response, key=%s err=%v", cacheKey, err)
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		newHTTPError(err, "error to read uploaded file", http.StatusInternalServerError).json(w)
 		return
 	}
 
-	customers, err := h.parser.Parse(ctx, tempFile)
+	output, httpErr := h.parseAndFilter(ctx, parser, file, params, calculator)
+	if httpErr != nil {
+		httpErr.json(w)
+		return
+	}
+
+	response, err := params.encoder.Encode(output)
 	if err != nil {
-		newHTTPError(err, "error to parse input file", errToStatusCode(err)).json(w)
+		newHTTPError(err, "error to build response output", http.StatusServiceUnavailable).json(w)
 		return
 	}
 
-	filteredCustomers, err := h.filter.ByNearLocation(
+	log.Infof(
+		"Filtered customers length response: rejected=%d duplicated=%d output=%d",
+		len(output.rejected),
+		len(output.duplicatedIDs),
+		len(output.customers),
+	)
+
+	var reportHeader = output.reportHeaders()
+
+	if err = writeCustomersResponse(w, params.encoder.ContentType(), reportHeader, response); err != nil {
+		newHTTPError(err, "", http.StatusInternalServerError).empty(w)
+	}
+
+	if err = h.cache.Save(ctx, cacheKey, encodeCachedResponse(reportHeader, response)); err != nil {
+		log.Errorf("Error to store response on cache: %v", err)
+	}
+}
+
+// parseAndFilter streams the customers from the parser straight to the filter, so only the customers accepted by the
+// filter are held in memory. Parse errors take precedence over the filter ones, as the latter may be caused by the
+// former, and the accepted customers are only notified once the whole file was parsed, as a file failing on strict
+// mode isn't meant to invite anyone.
+func (h *FilterCustomersHandler) parseAndFilter(
+	ctx context.Context,
+	parser CustomersFileParser,
+	file io.Reader,
+	params *filterCustomersParams,
+	calculator domain.DistanceCalculator,
+) (*filterCustomersOutput, *httpError) {
+	type parseResult struct {
+		rejected domain.RejectedLines
+		err      error
+	}
+
+	var (
+		customers = make(chan domain.Customer)
+		parsed    = make(chan parseResult, 1)
+	)
+
+	// stops the parser whenever the filter returns without consuming all the customers
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+
+	go func() {
+		// a panicking parser fails the request instead of the whole server, its deferred close of the channel still
+		// releasing the filter
+		defer func() {
+			if p := recover(); p != nil {
+				parsed <- parseResult{err: errors.Errorf("panic while parsing file: %v", p)}
+			}
+		}()
+
+		rejected, err := parser.Stream(streamCtx, file, params.parseMode, customers)
+		parsed <- parseResult{rejected: rejected, err: err}
+	}()
+
+	output, filterErr := filterByParams(ctx, h.filter, customers, params, calculator)
+
+	cancelStream()
+	result := <-parsed
+
+	if result.err != nil {
+		return nil, newHTTPError(result.err, "error to parse input file", errToStatusCode(result.err))
+	}
+
+	if filterErr != nil {
+		return nil, newHTTPError(filterErr, "error to filter customers by location", errToStatusCode(filterErr))
+	}
+
+	h.filter.Notify(ctx, output.customers)
+
+	output.rejected = result.rejected
+	output.distancePrecision = h.cfg.DistancePrecision
+
+	return output, nil
+}
+
+// filterByParams returns the output presenting the customers within the distance bands on bands mode, the ones
+// assigned to every office on offices mode, the ones inside the geofence on geofence mode, the nearest ones on nearest
+// mode, or the ones within the radius otherwise.
+// The rejected lines and the distance precision are left to the caller.
+func filterByParams(
+	ctx context.Context,
+	filter FilterCustomersUsecase,
+	customers <-chan domain.Customer,
+	params *filterCustomersParams,
+	calculator domain.DistanceCalculator,
+) (*filterCustomersOutput, error) {
+	var (
+		output = &filterCustomersOutput{
+			parseMode:    params.parseMode,
+			report:       params.report,
+			baseLocation: params.baseLocation,
+			officeName:   params.officeName,
+			radius:       params.radius,
+		}
+		err error
+	)
+
+	if params.bands != nil {
+		output.bands, output.duplicatedIDs, err = filter.ByDistanceBands(
+			ctx,
+			customers,
+			params.baseLocation,
+			params.bands,
+			params.orderBy,
+			params.duplicatePolicy,
+			calculator,
+		)
+
+		for _, band := range output.bands {
+			output.customers = append(output.customers, band.Customers...)
+		}
+	} else if params.offices != nil {
+		output.offices, output.duplicatedIDs, err = filter.ByNearestOffice(
+			ctx,
+			customers,
+			params.offices,
+			params.orderBy,
+			params.duplicatePolicy,
+			calculator,
+		)
+
+		for _, group := range output.offices {
+			output.customers = append(output.customers, group.Customers...)
+		}
+	} else {
+		output.customers, output.duplicatedIDs, err = filterNearCustomers(ctx, filter, customers, params, calculator)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// filterNearCustomers returns the customers inside the geofence on geofence mode, the nearest ones on nearest mode,
+// or the ones within the radius otherwise.
+func filterNearCustomers(
+	ctx context.Context,
+	filter FilterCustomersUsecase,
+	customers <-chan domain.Customer,
+	params *filterCustomersParams,
+	calculator domain.DistanceCalculator,
+) (domain.NearCustomers, []int, error) {
+	if params.geofence != nil {
+		return filter.WithinGeofence(
+			ctx,
+			customers,
+			params.geofence,
+			params.baseLocation,
+			params.orderBy,
+			params.duplicatePolicy,
+			calculator,
+		)
+	}
+
+	if params.nearest > 0 {
+		return filter.NearestToLocation(
+			ctx,
+			customers,
+			params.baseLocation,
+			params.nearest,
+			params.radius,
+			params.orderBy,
+			params.duplicatePolicy,
+			calculator,
+		)
+	}
+
+	return filter.ByNearLocation(
 		ctx,
 		customers,
-		h.cfg.GetBaseLocation(),
-		decimal.NewFromInt32(h.cfg.LocationNearTo),
-		domain.OrderByCustomerID,
+		params.baseLocation,
+		params.radius,
+		params.orderBy,
+		params.duplicatePolicy,
+		calculator,
 	)
+}
+
+// writeCustomersResponse writes the encoded customers along with their report headers.
+func writeCustomersResponse(w http.ResponseWriter, contentType string, header http.Header, body []byte) error {
+	for key, values := range header {
+		w.Header()[key] = values
+	}
+
+	w.Header().Set("Content-Type", contentType)
+
+	_, err := w.Write(body)
+
+	return err //nolint:wrapcheck // as written by the response writer
+}
+
+// encodeCachedResponse prefixes the response body with its report headers, formatted like the MIME headers and
+// followed by a blank line, so they're cached along with it.
+func encodeCachedResponse(header http.Header, body []byte) []byte {
+	var buffer = &bytes.Buffer{}
+
+	header.Write(buffer)       //nolint:errcheck,gosec // writing to a buffer doesn't fail
+	buffer.WriteString("\r\n") //nolint:errcheck,gosec // writing to a buffer doesn't fail
+	buffer.Write(body)         //nolint:errcheck,gosec // writing to a buffer doesn't fail
+
+	return buffer.Bytes()
+}
+
+// decodeCachedResponse splits a cached response into its report headers and body.
+func decodeCachedResponse(cached []byte) (http.Header, []byte, error) {
+	var reader = bufio.NewReader(bytes.NewReader(cached))
+
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
 	if err != nil {
-		newHTTPError(err, "error to filter customers by location", errToStatusCode(err)).json(w)
-		return
+		return nil, nil, errors.Wrap(err, "error to read cached response headers")
 	}
 
-	response, err := customersToJSONOutput(filteredCustomers)
+	body, err := io.ReadAll(reader)
 	if err != nil {
-		newHTTPError(err, "error to build response output", http.StatusServiceUnavailable).json(w)
-		return
+		return nil, nil, errors.Wrap(err, "error to read cached response body")
 	}
 
-	log.Infof("Filtered customers length response: input=%d output=%d", len(customers), len(filteredCustomers))
+	return http.Header(header), body, nil
+}
 
-	if _, err = w.Write(response); err != nil {
-		newHTTPError(err, "", http.StatusInternalServerError).empty(w)
+// hashFile reads the whole file, returning the MD5 hash of its contents and its number of lines.
+func hashFile(file io.Reader) ([]byte, int, error) {
+	var (
+		hash  = md5.New()
+		lines = &lineCounter{}
+	)
+
+	if _, err := io.Copy(io.MultiWriter(hash, lines), file); err != nil {
+		return nil, 0, errors.Wrap(err, "error to hash file")
 	}
 
-	if err = h.cache.Save(ctx, fileContents, response); err != nil {
-		log.Errorf("Error to store response on cache: %v", err)
+	return hash.Sum(nil), lines.count, nil
+}
+
+// lineCounter is a writer counting the lines written to it, including a last one without line break.
+type lineCounter struct {
+	count     int
+	lastBreak bool
+}
+
+func (l *lineCounter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if l.count == 0 || l.lastBreak {
+		l.count++ // a line starts
 	}
+
+	l.count += bytes.Count(p[:len(p)-1], []byte{'\n'})
+	l.lastBreak = p[len(p)-1] == '\n'
+
+	return len(p), nil
+}
+
+// parserFor chooses the parser by the uploaded file extension, falling back to its Content-Type.
+func (p CustomersFileParsers) parserFor(header *multipart.FileHeader) (CustomersFileParser, bool) {
+	if parser, ok := p[strings.ToLower(filepath.Ext(header.Filename))]; ok {
+		return parser, true
+	}
+
+	contentType, _, err := mime.ParseMediaType(header.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, false
+	}
+
+	parser, ok := p[contentTypeFileExtensions[contentType]]
+
+	return parser, ok
 }
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -32,35 +33,136 @@ func TestFilterCustomersAPI(t *testing.T) {
 	var filterCustomersHandler = NewFilterCustomersHandler(
 		log,
 		cfg,
-		customerfile.NewCustomersFileParser(),
-		usecase.NewFilterCustomers(log, customernotify.NewStdOutNotifier(log)),
-		cache.NewInMemoryFilterCustomersCache(log),
+		CustomersFileParsers{
+			TXTFileExtension:     customerfile.NewCustomersFileParser(),
+			CSVFileExtension:     customerfile.NewCSVCustomersFileParser(),
+			GeoJSONFileExtension: customerfile.NewGeoJSONCustomersFileParser(),
+		},
+		usecase.NewFilterCustomers(log, customernotify.NewStdOutNotifier(log), cfg.FilterWorkers),
+		cache.NewInMemoryFilterCustomersCache(log, cfg.CacheMaxEntries, cfg.CacheMaxBytes, cfg.CacheTTL),
 	)
 
-	w := httptest.NewRecorder()
+	const expectedStatusCode = 200
+	const expectedBody = `[{"id":4,"name":"Ian Kehoe","distance_km":10.567},{"id":5,"name":"Nora Dempsey","distance_km":23.287},{"id":6,"name":"Theresa Enright","distance_km":24.085},{"id":8,"name":"Eoin Ahearn","distance_km":83.533},{"id":11,"name":"Richard Finnegan","distance_km":38.138},{"id":12,"name":"Christina McArdle","distance_km":41.769},{"id":13,"name":"Olive Ahearn","distance_km":62.232},{"id":15,"name":"Michael Ahearn","distance_km":43.722},{"id":17,"name":"Patricia Cahill","distance_km":96.079},{"id":23,"name":"Eoin Gallagher","distance_km":82.695},{"id":24,"name":"Rose Enright","distance_km":89.031},{"id":26,"name":"Stephen McArdle","distance_km":98.875},{"id":29,"name":"Oliver Ahearn","distance_km":72.202},{"id":30,"name":"Nick Enright","distance_km":82.643},{"id":31,"name":"Alan Behan","distance_km":44.291},{"id":39,"name":"Lisa Ahearn","distance_km":38.358}]`
 
-	postRequestWithValidFile, err := newRequestWithFile(http.MethodPost, "localhost:8080", "file", "customers.txt")
-	if err != nil {
-		t.Fatal("failed to create valid request")
-	}
+	for _, fileName := range []string{"customers.txt", "customers.csv", "customers.geojson"} {
+		fileName := fileName
 
-	filterCustomersHandler.Handle(w, postRequestWithValidFile)
+		t.Run(fileName, func(t *testing.T) {
+			w := httptest.NewRecorder()
 
-	// asserts
+			postRequestWithValidFile, err := newRequestWithFile(http.MethodPost, "localhost:8080", "file", fileName)
+			if err != nil {
+				t.Fatal("failed to create valid request")
+			}
 
-	const expectedStatusCode = 200
-	const expectedBody = `[{"id":4,"name":"Ian Kehoe"},{"id":5,"name":"Nora Dempsey"},{"id":6,"name":"Theresa Enright"},{"id":8,"name":"Eoin Ahearn"},{"id":11,"name":"Richard Finnegan"},{"id":12,"name":"Christina McArdle"},{"id":13,"name":"Olive Ahearn"},{"id":15,"name":"Michael Ahearn"},{"id":17,"name":"Patricia Cahill"},{"id":23,"name":"Eoin Gallagher"},{"id":24,"name":"Rose Enright"},{"id":26,"name":"Stephen McArdle"},{"id":29,"name":"Oliver Ahearn"},{"id":30,"name":"Nick Enright"},{"id":31,"name":"Alan Behan"},{"id":39,"name":"Lisa Ahearn"}]`
+			filterCustomersHandler.Handle(w, postRequestWithValidFile)
+
+			// asserts
+
+			httpResponse := w.Result()
+			defer httpResponse.Body.Close()
+
+			bytesResponse, err := io.ReadAll(httpResponse.Body)
+			if err != nil {
+				t.Fatal("failed to read buffer response body")
+			}
 
-	httpResponse := w.Result()
-	defer httpResponse.Body.Close()
+			assert.Equal(t, expectedStatusCode, httpResponse.StatusCode, "HTTP Status Code does not match")
+			assert.Equal(t, expectedBody, string(bytesResponse), "HTTP Response Body does not match")
+		})
+	}
+}
+
+// TestFilterCustomersAPI_ContentNegotiation asserts the same upload is answered, and cached, on every requested format.
+func TestFilterCustomersAPI_ContentNegotiation(t *testing.T) {
+	t.Parallel()
 
-	bytesResponse, err := io.ReadAll(httpResponse.Body)
+	var log = logger.NewEmptyLogger()
+
+	cfg, err := loadConfig()
 	if err != nil {
-		t.Fatal("failed to read buffer response body")
+		log.Fatalf("error to load configuration: %v", err)
+	}
+
+	var filterCustomersHandler = NewFilterCustomersHandler(
+		log,
+		cfg,
+		CustomersFileParsers{TXTFileExtension: customerfile.NewCustomersFileParser()},
+		usecase.NewFilterCustomers(log, customernotify.NewStdOutNotifier(log), cfg.FilterWorkers),
+		cache.NewInMemoryFilterCustomersCache(log, cfg.CacheMaxEntries, cfg.CacheMaxBytes, cfg.CacheTTL),
+	)
+
+	tests := []struct {
+		accept          string
+		wantStatusCode  int
+		wantContentType string
+		wantBodyPrefix  string
+	}{
+		{
+			accept:          "text/csv",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/csv",
+			wantBodyPrefix:  "id,name,distance_km\n4,Ian Kehoe,10.567\n5,Nora Dempsey,23.287\n",
+		},
+		{
+			accept:          "application/json",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "application/json",
+			wantBodyPrefix:  `[{"id":4,"name":"Ian Kehoe","distance_km":10.567},`,
+		},
+		{
+			accept:          "text/plain",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/plain",
+			wantBodyPrefix:  "4\tIan Kehoe\t10.567\n",
+		},
+		{
+			accept:          "application/x-ndjson",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			wantBodyPrefix:  "{\"id\":4,\"name\":\"Ian Kehoe\",\"distance_km\":10.567}\n",
+		},
+		{
+			accept:          "application/geo+json",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "application/geo+json",
+			wantBodyPrefix:  `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[-6.257664,53.339428]},"properties":{"kind":"office","radius_km":100}},`,
+		},
+		{
+			accept:          "text/html",
+			wantStatusCode:  http.StatusNotAcceptable,
+			wantContentType: "application/json",
+			wantBodyPrefix:  `{"error":"invalid request parameters: none of the accepted content types is supported"}`,
+		},
 	}
 
-	assert.Equal(t, expectedStatusCode, httpResponse.StatusCode, "HTTP Status Code does not match")
-	assert.Equal(t, expectedBody, string(bytesResponse), "HTTP Response Body does not match")
+	// every format is requested twice, so the second response comes from the cache
+	for i := 0; i < 2; i++ {
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+
+			request, err := newRequestWithFile(http.MethodPost, "localhost:8080", "file", "customers.txt")
+			if err != nil {
+				t.Fatal("failed to create valid request")
+			}
+			request.Header.Set("Accept", tt.accept)
+
+			filterCustomersHandler.Handle(w, request)
+
+			httpResponse := w.Result()
+
+			bytesResponse, err := io.ReadAll(httpResponse.Body)
+			if err != nil {
+				t.Fatal("failed to read buffer response body")
+			}
+			httpResponse.Body.Close()
+
+			assert.Equal(t, tt.wantStatusCode, httpResponse.StatusCode, "HTTP Status Code does not match")
+			assert.Equal(t, tt.wantContentType, httpResponse.Header.Get("Content-Type"), "Content-Type does not match")
+			assert.True(t, strings.HasPrefix(string(bytesResponse), tt.wantBodyPrefix), "HTTP Response Body does not match: %s", bytesResponse)
+		}
+	}
 }
 
 func loadConfig() (*config.Config, error) {
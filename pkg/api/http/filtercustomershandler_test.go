@@ -2,15 +2,20 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"crypto/md5"
 	"go.uber.org/mock/gomock"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
@@ -18,6 +23,7 @@ import (
 
 	"github.com/tonytcb/party-invite/pkg/domain"
 	"github.com/tonytcb/party-invite/pkg/infrastructure/config"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/customerfile"
 	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
 )
 
@@ -25,8 +31,10 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 	t.Parallel()
 
 	defaultConfig := &config.Config{
-		BaseLocation:   "dublin",
-		LocationNearTo: 100,
+		BaseLocation:      "dublin",
+		LocationNearTo:    100,
+		DistancePrecision: 3,
+		Locations:         "sao paulo:-23.533773,-46.625290",
 	}
 
 	saoPaulo, err := domain.NewCoordinate("-23.533773", "-46.625290")
@@ -54,6 +62,13 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 	putRequest, _ := newRequestWithFile(http.MethodPut, "localhost:8080", "file", "customers.txt")
 	postRequestWithInvalidRequestParamName, _ := newRequestWithFile(http.MethodPost, "localhost:8080", "another_name", "customers.txt")
 	postRequestWithInvalidFile, _ := newRequestWithFile(http.MethodPost, "localhost:8080", "file", "invalid-ext.sql")
+	postRequestOnLenientMode, _ := newRequestWithFile(http.MethodPost, "localhost:8080?mode=lenient", "file", "customers.txt")
+	postRequestRejectingDuplicates, _ := newRequestWithFile(http.MethodPost, "localhost:8080?duplicates=reject", "file", "customers.txt")
+	postRequestWithVincenty, _ := newRequestWithFile(http.MethodPost, "localhost:8080?distance_algorithm=vincenty", "file", "customers.txt")
+	postRequestWithInvalidRadius, _ := newRequestWithFile(http.MethodPost, "localhost:8080?radius_km=-1", "file", "customers.txt")
+	postRequestForNearest, _ := newRequestWithFile(http.MethodPost, "localhost:8080?nearest=2", "file", "customers.txt")
+	postRequestForOffices, _ := newRequestWithFile(http.MethodPost, "localhost:8080?office=dublin&office=sao+paulo&radius_km=10&radius_km=20", "file", "customers.txt")
+	postRequestWithCustomLocation, _ := newRequestWithFile(http.MethodPost, "localhost:8080?latitude=-23.533773&longitude=-46.625290&radius_km=500", "file", "customers.txt")
 
 	var log = logger.NewLogger(&bytes.Buffer{})
 
@@ -72,6 +87,7 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 		args             args
 		wantStatusCode   int
 		wantResponseBody string
+		wantHeader       http.Header // only the given headers are checked
 	}{
 		{
 			name: "should handle successfully a file containing valid customers",
@@ -79,8 +95,8 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
 					parser := NewMockCustomersFileParser(ctrl)
 					parser.EXPECT().
-						Parse(gomock.Any(), gomock.Any()).
-						Return(customersList1, nil).
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(streamOf(customersList1, domain.RejectedLines{}, nil)).
 						Times(1)
 
 					return parser
@@ -88,11 +104,18 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
 					filter := NewMockFilterCustomersUsecase(ctrl)
 
-					customers := []domain.Customer{customer1, customer2}
+					customers := []domain.NearCustomer{
+						domain.NewNearCustomer(customer1, decimal.Zero),
+						domain.NewNearCustomer(customer2, decimal.RequireFromString("9390.05213")),
+					}
 
 					filter.EXPECT().
-						ByNearLocation(gomock.Any(), customersList1, domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID).
-						Return(customers, nil).
+						ByNearLocation(gomock.Any(), gomock.Any(), domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID, domain.DuplicatePolicyKeepFirst, domain.HaversineDistance{}).
+						DoAndReturn(filterOf(t, customersList1, customers, nil, nil)).
+						Times(1)
+
+					filter.EXPECT().
+						Notify(gomock.Any(), domain.NearCustomers(customers)).
 						Times(1)
 
 					return filter
@@ -115,7 +138,7 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 				request:        postRequestWithValidFile,
 			},
 			wantStatusCode:   http.StatusOK,
-			wantResponseBody: `[{"id":1,"name":"User name 1"},{"id":2,"name":"User name 2"}]`,
+			wantResponseBody: `[{"id":1,"name":"User name 1","distance_km":0.000},{"id":2,"name":"User name 2","distance_km":9390.052}]`,
 		},
 		{
 			name: "should return cached response",
@@ -130,7 +153,7 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 					cache := NewMockFilterCustomersCache(ctrl)
 					cache.EXPECT().
 						Get(gomock.Any(), gomock.Any()).
-						Return([]byte(`[{"id":2,"name":"User name 2"}]`), nil).
+						Return([]byte("X-Duplicated-User-Ids: 2\r\n\r\n"+`[{"id":2,"name":"User name 2"}]`), nil).
 						Times(1)
 					return cache
 				},
@@ -141,6 +164,7 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 			},
 			wantStatusCode:   http.StatusOK,
 			wantResponseBody: `[{"id":2,"name":"User name 2"}]`,
+			wantHeader:       http.Header{"Content-Type": {jsonContentType}, duplicatedUserIDsHeader: {"2"}},
 		},
 
 		{
@@ -207,19 +231,318 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 			wantResponseBody: `{"error":"invalid '.sql' file extension"}`,
 		},
 		{
-			name: "should error on parser due to some domain validation",
+			name: "should filter customers by the base location and radius informed on the request",
+			fields: fields{
+				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
+					parser := NewMockCustomersFileParser(ctrl)
+					parser.EXPECT().
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(streamOf(customersList1, domain.RejectedLines{}, nil)).
+						Times(1)
+
+					return parser
+				},
+				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
+					filter := NewMockFilterCustomersUsecase(ctrl)
+					filter.EXPECT().
+						ByNearLocation(gomock.Any(), gomock.Any(), saoPaulo, decimal.NewFromInt32(500), domain.OrderByCustomerID, domain.DuplicatePolicyKeepFirst, domain.HaversineDistance{}).
+						DoAndReturn(filterOf(t, customersList1, []domain.NearCustomer{domain.NewNearCustomer(customer2, decimal.Zero)}, nil, nil)).
+						Times(1)
+
+					filter.EXPECT().
+						Notify(gomock.Any(), gomock.Any()).
+						Times(1)
+
+					return filter
+				},
+				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
+					cache := NewMockFilterCustomersCache(ctrl)
+					cache.EXPECT().
+						Get(gomock.Any(), gomock.Any()).
+						Return(nil, nil).
+						Times(1)
+					cache.EXPECT().
+						Save(gomock.Any(), gomock.Any(), gomock.Any()).
+						Return(nil).
+						Times(1)
+					return cache
+				},
+			},
+			args: args{
+				responseWriter: httptest.NewRecorder(),
+				request:        postRequestWithCustomLocation,
+			},
+			wantStatusCode:   http.StatusOK,
+			wantResponseBody: `[{"id":2,"name":"User name 2","distance_km":0.000}]`,
+		},
+		{
+			name: "should report the rejected lines on lenient mode",
+			fields: fields{
+				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
+					parser := NewMockCustomersFileParser(ctrl)
+					parser.EXPECT().
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeLenient, gomock.Any()).
+						DoAndReturn(streamOf(customersList1, domain.RejectedLines{{Line: 7, Content: "{", Reason: "invalid json"}}, nil)).
+						Times(1)
+
+					return parser
+				},
+				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
+					filter := NewMockFilterCustomersUsecase(ctrl)
+					filter.EXPECT().
+						ByNearLocation(gomock.Any(), gomock.Any(), domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID, domain.DuplicatePolicyKeepFirst, domain.HaversineDistance{}).
+						DoAndReturn(filterOf(t, customersList1, []domain.NearCustomer{domain.NewNearCustomer(customer1, decimal.Zero)}, nil, nil)).
+						Times(1)
+
+					filter.EXPECT().
+						Notify(gomock.Any(), gomock.Any()).
+						Times(1)
+
+					return filter
+				},
+				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
+					cache := NewMockFilterCustomersCache(ctrl)
+					cache.EXPECT().
+						Get(gomock.Any(), gomock.Any()).
+						Return(nil, nil).
+						Times(1)
+					cache.EXPECT().
+						Save(gomock.Any(), gomock.Any(), gomock.Any()).
+						Return(nil).
+						Times(1)
+					return cache
+				},
+			},
+			args: args{
+				responseWriter: httptest.NewRecorder(),
+				request:        postRequestOnLenientMode,
+			},
+			wantStatusCode:   http.StatusOK,
+			wantResponseBody: `{"customers":[{"id":1,"name":"User name 1","distance_km":0.000}],"rejected":[{"line":7,"content":"{","reason":"invalid json"}]}`,
+			wantHeader:       http.Header{rejectedLinesHeader: {"7"}, rejectedLinesCountHeader: {"1"}, duplicatedUserIDsHeader: nil},
+		},
+		{
+			name: "should warn about duplicated customers, keeping the first one",
+			fields: fields{
+				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
+					parser := NewMockCustomersFileParser(ctrl)
+					parser.EXPECT().
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(streamOf(domain.Customers{customer1, customer1.WithLocation(domain.DublinLocation)}, domain.RejectedLines{}, nil)).
+						Times(1)
+
+					return parser
+				},
+				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
+					filter := NewMockFilterCustomersUsecase(ctrl)
+					filter.EXPECT().
+						ByNearLocation(gomock.Any(), gomock.Any(), domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID, domain.DuplicatePolicyKeepFirst, domain.HaversineDistance{}).
+						DoAndReturn(filterOf(t, domain.Customers{customer1, customer1.WithLocation(domain.DublinLocation)}, []domain.NearCustomer{domain.NewNearCustomer(customer1, decimal.Zero)}, []int{1}, nil)).
+						Times(1)
+
+					filter.EXPECT().
+						Notify(gomock.Any(), gomock.Any()).
+						Times(1)
+
+					return filter
+				},
+				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
+					cache := NewMockFilterCustomersCache(ctrl)
+					cache.EXPECT().
+						Get(gomock.Any(), gomock.Any()).
+						Return(nil, nil).
+						Times(1)
+					cache.EXPECT().
+						Save(gomock.Any(), gomock.Any(), []byte(
+							"X-Duplicated-User-Ids: 1\r\nX-Duplicated-User-Ids-Count: 1\r\n\r\n"+
+								`[{"id":1,"name":"User name 1","distance_km":0.000}]`,
+						)).
+						Return(nil).
+						Times(1)
+					return cache
+				},
+			},
+			args: args{
+				responseWriter: httptest.NewRecorder(),
+				request:        postRequestWithValidFile,
+			},
+			wantStatusCode:   http.StatusOK,
+			wantResponseBody: `[{"id":1,"name":"User name 1","distance_km":0.000}]`,
+			wantHeader:       http.Header{duplicatedUserIDsHeader: {"1"}, duplicatedUserIDsCountHeader: {"1"}},
+		},
+		{
+			name: "should error on duplicated customers with the reject policy",
+			fields: fields{
+				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
+					parser := NewMockCustomersFileParser(ctrl)
+					parser.EXPECT().
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(streamOf(domain.Customers{customer1, customer2, customer1}, domain.RejectedLines{}, nil)).
+						Times(1)
+
+					return parser
+				},
+				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
+					filter := NewMockFilterCustomersUsecase(ctrl)
+					filter.EXPECT().
+						ByNearLocation(gomock.Any(), gomock.Any(), domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID, domain.DuplicatePolicyReject, domain.HaversineDistance{}).
+						DoAndReturn(filterOf(t, domain.Customers{customer1, customer2, customer1}, nil, nil, domain.NewErrInvalidArgument("user_id 1 found more than once", "duplicated customers"))).
+						Times(1)
+
+					return filter
+				},
+				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
+					cache := NewMockFilterCustomersCache(ctrl)
+					cache.EXPECT().
+						Get(gomock.Any(), gomock.Any()).
+						Return(nil, nil).
+						Times(1)
+					return cache
+				},
+			},
+			args: args{
+				responseWriter: httptest.NewRecorder(),
+				request:        postRequestRejectingDuplicates,
+			},
+			wantStatusCode:   http.StatusUnprocessableEntity,
+			wantResponseBody: `{"error":"error to filter customers by location: duplicated customers: user_id 1 found more than once"}`,
+		},
+		{
+			name: "should calculate distances with the requested algorithm",
+			fields: fields{
+				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
+					parser := NewMockCustomersFileParser(ctrl)
+					parser.EXPECT().
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(streamOf(customersList1, domain.RejectedLines{}, nil)).
+						Times(1)
+
+					return parser
+				},
+				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
+					filter := NewMockFilterCustomersUsecase(ctrl)
+					filter.EXPECT().
+						ByNearLocation(gomock.Any(), gomock.Any(), domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID, domain.DuplicatePolicyKeepFirst, domain.VincentyDistance{}).
+						DoAndReturn(filterOf(t, customersList1, []domain.NearCustomer{domain.NewNearCustomer(customer1, decimal.Zero)}, nil, nil)).
+						Times(1)
+
+					filter.EXPECT().
+						Notify(gomock.Any(), gomock.Any()).
+						Times(1)
+
+					return filter
+				},
+				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
+					cache := NewMockFilterCustomersCache(ctrl)
+					cache.EXPECT().
+						Get(gomock.Any(), gomock.Any()).
+						Return(nil, nil).
+						Times(1)
+					cache.EXPECT().
+						Save(gomock.Any(), gomock.Any(), gomock.Any()).
+						Return(nil).
+						Times(1)
+					return cache
+				},
+			},
+			args: args{
+				responseWriter: httptest.NewRecorder(),
+				request:        postRequestWithVincenty,
+			},
+			wantStatusCode:   http.StatusOK,
+			wantResponseBody: `[{"id":1,"name":"User name 1","distance_km":0.000}]`,
+		},
+		{
+			name: "should return the nearest customers regardless of the radius on nearest mode",
 			fields: fields{
 				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
 					parser := NewMockCustomersFileParser(ctrl)
 					parser.EXPECT().
-						Parse(gomock.Any(), gomock.Any()).
-						Return(nil, domain.NewErrInvalidArgument("root cause", "failed some domain validation"))
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(streamOf(customersList1, domain.RejectedLines{}, nil)).
+						Times(1)
 
 					return parser
 				},
+				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
+					filter := NewMockFilterCustomersUsecase(ctrl)
+
+					customers := []domain.NearCustomer{
+						domain.NewNearCustomer(customer1, decimal.Zero),
+						domain.NewNearCustomer(customer3, decimal.RequireFromString("4987.41538")),
+					}
+
+					filter.EXPECT().
+						NearestToLocation(gomock.Any(), gomock.Any(), domain.DublinLocation, 2, decimal.Zero, domain.OrderByDistance, domain.DuplicatePolicyKeepFirst, domain.HaversineDistance{}).
+						DoAndReturn(nearestOf(t, customersList1, customers, nil, nil)).
+						Times(1)
+
+					filter.EXPECT().
+						Notify(gomock.Any(), gomock.Any()).
+						Times(1)
+
+					return filter
+				},
+				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
+					cache := NewMockFilterCustomersCache(ctrl)
+					cache.EXPECT().
+						Get(gomock.Any(), gomock.Any()).
+						Return(nil, nil).
+						Times(1)
+					cache.EXPECT().
+						Save(gomock.Any(), gomock.Any(), gomock.Any()).
+						Return(nil).
+						Times(1)
+					return cache
+				},
+			},
+			args: args{
+				responseWriter: httptest.NewRecorder(),
+				request:        postRequestForNearest,
+			},
+			wantStatusCode:   http.StatusOK,
+			wantResponseBody: `[{"id":1,"name":"User name 1","distance_km":0.000},{"id":3,"name":"User name 3","distance_km":4987.415}]`,
+		},
+		{
+			name: "should error on invalid request parameters",
+			fields: fields{
+				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
+					return NewMockCustomersFileParser(ctrl)
+				},
 				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
 					return NewMockFilterCustomersUsecase(ctrl)
 				},
+				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
+					return NewMockFilterCustomersCache(ctrl)
+				},
+			},
+			args: args{
+				responseWriter: httptest.NewRecorder(),
+				request:        postRequestWithInvalidRadius,
+			},
+			wantStatusCode:   http.StatusUnprocessableEntity,
+			wantResponseBody: `{"error":"invalid request parameters: invalid radius_km: must be greater than zero"}`,
+		},
+		{
+			name: "should error on parser due to some domain validation",
+			fields: fields{
+				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
+					parser := NewMockCustomersFileParser(ctrl)
+					parser.EXPECT().
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(streamOf(nil, nil, domain.NewErrInvalidArgument("root cause", "failed some domain validation")))
+
+					return parser
+				},
+				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
+					filter := NewMockFilterCustomersUsecase(ctrl)
+					filter.EXPECT().
+						ByNearLocation(gomock.Any(), gomock.Any(), domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID, domain.DuplicatePolicyKeepFirst, domain.HaversineDistance{}).
+						DoAndReturn(filterOf(t, domain.Customers{}, domain.NearCustomers{}, nil, nil)).
+						Times(1)
+
+					return filter
+				},
 				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
 					cache := NewMockFilterCustomersCache(ctrl)
 					cache.EXPECT().
@@ -236,14 +559,160 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 			wantStatusCode:   http.StatusUnprocessableEntity,
 			wantResponseBody: `{"error":"error to parse input file: failed some domain validation: root cause"}`,
 		},
+		{
+			name: "should group the customers by their nearest office when several offices are informed",
+			fields: fields{
+				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
+					parser := NewMockCustomersFileParser(ctrl)
+					parser.EXPECT().
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(streamOf(customersList1, domain.RejectedLines{}, nil)).
+						Times(1)
+
+					return parser
+				},
+				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
+					filter := NewMockFilterCustomersUsecase(ctrl)
+
+					var (
+						dublin  = domain.NewOffice("dublin", domain.DublinLocation, decimal.NewFromInt(10))
+						paulo   = domain.NewOffice("sao paulo", saoPaulo, decimal.NewFromInt(20))
+						offices = []domain.Office{dublin, paulo}
+						groups  = []domain.OfficeCustomers{
+							{Office: dublin, Customers: domain.NearCustomers{domain.NewNearCustomer(customer1, decimal.Zero)}},
+							{Office: paulo, Customers: domain.NearCustomers{domain.NewNearCustomer(customer2, decimal.RequireFromString("1.5"))}},
+						}
+					)
+
+					filter.EXPECT().
+						ByNearestOffice(gomock.Any(), gomock.Any(), offices, domain.OrderByCustomerID, domain.DuplicatePolicyKeepFirst, domain.HaversineDistance{}).
+						DoAndReturn(func(_ context.Context, customers <-chan domain.Customer, _ []domain.Office, _ domain.OrderBy, _ domain.DuplicatePolicy, _ domain.DistanceCalculator) ([]domain.OfficeCustomers, []int, error) {
+							var got = domain.Customers{}
+
+							for customer := range customers {
+								got = append(got, customer)
+							}
+
+							assert.Equal(t, domain.Customers(customersList1), got)
+
+							return groups, nil, nil
+						}).
+						Times(1)
+					filter.EXPECT().
+						Notify(gomock.Any(), domain.NearCustomers{groups[0].Customers[0], groups[1].Customers[0]}).
+						Times(1)
+
+					return filter
+				},
+				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
+					cache := NewMockFilterCustomersCache(ctrl)
+					cache.EXPECT().
+						Get(gomock.Any(), gomock.Any()).
+						Return(nil, nil).
+						Times(1)
+					cache.EXPECT().
+						Save(gomock.Any(), gomock.Any(), gomock.Any()).
+						Return(nil).
+						Times(1)
+					return cache
+				},
+			},
+			args: args{
+				responseWriter: httptest.NewRecorder(),
+				request:        postRequestForOffices,
+			},
+			wantStatusCode: http.StatusOK,
+			wantResponseBody: `{"offices":[` +
+				`{"name":"dublin","radius_km":10,"count":1,"customers":[{"id":1,"name":"User name 1","distance_km":0.000}]},` +
+				`{"name":"sao paulo","radius_km":20,"count":1,"customers":[{"id":2,"name":"User name 2","distance_km":1.500}]}]}`,
+		},
+		{
+			name: "should not notify the customers accepted before the file fails to parse on strict mode",
+			fields: fields{
+				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
+					parser := NewMockCustomersFileParser(ctrl)
+					parser.EXPECT().
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(streamOf(customersList1, nil, domain.NewErrInvalidArgument("root cause", "error to parse line=3")))
+
+					return parser
+				},
+				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
+					filter := NewMockFilterCustomersUsecase(ctrl)
+					filter.EXPECT().
+						ByNearLocation(gomock.Any(), gomock.Any(), domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID, domain.DuplicatePolicyKeepFirst, domain.HaversineDistance{}).
+						DoAndReturn(filterOf(t, customersList1, []domain.NearCustomer{domain.NewNearCustomer(customer1, decimal.Zero)}, nil, nil)).
+						Times(1)
+					filter.EXPECT().
+						Notify(gomock.Any(), gomock.Any()).
+						Times(0)
+
+					return filter
+				},
+				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
+					cache := NewMockFilterCustomersCache(ctrl)
+					cache.EXPECT().
+						Get(gomock.Any(), gomock.Any()).
+						Return(nil, nil).
+						Times(1)
+					return cache
+				},
+			},
+			args: args{
+				responseWriter: httptest.NewRecorder(),
+				request:        postRequestWithValidFile,
+			},
+			wantStatusCode:   http.StatusUnprocessableEntity,
+			wantResponseBody: `{"error":"error to parse input file: error to parse line=3: root cause"}`,
+		},
+		{
+			name: "should error on a panicking parser",
+			fields: fields{
+				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
+					parser := NewMockCustomersFileParser(ctrl)
+					parser.EXPECT().
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(func(_ context.Context, _ io.Reader, _ domain.ParseMode, ch chan<- domain.Customer) (domain.RejectedLines, error) {
+							defer close(ch)
+
+							panic("nil customer")
+						})
+
+					return parser
+				},
+				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
+					filter := NewMockFilterCustomersUsecase(ctrl)
+					filter.EXPECT().
+						ByNearLocation(gomock.Any(), gomock.Any(), domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID, domain.DuplicatePolicyKeepFirst, domain.HaversineDistance{}).
+						DoAndReturn(filterOf(t, domain.Customers{}, domain.NearCustomers{}, nil, nil)).
+						Times(1)
+
+					return filter
+				},
+				cache: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersCache {
+					cache := NewMockFilterCustomersCache(ctrl)
+					cache.EXPECT().
+						Get(gomock.Any(), gomock.Any()).
+						Return(nil, nil).
+						Times(1)
+					return cache
+				},
+			},
+			args: args{
+				responseWriter: httptest.NewRecorder(),
+				request:        postRequestWithValidFile,
+			},
+			wantStatusCode:   http.StatusInternalServerError,
+			wantResponseBody: `{"error":"error to parse input file: panic while parsing file: nil customer"}`,
+		},
 		{
 			name: "should error on filter customers usecase",
 			fields: fields{
 				parser: func(t *testing.T, ctrl *gomock.Controller) CustomersFileParser {
 					parser := NewMockCustomersFileParser(ctrl)
 					parser.EXPECT().
-						Parse(gomock.Any(), gomock.Any()).
-						Return(customersList1, nil).
+						Stream(gomock.Any(), gomock.Any(), domain.ParseModeStrict, gomock.Any()).
+						DoAndReturn(streamOf(customersList1, domain.RejectedLines{}, nil)).
 						Times(1)
 
 					return parser
@@ -251,8 +720,8 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 				filter: func(t *testing.T, ctrl *gomock.Controller) FilterCustomersUsecase {
 					filter := NewMockFilterCustomersUsecase(ctrl)
 					filter.EXPECT().
-						ByNearLocation(gomock.Any(), customersList1, domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID).
-						Return(nil, errors.New("some error on calculation")).
+						ByNearLocation(gomock.Any(), gomock.Any(), domain.DublinLocation, decimal.NewFromInt32(100), domain.OrderByCustomerID, domain.DuplicatePolicyKeepFirst, domain.HaversineDistance{}).
+						DoAndReturn(filterOf(t, customersList1, nil, nil, errors.New("some error on calculation"))).
 						Times(1)
 
 					return filter
@@ -283,9 +752,11 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 			defer mockCtrl.Finish()
 
 			h := &FilterCustomersHandler{
-				log:    log,
-				cfg:    defaultConfig,
-				parser: tt.fields.parser(t, mockCtrl),
+				log: log,
+				cfg: defaultConfig,
+				parsers: CustomersFileParsers{
+					TXTFileExtension: tt.fields.parser(t, mockCtrl),
+				},
 				filter: tt.fields.filter(t, mockCtrl),
 				cache:  tt.fields.cache(t, mockCtrl),
 			}
@@ -301,11 +772,216 @@ func TestFilterCustomersHandler_Handle(t *testing.T) {
 
 				assert.Equal(t, tt.wantStatusCode, httpResponse.StatusCode, "HTTP Status Code does not match")
 				assert.Equal(t, tt.wantResponseBody, string(bytesResponse), "HTTP Response Body does not match")
+
+				for key := range tt.wantHeader {
+					assert.Equal(t, tt.wantHeader.Values(key), httpResponse.Header.Values(key), "HTTP Header %s does not match", key)
+				}
 			}
 		})
 	}
 }
 
+func TestCustomersFileParsers_parserFor(t *testing.T) {
+	t.Parallel()
+
+	var (
+		txtParser = customerfile.NewCustomersFileParser()
+		csvParser = customerfile.NewCSVCustomersFileParser()
+		parsers   = CustomersFileParsers{
+			TXTFileExtension: txtParser,
+			CSVFileExtension: csvParser,
+		}
+	)
+
+	newFileHeader := func(fileName string, contentType string) *multipart.FileHeader {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", contentType)
+
+		return &multipart.FileHeader{Filename: fileName, Header: header}
+	}
+
+	tests := []struct {
+		name   string
+		header *multipart.FileHeader
+		want   CustomersFileParser
+		wantOk bool
+	}{
+		{
+			name:   "should choose the parser by the file extension",
+			header: newFileHeader("customers.txt", "application/octet-stream"),
+			want:   txtParser,
+			wantOk: true,
+		},
+		{
+			name:   "should choose the parser by the file extension ignoring its case",
+			header: newFileHeader("CUSTOMERS.CSV", "application/octet-stream"),
+			want:   csvParser,
+			wantOk: true,
+		},
+		{
+			name:   "should fall back to the Content-Type when the extension is unknown",
+			header: newFileHeader("crm-export", "text/csv; charset=utf-8"),
+			want:   csvParser,
+			wantOk: true,
+		},
+		{
+			name:   "should not choose any parser for unknown extension and Content-Type",
+			header: newFileHeader("customers.sql", "application/sql"),
+			want:   nil,
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parsers.parserFor(tt.header)
+
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_hashFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		contents  string
+		wantLines int
+	}{
+		{
+			name:      "should count no lines on an empty file",
+			contents:  "",
+			wantLines: 0,
+		},
+		{
+			name:      "should count the last line without line break",
+			contents:  "first\nsecond",
+			wantLines: 2,
+		},
+		{
+			name:      "should not count an empty line after the last line break",
+			contents:  "first\nsecond\n",
+			wantLines: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			var wantHash = md5.Sum([]byte(tt.contents))
+
+			hash, lines, err := hashFile(strings.NewReader(tt.contents))
+
+			assert.NoError(t, err)
+			assert.Equal(t, wantHash[:], hash)
+			assert.Equal(t, tt.wantLines, lines)
+
+			// the same, reading byte by byte
+			hash, lines, err = hashFile(iotest.OneByteReader(strings.NewReader(tt.contents)))
+
+			assert.NoError(t, err)
+			assert.Equal(t, wantHash[:], hash)
+			assert.Equal(t, tt.wantLines, lines)
+		})
+	}
+}
+
+// streamOf mocks a parser streaming the customers, then returning the rejected lines and the error.
+func streamOf(
+	customers domain.Customers,
+	rejected domain.RejectedLines,
+	err error,
+) func(context.Context, io.Reader, domain.ParseMode, chan<- domain.Customer) (domain.RejectedLines, error) {
+	return func(ctx context.Context, _ io.Reader, _ domain.ParseMode, ch chan<- domain.Customer) (domain.RejectedLines, error) {
+		defer close(ch)
+
+		for _, customer := range customers {
+			select {
+			case ch <- customer:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		return rejected, err
+	}
+}
+
+// filterOf mocks a filter consuming all the streamed customers, asserting they're the expected ones.
+func filterOf(
+	t *testing.T,
+	want domain.Customers,
+	result domain.NearCustomers,
+	duplicatedIDs []int,
+	err error,
+) func(
+	context.Context,
+	<-chan domain.Customer,
+	*domain.Coordinate,
+	decimal.Decimal,
+	domain.OrderBy,
+	domain.DuplicatePolicy,
+	domain.DistanceCalculator,
+) (domain.NearCustomers, []int, error) {
+	return func(
+		_ context.Context,
+		customers <-chan domain.Customer,
+		_ *domain.Coordinate,
+		_ decimal.Decimal,
+		_ domain.OrderBy,
+		_ domain.DuplicatePolicy,
+		_ domain.DistanceCalculator,
+	) (domain.NearCustomers, []int, error) {
+		var got = domain.Customers{}
+
+		for customer := range customers {
+			got = append(got, customer)
+		}
+
+		assert.Equal(t, want, got)
+
+		return result, duplicatedIDs, err
+	}
+}
+
+// nearestOf is like filterOf, for the nearest mode.
+func nearestOf(
+	t *testing.T,
+	want domain.Customers,
+	result domain.NearCustomers,
+	duplicatedIDs []int,
+	err error,
+) func(
+	context.Context,
+	<-chan domain.Customer,
+	*domain.Coordinate,
+	int,
+	decimal.Decimal,
+	domain.OrderBy,
+	domain.DuplicatePolicy,
+	domain.DistanceCalculator,
+) (domain.NearCustomers, []int, error) {
+	var filter = filterOf(t, want, result, duplicatedIDs, err)
+
+	return func(
+		ctx context.Context,
+		customers <-chan domain.Customer,
+		baseLocation *domain.Coordinate,
+		_ int,
+		maxDistance decimal.Decimal,
+		orderBy domain.OrderBy,
+		duplicatePolicy domain.DuplicatePolicy,
+		calculator domain.DistanceCalculator,
+	) (domain.NearCustomers, []int, error) {
+		return filter(ctx, customers, baseLocation, maxDistance, orderBy, duplicatePolicy, calculator)
+	}
+}
+
 func newRequestWithFile(method string, endpoint string, fieldName string, fileName string) (*http.Request, error) {
 	currentDir, _ := os.Getwd()
 	fileDir := currentDir + "/../../../Data"
@@ -338,3 +1014,23 @@ func newRequestWithFile(method string, endpoint string, fieldName string, fileNa
 
 	return r, nil
 }
+
+func Test_decodeCachedResponse(t *testing.T) {
+	t.Parallel()
+
+	var header = http.Header{rejectedLinesHeader: {"7,9"}, rejectedLinesCountHeader: {"2"}}
+
+	gotHeader, gotBody, err := decodeCachedResponse(encodeCachedResponse(header, []byte("100\tTony Tester\t5.123\n")))
+	assert.NoError(t, err)
+	assert.Equal(t, header, gotHeader)
+	assert.Equal(t, []byte("100\tTony Tester\t5.123\n"), gotBody)
+
+	gotHeader, gotBody, err = decodeCachedResponse(encodeCachedResponse(http.Header{}, []byte{}))
+	assert.NoError(t, err)
+	assert.Empty(t, gotHeader)
+	assert.Empty(t, gotBody)
+
+	// responses cached before the headers were, answered as misses
+	_, _, err = decodeCachedResponse([]byte(`[{"id":2,"name":"User name 2"}]`))
+	assert.ErrorContains(t, err, "error to read cached response headers")
+}
@@ -0,0 +1,405 @@
+package http
+
+import (
+	"crypto/md5"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/config"
+)
+
+const (
+	officeParam     = "office"
+	latitudeParam   = "latitude"
+	longitudeParam  = "longitude"
+	radiusParam     = "radius_km"
+	nearestParam    = "nearest"
+	geofenceParam   = "geofence"
+	bandsParam      = "bands"
+	orderByParam    = "order_by"
+	modeParam       = "mode"
+	duplicatesParam = "duplicates"
+	algorithmParam  = "distance_algorithm"
+	reportParam     = "report"
+
+	contentTypeParam = "content_type"
+	officesValue     = "offices"
+)
+
+// filterCustomersParams holds the optional parameters of a filter customers request, already resolved
+// against the configuration defaults.
+type filterCustomersParams struct {
+	officeName        string
+	baseLocation      *domain.Coordinate
+	radius            decimal.Decimal      // zero on nearest mode without max distance, and on the other modes
+	nearest           int                  // zero unless on nearest mode
+	geofence          *domain.Geofence     // nil unless on geofence mode
+	bands             domain.DistanceBands // nil unless on bands mode
+	offices           []domain.Office      // nil unless on offices mode
+	orderBy           domain.OrderBy
+	parseMode         domain.ParseMode
+	duplicatePolicy   domain.DuplicatePolicy
+	distanceAlgorithm domain.DistanceAlgorithm
+	report            bool // whether JSON responses report what was found on the input even on strict mode
+	encoder           customersEncoder
+}
+
+// parseFilterCustomersParams reads the request parameters, either from the multipart form or from the query string.
+// The base location may be defined by an office name or by latitude/longitude, falling back to BASE_LOCATION, and
+// the radius, in kilometers, falls back to LOCATION_NEAR_TO. On nearest mode, when the number of nearest customers is
+// informed, the radius is an optional max distance instead, and the result is ordered by distance rather than by
+// customer ID, unless another order is informed. On geofence mode, when a polygon is informed as GeoJSON or WKT, the
+// customers inside it are returned instead, so neither the radius nor the number of nearest customers are accepted.
+// On bands mode, when the upper bounds of the distance bands are informed, the customers within the outer band are
+// returned bucketed into the bands, which is exclusive with the other modes and only presented as JSON. On offices
+// mode, when more than one office is informed, each customer is assigned to the nearest office within whose radius it
+// is, the radius being informed once for every office or once per office, which is exclusive with the other modes and
+// only presented as JSON too. The file is
+// parsed on strict mode unless the lenient one is informed, and customers sharing the same user_id are resolved by
+// the informed duplicate policy, falling back to DUPLICATE_POLICY. Distances are calculated by the informed
+// algorithm, falling back to DISTANCE_ALGORITHM. The response content type is negotiated through the Accept header,
+// and JSON responses become a report of the customers and of what was found on the input when it's requested.
+func parseFilterCustomersParams(r *http.Request, cfg *config.Config) (*filterCustomersParams, error) {
+	var (
+		office     = strings.TrimSpace(r.FormValue(officeParam))
+		latitude   = strings.TrimSpace(r.FormValue(latitudeParam))
+		longitude  = strings.TrimSpace(r.FormValue(longitudeParam))
+		radius     = strings.TrimSpace(r.FormValue(radiusParam))
+		nearest    = strings.TrimSpace(r.FormValue(nearestParam))
+		geofence   = strings.TrimSpace(r.FormValue(geofenceParam))
+		bands      = strings.TrimSpace(r.FormValue(bandsParam))
+		orderBy    = strings.TrimSpace(r.FormValue(orderByParam))
+		mode       = strings.TrimSpace(r.FormValue(modeParam))
+		duplicates = strings.TrimSpace(r.FormValue(duplicatesParam))
+		algorithm  = strings.TrimSpace(r.FormValue(algorithmParam))
+		report     = strings.TrimSpace(r.FormValue(reportParam))
+		params     = &filterCustomersParams{}
+		err        error
+	)
+
+	var encoders = customersEncoders
+
+	switch {
+	case bands != "":
+		encoders = bandsEncoders
+	case len(r.Form[officeParam]) > 1:
+		encoders = officesEncoders
+	}
+
+	if params.encoder, err = negotiateEncoder(r.Header.Get("Accept"), encoders); err != nil {
+		return nil, err
+	}
+
+	if params.baseLocation, params.officeName, err = resolveBaseLocation(cfg, office, latitude, longitude); err != nil {
+		return nil, err
+	}
+
+	if params.nearest, err = parseNearest(nearest); err != nil {
+		return nil, err
+	}
+
+	if params.geofence, err = parseGeofence(geofence, radius, nearest); err != nil {
+		return nil, err
+	}
+
+	if params.bands, err = parseBands(bands, radius, nearest, geofence); err != nil {
+		return nil, err
+	}
+
+	var offices, radii = r.Form[officeParam], r.Form[radiusParam]
+
+	if params.offices, err = parseOffices(cfg, offices, radii, nearest, geofence, bands); err != nil {
+		return nil, err
+	}
+
+	var fallbackRadius = params.nearest == 0 && params.geofence == nil && params.bands == nil && params.offices == nil
+
+	if params.offices != nil {
+		radius = "" // already applied to the offices
+	}
+
+	if params.radius, err = parseRadius(radius, fallbackRadius, cfg); err != nil {
+		return nil, err
+	}
+
+	params.orderBy = domain.OrderByCustomerID
+	if params.nearest > 0 {
+		params.orderBy = domain.OrderByDistance
+	}
+
+	if orderBy != "" {
+		if params.orderBy, err = domain.ParseOrderBy(orderBy); err != nil {
+			return nil, err
+		}
+	}
+
+	params.parseMode = domain.ParseModeStrict
+
+	if mode != "" {
+		if params.parseMode, err = domain.NewParseMode(mode); err != nil {
+			return nil, err
+		}
+	}
+
+	if params.duplicatePolicy, err = cfg.GetDuplicatePolicy(); err != nil {
+		return nil, err
+	}
+
+	if duplicates != "" {
+		if params.duplicatePolicy, err = domain.NewDuplicatePolicy(duplicates); err != nil {
+			return nil, err
+		}
+	}
+
+	if params.distanceAlgorithm, err = cfg.GetDistanceAlgorithm(); err != nil {
+		return nil, err
+	}
+
+	if algorithm != "" {
+		if params.distanceAlgorithm, err = domain.NewDistanceAlgorithm(algorithm); err != nil {
+			return nil, err
+		}
+	}
+
+	if params.report, err = parseReport(report); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// parseReport returns whether the report is requested, which it isn't when not informed.
+func parseReport(report string) (bool, error) {
+	if report == "" {
+		return false, nil
+	}
+
+	requested, err := strconv.ParseBool(report)
+	if err != nil {
+		return false, domain.NewErrInvalidArgument("must be true or false", "invalid "+reportParam)
+	}
+
+	return requested, nil
+}
+
+// parseNearest returns the informed number of nearest customers, or zero when it's not informed.
+func parseNearest(nearest string) (int, error) {
+	if nearest == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(nearest)
+	if err != nil || n <= 0 {
+		return 0, domain.NewErrInvalidArgument("must be a positive integer", "invalid "+nearestParam)
+	}
+
+	return n, nil
+}
+
+// parseGeofence returns the informed geofence, or nil when it's not informed.
+func parseGeofence(geofence, radius, nearest string) (*domain.Geofence, error) {
+	if geofence == "" {
+		return nil, nil
+	}
+
+	if radius != "" || nearest != "" {
+		return nil, domain.NewErrInvalidArgument(
+			"it's mutually exclusive with "+radiusParam+" and "+nearestParam,
+			"invalid "+geofenceParam,
+		)
+	}
+
+	return domain.ParseGeofence(geofence)
+}
+
+// parseBands returns the informed distance bands, or nil when they're not informed.
+func parseBands(bands, radius, nearest, geofence string) (domain.DistanceBands, error) {
+	if bands == "" {
+		return nil, nil
+	}
+
+	if radius != "" || nearest != "" || geofence != "" {
+		return nil, domain.NewErrInvalidArgument(
+			"it's mutually exclusive with "+radiusParam+", "+nearestParam+" and "+geofenceParam,
+			"invalid "+bandsParam,
+		)
+	}
+
+	return domain.ParseDistanceBands(bands)
+}
+
+// parseOffices returns the informed offices along with their radius, falling back to LOCATION_NEAR_TO, or nil when
+// fewer than two offices are informed. The radius is either informed once, applying to every office, or once per
+// office, in the same order. Coordinates were already refused along with them, as the offices are the base locations.
+func parseOffices(
+	cfg *config.Config,
+	offices []string,
+	radii []string,
+	nearest, geofence, bands string,
+) ([]domain.Office, error) {
+	if len(offices) < 2 { //nolint:gomnd // a single office is the base location
+		return nil, nil
+	}
+
+	if nearest != "" || geofence != "" || bands != "" {
+		return nil, domain.NewErrInvalidArgument(
+			"several offices are mutually exclusive with "+nearestParam+", "+geofenceParam+" and "+bandsParam,
+			"invalid "+officeParam,
+		)
+	}
+
+	if len(radii) > 1 && len(radii) != len(offices) {
+		return nil, domain.NewErrInvalidArgument(
+			fmt.Sprintf("must be informed once or once per office, got %d for %d offices", len(radii), len(offices)),
+			"invalid "+radiusParam,
+		)
+	}
+
+	var (
+		result = make([]domain.Office, 0, len(offices))
+		seen   = make(map[string]bool, len(offices))
+	)
+
+	for i, office := range offices {
+		var name = strings.ToLower(strings.TrimSpace(office))
+
+		if seen[name] {
+			return nil, domain.NewErrInvalidArgument(
+				fmt.Sprintf("'%s' informed more than once", name),
+				"invalid "+officeParam,
+			)
+		}
+
+		seen[name] = true
+
+		location, err := cfg.GetLocation(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var radius string
+		if len(radii) > 0 {
+			radius = strings.TrimSpace(radii[min(i, len(radii)-1)])
+		}
+
+		value, err := parseRadius(radius, true, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, domain.NewOffice(name, location, value))
+	}
+
+	return result, nil
+}
+
+// parseRadius returns the informed radius, falling back to LOCATION_NEAR_TO when fallback is set, or to zero, meaning
+// no radius, otherwise.
+func parseRadius(radius string, fallback bool, cfg *config.Config) (decimal.Decimal, error) {
+	if radius == "" {
+		if !fallback {
+			return decimal.Zero, nil
+		}
+
+		return decimal.NewFromInt32(cfg.LocationNearTo), nil
+	}
+
+	value, err := decimal.NewFromString(radius)
+	if err != nil {
+		return decimal.Zero, domain.NewErrInvalidArgument(err.Error(), "invalid "+radiusParam)
+	}
+
+	if !value.IsPositive() {
+		return decimal.Zero, domain.NewErrInvalidArgument("must be greater than zero", "invalid "+radiusParam)
+	}
+
+	return value, nil
+}
+
+// resolveBaseLocation returns the base location and, when it's defined by an office, its name.
+func resolveBaseLocation(cfg *config.Config, office, latitude, longitude string) (*domain.Coordinate, string, error) {
+	switch {
+	case office != "" && (latitude != "" || longitude != ""):
+		return nil, "", domain.NewErrInvalidArgument(
+			"office and latitude/longitude are mutually exclusive",
+			"invalid base location",
+		)
+
+	case office != "":
+		location, err := cfg.GetLocation(office)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return location, strings.ToLower(office), nil
+
+	case latitude != "" || longitude != "":
+		if latitude == "" || longitude == "" {
+			return nil, "", domain.NewErrInvalidArgument(
+				"latitude and longitude must be informed together",
+				"invalid base location",
+			)
+		}
+
+		location, err := domain.NewCoordinate(latitude, longitude)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return location, "", nil
+
+	default:
+		location, err := cfg.GetBaseLocation()
+		if err != nil {
+			return nil, "", err
+		}
+
+		return location, "", nil
+	}
+}
+
+// encode returns a canonical representation of the parameters, so equivalent requests produce the same value.
+func (p *filterCustomersParams) encode() string {
+	values := url.Values{}
+	values.Set(officeParam, p.officeName)
+	values.Set(latitudeParam, p.baseLocation.Latitude.String())
+	values.Set(longitudeParam, p.baseLocation.Longitude.String())
+	values.Set(radiusParam, p.radius.String())
+	values.Set(nearestParam, strconv.Itoa(p.nearest))
+	if p.geofence != nil {
+		values.Set(geofenceParam, p.geofence.String())
+	}
+	if p.bands != nil {
+		values.Set(bandsParam, p.bands.String())
+	}
+	for _, office := range p.offices {
+		values.Add(officesValue, office.Name+":"+office.Radius.String())
+	}
+	values.Set(orderByParam, p.orderBy.String())
+	values.Set(modeParam, p.parseMode.String())
+	values.Set(duplicatesParam, p.duplicatePolicy.String())
+	values.Set(algorithmParam, p.distanceAlgorithm.String())
+	values.Set(reportParam, strconv.FormatBool(p.report))
+	values.Set(contentTypeParam, p.encoder.ContentType())
+
+	return values.Encode()
+}
+
+// cacheKey identifies a response by the hash of the uploaded file contents and the parameters applied to filter it,
+// along with the settings of the server the response depends on: the calculator actually used, as the fast one may
+// be chosen by the size of the file, and the precision of the distances.
+func (p *filterCustomersParams) cacheKey(
+	fileHash []byte,
+	calculator domain.DistanceCalculator,
+	distancePrecision int32,
+) string {
+	var settings = fmt.Sprintf("%s&calculator=%T&precision=%d", p.encode(), calculator, distancePrecision)
+
+	return fmt.Sprintf("%x-%x", fileHash, md5.Sum([]byte(settings)))
+}
@@ -0,0 +1,432 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/config"
+)
+
+func Test_parseFilterCustomersParams(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		BaseLocation:   "dublin",
+		LocationNearTo: 100,
+		Locations:      "cork:51.897233,-8.470456",
+	}
+
+	cork, err := domain.NewCoordinate("51.897233", "-8.470456")
+	if err != nil {
+		t.Fatal("failed to build coordinate")
+	}
+	saoPaulo, err := domain.NewCoordinate("-23.533773", "-46.625290")
+	if err != nil {
+		t.Fatal("failed to build coordinate")
+	}
+
+	dublinGeofence, err := domain.NewGeofenceFromWKT("POLYGON ((-6.5 53.2, -6 53.2, -6 53.5, -6.5 53.2))")
+	if err != nil {
+		t.Fatal("failed to build geofence")
+	}
+
+	bands, err := domain.ParseDistanceBands("25,50,100")
+	if err != nil {
+		t.Fatal("failed to build distance bands")
+	}
+
+	isInvalidArgument := func(msg string) assert.ErrorAssertionFunc {
+		return func(t assert.TestingT, err error, i ...interface{}) bool {
+			return assert.ErrorContains(t, err, msg) && errors.As(err, &domain.ErrInvalidArgument{})
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		accept  string
+		want    *filterCustomersParams
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:  "should fall back to the configured base location and radius",
+			query: "",
+			want: &filterCustomersParams{
+				baseLocation: domain.DublinLocation,
+				radius:       decimal.NewFromInt32(100),
+				orderBy:      domain.OrderByCustomerID,
+				encoder:      jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:  "should resolve a registered office and a custom radius",
+			query: "office=cork&radius_km=50",
+			want: &filterCustomersParams{
+				officeName:   "cork",
+				baseLocation: cork,
+				radius:       decimal.NewFromInt32(50),
+				orderBy:      domain.OrderByCustomerID,
+				encoder:      jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:  "should build the base location from latitude and longitude",
+			query: "latitude=-23.533773&longitude=-46.625290&radius_km=12.5&order_by=distance_desc",
+			want: &filterCustomersParams{
+				baseLocation: saoPaulo,
+				radius:       decimal.RequireFromString("12.5"),
+				orderBy:      domain.OrderByDistanceDesc,
+				encoder:      jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "should negotiate the geojson content type",
+			query:  "",
+			accept: "text/html, application/geo+json;q=0.9",
+			want: &filterCustomersParams{
+				baseLocation: domain.DublinLocation,
+				radius:       decimal.NewFromInt32(100),
+				orderBy:      domain.OrderByCustomerID,
+				encoder:      geoJSONEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "should error when none of the accepted content types is supported",
+			query:  "",
+			accept: "text/html, application/xml",
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, errNotAcceptable)
+			},
+		},
+		{
+			name:    "should error on unknown office",
+			query:   "office=atlanta",
+			wantErr: isInvalidArgument("unknown location"),
+		},
+		{
+			name:    "should error when office and coordinates are informed together",
+			query:   "office=cork&latitude=1&longitude=2",
+			wantErr: isInvalidArgument("mutually exclusive"),
+		},
+		{
+			name:    "should error when only latitude is informed",
+			query:   "latitude=1",
+			wantErr: isInvalidArgument("latitude and longitude must be informed together"),
+		},
+		{
+			name:    "should error on invalid latitude",
+			query:   "latitude=north&longitude=2",
+			wantErr: isInvalidArgument("invalid latitude"),
+		},
+		{
+			name:    "should error on out of range longitude",
+			query:   "latitude=1&longitude=-500",
+			wantErr: isInvalidArgument("invalid longitude"),
+		},
+		{
+			name:    "should error on non numeric radius",
+			query:   "radius_km=far",
+			wantErr: isInvalidArgument("invalid radius_km"),
+		},
+		{
+			name:    "should error on non positive radius",
+			query:   "radius_km=0",
+			wantErr: isInvalidArgument("invalid radius_km"),
+		},
+		{
+			name:  "should parse the lenient mode",
+			query: "mode=lenient",
+			want: &filterCustomersParams{
+				baseLocation: domain.DublinLocation,
+				radius:       decimal.NewFromInt32(100),
+				orderBy:      domain.OrderByCustomerID,
+				parseMode:    domain.ParseModeLenient,
+				encoder:      jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:  "should parse the duplicate policy",
+			query: "duplicates=keep_nearest",
+			want: &filterCustomersParams{
+				baseLocation:    domain.DublinLocation,
+				radius:          decimal.NewFromInt32(100),
+				orderBy:         domain.OrderByCustomerID,
+				duplicatePolicy: domain.DuplicatePolicyKeepNearest,
+				encoder:         jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should error on unknown duplicate policy",
+			query:   "duplicates=merge",
+			wantErr: isInvalidArgument("invalid duplicate policy"),
+		},
+		{
+			name:  "should parse the distance algorithm",
+			query: "distance_algorithm=law_of_cosines",
+			want: &filterCustomersParams{
+				baseLocation:      domain.DublinLocation,
+				radius:            decimal.NewFromInt32(100),
+				orderBy:           domain.OrderByCustomerID,
+				distanceAlgorithm: domain.DistanceAlgorithmSphericalLawOfCosines,
+				encoder:           jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should error on unknown distance algorithm",
+			query:   "distance_algorithm=manhattan",
+			wantErr: isInvalidArgument("invalid distance algorithm"),
+		},
+		{
+			name:    "should error on unknown mode",
+			query:   "mode=relaxed",
+			wantErr: isInvalidArgument("invalid parse mode"),
+		},
+		{
+			name:  "should not fall back to the configured radius on nearest mode, ordering by distance",
+			query: "nearest=20",
+			want: &filterCustomersParams{
+				baseLocation: domain.DublinLocation,
+				radius:       decimal.Zero,
+				nearest:      20,
+				orderBy:      domain.OrderByDistance,
+				encoder:      jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:  "should parse the max distance and the order on nearest mode",
+			query: "nearest=5&radius_km=50&order_by=name",
+			want: &filterCustomersParams{
+				baseLocation: domain.DublinLocation,
+				radius:       decimal.NewFromInt32(50),
+				nearest:      5,
+				orderBy:      domain.OrderByName,
+				encoder:      jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should error on non positive nearest",
+			query:   "nearest=0",
+			wantErr: isInvalidArgument("invalid nearest"),
+		},
+		{
+			name:    "should error on non numeric nearest",
+			query:   "nearest=all",
+			wantErr: isInvalidArgument("invalid nearest"),
+		},
+		{
+			name:  "should parse the geofence, without falling back to the configured radius",
+			query: "geofence=" + url.QueryEscape(`{"type": "Polygon", "coordinates": [[[-6.5, 53.2], [-6, 53.2], [-6, 53.5], [-6.5, 53.2]]]}`),
+			want: &filterCustomersParams{
+				baseLocation: domain.DublinLocation,
+				radius:       decimal.Zero,
+				geofence:     dublinGeofence,
+				orderBy:      domain.OrderByCustomerID,
+				encoder:      jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should error on geofence along with radius",
+			query:   "radius_km=10&geofence=" + url.QueryEscape("POLYGON ((-6.5 53.2, -6 53.2, -6 53.5, -6.5 53.2))"),
+			wantErr: isInvalidArgument("invalid geofence: it's mutually exclusive with radius_km and nearest"),
+		},
+		{
+			name:    "should error on invalid geofence",
+			query:   "geofence=" + url.QueryEscape("POLYGON ((-6.5 53.2, -6 53.2, -6 53.5))"),
+			wantErr: isInvalidArgument("invalid geofence: ring must have at least 4 positions, got 3"),
+		},
+		{
+			name:  "should parse the distance bands, without falling back to the configured radius",
+			query: "bands=25,50,100",
+			want: &filterCustomersParams{
+				baseLocation: domain.DublinLocation,
+				radius:       decimal.Zero,
+				bands:        bands,
+				orderBy:      domain.OrderByCustomerID,
+				encoder:      jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "should error when the accepted content types don't support the distance bands",
+			query:  "bands=25,50,100",
+			accept: "text/csv",
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, errNotAcceptable)
+			},
+		},
+		{
+			name:    "should error on distance bands along with nearest",
+			query:   "bands=25,50&nearest=10",
+			wantErr: isInvalidArgument("invalid bands: it's mutually exclusive with radius_km, nearest and geofence"),
+		},
+		{
+			name:    "should error on distance bands out of order",
+			query:   "bands=50,25",
+			wantErr: isInvalidArgument("invalid distance bands: upper bound 25 must be greater than 50"),
+		},
+		{
+			name:  "should parse several offices, with a radius per office",
+			query: "office=dublin&office=Cork&radius_km=50&radius_km=80.5",
+			want: &filterCustomersParams{
+				officeName:   "dublin",
+				baseLocation: domain.DublinLocation,
+				radius:       decimal.Zero,
+				offices: []domain.Office{
+					domain.NewOffice("dublin", domain.DublinLocation, decimal.NewFromInt(50)),
+					domain.NewOffice("cork", cork, decimal.RequireFromString("80.5")),
+				},
+				orderBy: domain.OrderByCustomerID,
+				encoder: jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:  "should fall back to the configured radius for every office",
+			query: "office=cork&office=dublin",
+			want: &filterCustomersParams{
+				officeName:   "cork",
+				baseLocation: cork,
+				radius:       decimal.Zero,
+				offices: []domain.Office{
+					domain.NewOffice("cork", cork, decimal.NewFromInt32(100)),
+					domain.NewOffice("dublin", domain.DublinLocation, decimal.NewFromInt32(100)),
+				},
+				orderBy: domain.OrderByCustomerID,
+				encoder: jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "should error when the accepted content types don't support several offices",
+			query:  "office=dublin&office=cork",
+			accept: "text/csv",
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, errNotAcceptable)
+			},
+		},
+		{
+			name:    "should error on a radius neither shared nor informed per office",
+			query:   "office=dublin&office=cork&radius_km=10&radius_km=20&radius_km=30",
+			wantErr: isInvalidArgument("invalid radius_km: must be informed once or once per office, got 3 for 2 offices"),
+		},
+		{
+			name:    "should error on several offices along with nearest",
+			query:   "office=dublin&office=cork&nearest=10",
+			wantErr: isInvalidArgument("invalid office: several offices are mutually exclusive with nearest, geofence and bands"),
+		},
+		{
+			name:    "should error on an office informed more than once",
+			query:   "office=cork&office=Cork",
+			wantErr: isInvalidArgument("invalid office: 'cork' informed more than once"),
+		},
+		{
+			name:  "should parse the report request",
+			query: "report=true",
+			want: &filterCustomersParams{
+				baseLocation: domain.DublinLocation,
+				radius:       decimal.NewFromInt32(100),
+				orderBy:      domain.OrderByCustomerID,
+				report:       true,
+				encoder:      jsonEncoder{},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should error on invalid report request",
+			query:   "report=always",
+			wantErr: isInvalidArgument("invalid report: must be true or false"),
+		},
+		{
+			name:    "should error on unknown order",
+			query:   "order_by=age",
+			wantErr: isInvalidArgument("invalid order by"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/filter-customers?"+tt.query, nil)
+			r.Header.Set("Accept", tt.accept)
+
+			got, err := parseFilterCustomersParams(r, cfg)
+
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func Test_filterCustomersParams_cacheKey(t *testing.T) {
+	t.Parallel()
+
+	var (
+		fileContents = []byte(`{"latitude": "52.986375", "user_id": 12, "name": "Christina McArdle", "longitude": "-6.043701"}`)
+		dublin100    = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(100), encoder: jsonEncoder{}}
+		dublin50     = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(50), encoder: jsonEncoder{}}
+		byName       = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(100), orderBy: domain.OrderByName, encoder: jsonEncoder{}}
+		asCSV        = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(100), encoder: csvEncoder{}}
+		keepLast     = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(100), duplicatePolicy: domain.DuplicatePolicyKeepLast, encoder: jsonEncoder{}}
+		vincenty     = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(100), distanceAlgorithm: domain.DistanceAlgorithmVincenty, encoder: jsonEncoder{}}
+		offices      = &filterCustomersParams{baseLocation: domain.DublinLocation, offices: []domain.Office{domain.NewOffice("dublin", domain.DublinLocation, decimal.NewFromInt32(100))}, encoder: jsonEncoder{}}
+		reported     = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(100), report: true, encoder: jsonEncoder{}}
+		lenient      = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(100), parseMode: domain.ParseModeLenient, encoder: jsonEncoder{}}
+		nearest      = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(100), nearest: 20, encoder: jsonEncoder{}}
+	)
+
+	geofence, err := domain.NewGeofenceFromWKT("POLYGON ((-6.5 53.2, -6 53.2, -6 53.5, -6.5 53.2))")
+	if err != nil {
+		t.Fatal("failed to build geofence")
+	}
+
+	bands, err := domain.ParseDistanceBands("25,50,100")
+	if err != nil {
+		t.Fatal("failed to build distance bands")
+	}
+
+	var (
+		fenced    = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(100), geofence: geofence, encoder: jsonEncoder{}}
+		banded    = &filterCustomersParams{baseLocation: domain.DublinLocation, radius: decimal.NewFromInt32(100), bands: bands, encoder: jsonEncoder{}}
+		haversine = domain.HaversineDistance{}
+	)
+
+	assert.Equal(t, dublin100.cacheKey(fileContents, haversine, 3), dublin100.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), dublin50.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), byName.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), asCSV.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), lenient.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), reported.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), offices.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), keepLast.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), vincenty.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), nearest.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), fenced.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), banded.cacheKey(fileContents, haversine, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), dublin100.cacheKey([]byte(`another file`), haversine, 3))
+
+	// the settings of the server the response depends on
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), dublin100.cacheKey(fileContents, domain.FloatHaversineDistance{}, 3))
+	assert.NotEqual(t, dublin100.cacheKey(fileContents, haversine, 3), dublin100.cacheKey(fileContents, haversine, 5))
+}
@@ -41,19 +41,19 @@ func (m *MockCustomersFileParser) EXPECT() *MockCustomersFileParserMockRecorder
 	return m.recorder
 }
 
-// Parse mocks base method.
-func (m *MockCustomersFileParser) Parse(arg0 context.Context, arg1 io.Reader) (domain.Customers, error) {
+// Stream mocks base method.
+func (m *MockCustomersFileParser) Stream(arg0 context.Context, arg1 io.Reader, arg2 domain.ParseMode, arg3 chan<- domain.Customer) (domain.RejectedLines, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Parse", arg0, arg1)
-	ret0, _ := ret[0].(domain.Customers)
+	ret := m.ctrl.Call(m, "Stream", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.RejectedLines)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Parse indicates an expected call of Parse.
-func (mr *MockCustomersFileParserMockRecorder) Parse(arg0, arg1 any) *gomock.Call {
+// Stream indicates an expected call of Stream.
+func (mr *MockCustomersFileParserMockRecorder) Stream(arg0, arg1, arg2, arg3 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Parse", reflect.TypeOf((*MockCustomersFileParser)(nil).Parse), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockCustomersFileParser)(nil).Stream), arg0, arg1, arg2, arg3)
 }
 
 // MockFilterCustomersUsecase is a mock of FilterCustomersUsecase interface.
@@ -79,19 +79,96 @@ func (m *MockFilterCustomersUsecase) EXPECT() *MockFilterCustomersUsecaseMockRec
 	return m.recorder
 }
 
+// ByDistanceBands mocks base method.
+func (m *MockFilterCustomersUsecase) ByDistanceBands(ctx context.Context, customers <-chan domain.Customer, baseLocation *domain.Coordinate, bands domain.DistanceBands, orderBy domain.OrderBy, duplicatePolicy domain.DuplicatePolicy, calculator domain.DistanceCalculator) (domain.DistanceBands, []int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ByDistanceBands", ctx, customers, baseLocation, bands, orderBy, duplicatePolicy, calculator)
+	ret0, _ := ret[0].(domain.DistanceBands)
+	ret1, _ := ret[1].([]int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ByDistanceBands indicates an expected call of ByDistanceBands.
+func (mr *MockFilterCustomersUsecaseMockRecorder) ByDistanceBands(ctx, customers, baseLocation, bands, orderBy, duplicatePolicy, calculator any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByDistanceBands", reflect.TypeOf((*MockFilterCustomersUsecase)(nil).ByDistanceBands), ctx, customers, baseLocation, bands, orderBy, duplicatePolicy, calculator)
+}
+
 // ByNearLocation mocks base method.
-func (m *MockFilterCustomersUsecase) ByNearLocation(ctx context.Context, customers domain.Customers, baseLocation *domain.Coordinate, nearDistanceFilter decimal.Decimal, orderBy domain.OrderBy) (domain.Customers, error) {
+func (m *MockFilterCustomersUsecase) ByNearLocation(ctx context.Context, customers <-chan domain.Customer, baseLocation *domain.Coordinate, nearDistanceFilter decimal.Decimal, orderBy domain.OrderBy, duplicatePolicy domain.DuplicatePolicy, calculator domain.DistanceCalculator) (domain.NearCustomers, []int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ByNearLocation", ctx, customers, baseLocation, nearDistanceFilter, orderBy)
-	ret0, _ := ret[0].(domain.Customers)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "ByNearLocation", ctx, customers, baseLocation, nearDistanceFilter, orderBy, duplicatePolicy, calculator)
+	ret0, _ := ret[0].(domain.NearCustomers)
+	ret1, _ := ret[1].([]int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // ByNearLocation indicates an expected call of ByNearLocation.
-func (mr *MockFilterCustomersUsecaseMockRecorder) ByNearLocation(ctx, customers, baseLocation, nearDistanceFilter, orderBy any) *gomock.Call {
+func (mr *MockFilterCustomersUsecaseMockRecorder) ByNearLocation(ctx, customers, baseLocation, nearDistanceFilter, orderBy, duplicatePolicy, calculator any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByNearLocation", reflect.TypeOf((*MockFilterCustomersUsecase)(nil).ByNearLocation), ctx, customers, baseLocation, nearDistanceFilter, orderBy, duplicatePolicy, calculator)
+}
+
+// WithinGeofence mocks base method.
+func (m *MockFilterCustomersUsecase) WithinGeofence(ctx context.Context, customers <-chan domain.Customer, geofence *domain.Geofence, baseLocation *domain.Coordinate, orderBy domain.OrderBy, duplicatePolicy domain.DuplicatePolicy, calculator domain.DistanceCalculator) (domain.NearCustomers, []int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithinGeofence", ctx, customers, geofence, baseLocation, orderBy, duplicatePolicy, calculator)
+	ret0, _ := ret[0].(domain.NearCustomers)
+	ret1, _ := ret[1].([]int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// WithinGeofence indicates an expected call of WithinGeofence.
+func (mr *MockFilterCustomersUsecaseMockRecorder) WithinGeofence(ctx, customers, geofence, baseLocation, orderBy, duplicatePolicy, calculator any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinGeofence", reflect.TypeOf((*MockFilterCustomersUsecase)(nil).WithinGeofence), ctx, customers, geofence, baseLocation, orderBy, duplicatePolicy, calculator)
+}
+
+// NearestToLocation mocks base method.
+func (m *MockFilterCustomersUsecase) NearestToLocation(ctx context.Context, customers <-chan domain.Customer, baseLocation *domain.Coordinate, n int, maxDistance decimal.Decimal, orderBy domain.OrderBy, duplicatePolicy domain.DuplicatePolicy, calculator domain.DistanceCalculator) (domain.NearCustomers, []int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NearestToLocation", ctx, customers, baseLocation, n, maxDistance, orderBy, duplicatePolicy, calculator)
+	ret0, _ := ret[0].(domain.NearCustomers)
+	ret1, _ := ret[1].([]int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// NearestToLocation indicates an expected call of NearestToLocation.
+func (mr *MockFilterCustomersUsecaseMockRecorder) NearestToLocation(ctx, customers, baseLocation, n, maxDistance, orderBy, duplicatePolicy, calculator any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NearestToLocation", reflect.TypeOf((*MockFilterCustomersUsecase)(nil).NearestToLocation), ctx, customers, baseLocation, n, maxDistance, orderBy, duplicatePolicy, calculator)
+}
+
+// ByNearestOffice mocks base method.
+func (m *MockFilterCustomersUsecase) ByNearestOffice(ctx context.Context, customers <-chan domain.Customer, offices []domain.Office, orderBy domain.OrderBy, duplicatePolicy domain.DuplicatePolicy, calculator domain.DistanceCalculator) ([]domain.OfficeCustomers, []int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ByNearestOffice", ctx, customers, offices, orderBy, duplicatePolicy, calculator)
+	ret0, _ := ret[0].([]domain.OfficeCustomers)
+	ret1, _ := ret[1].([]int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ByNearestOffice indicates an expected call of ByNearestOffice.
+func (mr *MockFilterCustomersUsecaseMockRecorder) ByNearestOffice(ctx, customers, offices, orderBy, duplicatePolicy, calculator any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByNearestOffice", reflect.TypeOf((*MockFilterCustomersUsecase)(nil).ByNearestOffice), ctx, customers, offices, orderBy, duplicatePolicy, calculator)
+}
+
+// Notify mocks base method.
+func (m *MockFilterCustomersUsecase) Notify(ctx context.Context, customers domain.NearCustomers) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Notify", ctx, customers)
+}
+
+// Notify indicates an expected call of Notify.
+func (mr *MockFilterCustomersUsecaseMockRecorder) Notify(ctx, customers any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByNearLocation", reflect.TypeOf((*MockFilterCustomersUsecase)(nil).ByNearLocation), ctx, customers, baseLocation, nearDistanceFilter, orderBy)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Notify", reflect.TypeOf((*MockFilterCustomersUsecase)(nil).Notify), ctx, customers)
 }
 
 // MockFilterCustomersCache is a mock of FilterCustomersCache interface.
@@ -118,7 +195,7 @@ func (m *MockFilterCustomersCache) EXPECT() *MockFilterCustomersCacheMockRecorde
 }
 
 // Get mocks base method.
-func (m *MockFilterCustomersCache) Get(arg0 context.Context, arg1 []byte) ([]byte, error) {
+func (m *MockFilterCustomersCache) Get(arg0 context.Context, arg1 string) ([]byte, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Get", arg0, arg1)
 	ret0, _ := ret[0].([]byte)
@@ -133,7 +210,7 @@ func (mr *MockFilterCustomersCacheMockRecorder) Get(arg0, arg1 any) *gomock.Call
 }
 
 // Save mocks base method.
-func (m *MockFilterCustomersCache) Save(arg0 context.Context, arg1, arg2 []byte) error {
+func (m *MockFilterCustomersCache) Save(arg0 context.Context, arg1 string, arg2 []byte) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Save", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
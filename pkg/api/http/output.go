@@ -1,36 +1,400 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/tonytcb/party-invite/pkg/domain"
 )
 
 type customer struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID         int         `json:"id"`
+	Name       string      `json:"name"`
+	DistanceKm json.Number `json:"distance_km"`
 }
 
-func customersToJSONOutput(input domain.Customers) ([]byte, error) {
-	var customers = make([]customer, 0)
+type rejectedLine struct {
+	Line    int    `json:"line"`
+	Content string `json:"content"`
+	Reason  string `json:"reason"`
+}
+
+// warnings lists issues found on the input that didn't prevent the request from being answered.
+type warnings struct {
+	DuplicatedUserIDs []int `json:"duplicated_user_ids"`
+}
+
+type customersReport struct {
+	Customers []customer      `json:"customers"`
+	Rejected  *[]rejectedLine `json:"rejected,omitempty"` // only present on lenient mode
+	Warnings  *warnings       `json:"warnings,omitempty"`
+}
+
+// distanceBand presents the customers of a band, along with their count.
+type distanceBand struct {
+	FromKm    json.Number `json:"from_km"`
+	ToKm      json.Number `json:"to_km"`
+	Count     int         `json:"count"`
+	Customers []customer  `json:"customers"`
+}
+
+type distanceBandsReport struct {
+	Bands    []distanceBand  `json:"bands"`
+	Rejected *[]rejectedLine `json:"rejected,omitempty"` // only present on lenient mode
+	Warnings *warnings       `json:"warnings,omitempty"`
+}
+
+// officeGroup presents the customers assigned to an office, along with their count.
+type officeGroup struct {
+	Name      string      `json:"name"`
+	RadiusKm  json.Number `json:"radius_km"`
+	Count     int         `json:"count"`
+	Customers []customer  `json:"customers"`
+}
+
+type officesReport struct {
+	Offices  []officeGroup   `json:"offices"`
+	Rejected *[]rejectedLine `json:"rejected,omitempty"` // only present on lenient mode
+	Warnings *warnings       `json:"warnings,omitempty"`
+}
+
+// datasetMetadata presents a dataset without its customers, only counting them.
+type datasetMetadata struct {
+	ID        string          `json:"id"`
+	FileName  string          `json:"file_name"`
+	Customers int             `json:"customers"`
+	CreatedAt time.Time       `json:"created_at"`
+	Rejected  *[]rejectedLine `json:"rejected,omitempty"` // only present when parsed on lenient mode
+}
+
+// customersToJSONOutput encodes the customers as a JSON list, presenting distances rounded to the given precision.
+func customersToJSONOutput(input domain.NearCustomers, distancePrecision int32) ([]byte, error) {
+	bytes, err := json.Marshal(toCustomers(input, distancePrecision))
+	if err != nil {
+		return nil, errors.Wrap(err, "error to encode customers output")
+	}
+
+	return bytes, err
+}
+
+// customersReportToJSONOutput encodes the customers along with what was found while parsing the input file. The
+// rejected lines are presented when rejected is not nil, and the warnings when there are duplicated IDs.
+func customersReportToJSONOutput(
+	input domain.NearCustomers,
+	rejected domain.RejectedLines,
+	duplicatedIDs []int,
+	distancePrecision int32,
+) ([]byte, error) {
+	bytes, err := json.Marshal(customersReport{
+		Customers: toCustomers(input, distancePrecision),
+		Rejected:  toOptionalRejectedLines(rejected),
+		Warnings:  toWarnings(duplicatedIDs),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error to encode customers report output")
+	}
+
+	return bytes, nil
+}
+
+// distanceBandsToJSONOutput encodes the customers of every band, the nearest band first, along with what was found
+// while parsing the input file, like customersReportToJSONOutput does.
+func distanceBandsToJSONOutput(
+	input domain.DistanceBands,
+	rejected domain.RejectedLines,
+	duplicatedIDs []int,
+	distancePrecision int32,
+) ([]byte, error) {
+	var bands = make([]distanceBand, 0, len(input))
+
+	for _, band := range input {
+		bands = append(bands, distanceBand{
+			FromKm:    json.Number(band.From.String()),
+			ToKm:      json.Number(band.To.String()),
+			Count:     len(band.Customers),
+			Customers: toCustomers(band.Customers, distancePrecision),
+		})
+	}
+
+	bytes, err := json.Marshal(distanceBandsReport{
+		Bands:    bands,
+		Rejected: toOptionalRejectedLines(rejected),
+		Warnings: toWarnings(duplicatedIDs),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error to encode distance bands output")
+	}
+
+	return bytes, nil
+}
+
+// officesToJSONOutput encodes the customers assigned to every office, in the order the offices were informed, along
+// with what was found while parsing the input file, like customersReportToJSONOutput does.
+func officesToJSONOutput(
+	input []domain.OfficeCustomers,
+	rejected domain.RejectedLines,
+	duplicatedIDs []int,
+	distancePrecision int32,
+) ([]byte, error) {
+	var offices = make([]officeGroup, 0, len(input))
+
+	for _, group := range input {
+		offices = append(offices, officeGroup{
+			Name:      group.Office.Name,
+			RadiusKm:  json.Number(group.Office.Radius.String()),
+			Count:     len(group.Customers),
+			Customers: toCustomers(group.Customers, distancePrecision),
+		})
+	}
+
+	bytes, err := json.Marshal(officesReport{
+		Offices:  offices,
+		Rejected: toOptionalRejectedLines(rejected),
+		Warnings: toWarnings(duplicatedIDs),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error to encode offices output")
+	}
+
+	return bytes, nil
+}
+
+// datasetToJSONOutput encodes the metadata of the dataset.
+func datasetToJSONOutput(dataset *domain.Dataset) ([]byte, error) {
+	bytes, err := json.Marshal(datasetMetadata{
+		ID:        dataset.ID,
+		FileName:  dataset.FileName,
+		Customers: len(dataset.Customers),
+		CreatedAt: dataset.CreatedAt,
+		Rejected:  toOptionalRejectedLines(dataset.Rejected),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error to encode dataset output")
+	}
+
+	return bytes, nil
+}
+
+func toCustomers(input domain.NearCustomers, distancePrecision int32) []customer {
+	var customers = make([]customer, 0, len(input))
 
 	for _, v := range input {
 		customers = append(customers, customer{
-			ID:   v.ID,
-			Name: v.Name,
+			ID:         v.ID,
+			Name:       v.Name,
+			DistanceKm: json.Number(v.Distance.StringFixed(distancePrecision)),
+		})
+	}
+
+	return customers
+}
+
+func toRejectedLines(input domain.RejectedLines) []rejectedLine {
+	var lines = make([]rejectedLine, 0, len(input))
+
+	for _, v := range input {
+		lines = append(lines, rejectedLine{Line: v.Line, Content: v.Content, Reason: v.Reason})
+	}
+
+	return lines
+}
+
+func toOptionalRejectedLines(input domain.RejectedLines) *[]rejectedLine {
+	if input == nil {
+		return nil
+	}
+
+	lines := toRejectedLines(input)
+
+	return &lines
+}
+
+func toWarnings(duplicatedIDs []int) *warnings {
+	if len(duplicatedIDs) == 0 {
+		return nil
+	}
+
+	return &warnings{DuplicatedUserIDs: duplicatedIDs}
+}
+
+// customersToJSONLinesOutput encodes the customers as JSON lines, one customer object per line.
+func customersToJSONLinesOutput(input domain.NearCustomers, distancePrecision int32) ([]byte, error) {
+	var (
+		buffer  = &bytes.Buffer{}
+		encoder = json.NewEncoder(buffer)
+	)
+
+	for _, v := range input {
+		err := encoder.Encode(customer{
+			ID:         v.ID,
+			Name:       v.Name,
+			DistanceKm: json.Number(v.Distance.StringFixed(distancePrecision)),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "error to encode customers json lines output")
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// customersToCSVOutput encodes the customers as CSV, with an id,name,distance_km header.
+func customersToCSVOutput(input domain.NearCustomers, distancePrecision int32) ([]byte, error) {
+	var (
+		buffer = &bytes.Buffer{}
+		writer = csv.NewWriter(buffer)
+	)
+
+	if err := writer.Write([]string{"id", "name", "distance_km"}); err != nil {
+		return nil, errors.Wrap(err, "error to encode customers csv output")
+	}
+
+	for _, v := range input {
+		if err := writer.Write([]string{strconv.Itoa(v.ID), v.Name, v.Distance.StringFixed(distancePrecision)}); err != nil {
+			return nil, errors.Wrap(err, "error to encode customers csv output")
+		}
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return nil, errors.Wrap(err, "error to encode customers csv output")
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// customersToTextOutput encodes the customers as plain text, one customer per line with its id, name and distance
+// separated by tabs, so it's easily handled by shell tools like cut and awk.
+func customersToTextOutput(input domain.NearCustomers, distancePrecision int32) ([]byte, error) {
+	var (
+		buffer   = &bytes.Buffer{}
+		replacer = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	)
+
+	for _, v := range input {
+		fmt.Fprintf(buffer, "%d\t%s\t%s\n", v.ID, replacer.Replace(v.Name), v.Distance.StringFixed(distancePrecision))
+	}
+
+	return buffer.Bytes(), nil
+}
+
+const (
+	geoJSONFeatureCollectionType = "FeatureCollection"
+	geoJSONFeatureType           = "Feature"
+	geoJSONPointType             = "Point"
+
+	geoJSONOfficeKind   = "office"
+	geoJSONCustomerKind = "customer"
+)
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+	Rejected *[]rejectedLine  `json:"rejected,omitempty"` // foreign member, only present on lenient mode
+	Warnings *warnings        `json:"warnings,omitempty"` // foreign member
+}
+
+type geoJSONFeature struct {
+	Type       string       `json:"type"`
+	Geometry   geoJSONPoint `json:"geometry"`
+	Properties any          `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type        string        `json:"type"`
+	Coordinates []json.Number `json:"coordinates"`
+}
+
+type geoJSONOfficeProperties struct {
+	Kind     string      `json:"kind"`
+	Name     string      `json:"name,omitempty"`
+	RadiusKm json.Number `json:"radius_km,omitempty"` // absent on nearest mode without max distance
+}
+
+type geoJSONCustomerProperties struct {
+	Kind       string      `json:"kind"`
+	ID         int         `json:"user_id"`
+	Name       string      `json:"name"`
+	DistanceKm json.Number `json:"distance_km"`
+}
+
+// customersToGeoJSONOutput encodes the customers as a GeoJSON FeatureCollection of points. The first feature is
+// always the office used as base location, distinguished from the customers by its "kind" property.
+// When rejected is not nil, the rejected lines are presented as a "rejected" member of the collection, and the
+// duplicated IDs, if any, as a "warnings" one.
+func customersToGeoJSONOutput(
+	input domain.NearCustomers,
+	rejected domain.RejectedLines,
+	duplicatedIDs []int,
+	baseLocation *domain.Coordinate,
+	officeName string,
+	radius decimal.Decimal,
+	distancePrecision int32,
+) ([]byte, error) {
+	var features = make([]geoJSONFeature, 0, len(input)+1)
+
+	features = append(features, geoJSONFeature{
+		Type:     geoJSONFeatureType,
+		Geometry: newGeoJSONPoint(baseLocation),
+		Properties: geoJSONOfficeProperties{
+			Kind:     geoJSONOfficeKind,
+			Name:     officeName,
+			RadiusKm: toOptionalRadius(radius),
+		},
+	})
+
+	for _, v := range input {
+		features = append(features, geoJSONFeature{
+			Type:     geoJSONFeatureType,
+			Geometry: newGeoJSONPoint(v.Location),
+			Properties: geoJSONCustomerProperties{
+				Kind:       geoJSONCustomerKind,
+				ID:         v.ID,
+				Name:       v.Name,
+				DistanceKm: json.Number(v.Distance.StringFixed(distancePrecision)),
+			},
 		})
 	}
 
-	bytes, err := json.Marshal(customers)
+	var collection = geoJSONFeatureCollection{
+		Type:     geoJSONFeatureCollectionType,
+		Features: features,
+		Rejected: toOptionalRejectedLines(rejected),
+		Warnings: toWarnings(duplicatedIDs),
+	}
+
+	bytes, err := json.Marshal(collection)
 	if err != nil {
-		return nil, errors.Wrap(err, "error to encode customers output")
+		return nil, errors.Wrap(err, "error to encode customers geojson output")
 	}
 
-	return bytes, err
+	return bytes, nil
+}
+
+func toOptionalRadius(radius decimal.Decimal) json.Number {
+	if radius.IsZero() {
+		return ""
+	}
+
+	return json.Number(radius.String())
+}
+
+// newGeoJSONPoint builds a point geometry, whose coordinates follow the GeoJSON [longitude, latitude] order.
+func newGeoJSONPoint(location *domain.Coordinate) geoJSONPoint {
+	return geoJSONPoint{
+		Type:        geoJSONPointType,
+		Coordinates: []json.Number{json.Number(location.Longitude.String()), json.Number(location.Latitude.String())},
+	}
 }
 
 type httpError struct {
@@ -72,15 +436,24 @@ func (e httpError) empty(w http.ResponseWriter) {
 }
 
 func errToStatusCode(err error) int {
-	var invalidArgumentErr *domain.ErrInvalidArgument
+	var (
+		invalidArgumentErr *domain.ErrInvalidArgument
+		notFoundErr        *domain.ErrNotFound
+	)
 
 	switch {
 	case errors.As(err, &invalidArgumentErr):
 		return http.StatusUnprocessableEntity
 
-	case errors.Is(err, context.Canceled):
+	case errors.As(err, &notFoundErr):
+		return http.StatusNotFound
+
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 		return http.StatusGatewayTimeout
 
+	case errors.Is(err, errNotAcceptable):
+		return http.StatusNotAcceptable
+
 	default:
 		return http.StatusInternalServerError
 	}
@@ -2,6 +2,8 @@ package http
 
 import (
 	"context"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
 	"github.com/tonytcb/party-invite/pkg/domain"
 	"github.com/stretchr/testify/assert"
 	"io"
@@ -13,7 +15,8 @@ func Test_customersToJSONOutput(t *testing.T) {
 	t.Parallel()
 
 	type args struct {
-		input domain.Customers
+		input             domain.NearCustomers
+		distancePrecision int32
 	}
 	tests := []struct {
 		name    string
@@ -24,7 +27,8 @@ func Test_customersToJSONOutput(t *testing.T) {
 		{
 			name: "should return an empty json when customers is empty",
 			args: args{
-				input: []domain.Customer{},
+				input:             []domain.NearCustomer{},
+				distancePrecision: 3,
 			},
 			want:    []byte(`[]`),
 			wantErr: assert.NoError,
@@ -32,20 +36,332 @@ func Test_customersToJSONOutput(t *testing.T) {
 		{
 			name: "should return a valid json containing 2 customers",
 			args: args{
-				input: []domain.Customer{
-					{
-						ID:       100,
-						Name:     "Tony Tester",
-						Location: domain.DublinLocation,
-					},
-					{
-						ID:       200,
-						Name:     "Jon Doe",
-						Location: domain.DublinLocation,
-					},
+				input: []domain.NearCustomer{
+					domain.NewNearCustomer(
+						domain.NewCustomer(100, "Tony Tester", domain.DublinLocation),
+						decimal.RequireFromString("5.12345"),
+					),
+					domain.NewNearCustomer(
+						domain.NewCustomer(200, "Jon Doe", domain.DublinLocation),
+						decimal.RequireFromString("95"),
+					),
 				},
+				distancePrecision: 3,
 			},
-			want:    []byte(`[{"id":100,"name":"Tony Tester"},{"id":200,"name":"Jon Doe"}]`),
+			want:    []byte(`[{"id":100,"name":"Tony Tester","distance_km":5.123},{"id":200,"name":"Jon Doe","distance_km":95.000}]`),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should round distances to the given precision",
+			args: args{
+				input: []domain.NearCustomer{
+					domain.NewNearCustomer(
+						domain.NewCustomer(100, "Tony Tester", domain.DublinLocation),
+						decimal.RequireFromString("5.6789"),
+					),
+				},
+				distancePrecision: 0,
+			},
+			want:    []byte(`[{"id":100,"name":"Tony Tester","distance_km":6}]`),
+			wantErr: assert.NoError,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := customersToJSONOutput(tt.args.input, tt.args.distancePrecision)
+
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.EqualValues(t, string(tt.want), string(got))
+		})
+	}
+}
+
+func Test_customersReportToJSONOutput(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		input             domain.NearCustomers
+		rejected          domain.RejectedLines
+		duplicatedIDs     []int
+		distancePrecision int32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name: "should return empty lists when there are no customers nor rejected lines",
+			args: args{
+				input:             []domain.NearCustomer{},
+				rejected:          domain.RejectedLines{},
+				distancePrecision: 3,
+			},
+			want:    []byte(`{"customers":[],"rejected":[]}`),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should return the customers and the rejected lines",
+			args: args{
+				input: []domain.NearCustomer{
+					domain.NewNearCustomer(
+						domain.NewCustomer(100, "Tony Tester", domain.DublinLocation),
+						decimal.RequireFromString("5.12345"),
+					),
+				},
+				rejected: []domain.RejectedLine{
+					{Line: 2, Content: `{"user_id": 1`, Reason: "invalid json: unexpected end of JSON input"},
+				},
+				distancePrecision: 3,
+			},
+			want:    []byte(`{"customers":[{"id":100,"name":"Tony Tester","distance_km":5.123}],"rejected":[{"line":2,"content":"{\"user_id\": 1","reason":"invalid json: unexpected end of JSON input"}]}`),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should return the duplicated IDs as warnings, omitting the rejected lines when nil",
+			args: args{
+				input: []domain.NearCustomer{
+					domain.NewNearCustomer(
+						domain.NewCustomer(100, "Tony Tester", domain.DublinLocation),
+						decimal.RequireFromString("5.12345"),
+					),
+				},
+				rejected:          nil,
+				duplicatedIDs:     []int{100, 200},
+				distancePrecision: 3,
+			},
+			want:    []byte(`{"customers":[{"id":100,"name":"Tony Tester","distance_km":5.123}],"warnings":{"duplicated_user_ids":[100,200]}}`),
+			wantErr: assert.NoError,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := customersReportToJSONOutput(
+				tt.args.input,
+				tt.args.rejected,
+				tt.args.duplicatedIDs,
+				tt.args.distancePrecision,
+			)
+
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.EqualValues(t, string(tt.want), string(got))
+		})
+	}
+}
+
+func Test_distanceBandsToJSONOutput(t *testing.T) {
+	t.Parallel()
+
+	bands, err := domain.ParseDistanceBands("25,50.5")
+	if err != nil {
+		t.Fatal("failed to build distance bands")
+	}
+
+	bands[0].Customers = domain.NearCustomers{
+		domain.NewNearCustomer(domain.NewCustomer(100, "Tony Tester", domain.DublinLocation), decimal.RequireFromString("5.12345")),
+		domain.NewNearCustomer(domain.NewCustomer(200, "Ann Tester", domain.DublinLocation), decimal.RequireFromString("25")),
+	}
+	bands[1].Customers = domain.NearCustomers{}
+
+	got, err := distanceBandsToJSONOutput(bands, nil, []int{200}, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"bands":[`+
+		`{"from_km":0,"to_km":25,"count":2,"customers":[{"id":100,"name":"Tony Tester","distance_km":5.123},{"id":200,"name":"Ann Tester","distance_km":25.000}]},`+
+		`{"from_km":25,"to_km":50.5,"count":0,"customers":[]}],`+
+		`"warnings":{"duplicated_user_ids":[200]}}`, string(got))
+}
+
+func Test_officesToJSONOutput(t *testing.T) {
+	t.Parallel()
+
+	cork, err := domain.NewCoordinate("51.897233", "-8.470456")
+	if err != nil {
+		t.Fatal("failed to build coordinate")
+	}
+
+	var groups = []domain.OfficeCustomers{
+		{
+			Office: domain.NewOffice("dublin", domain.DublinLocation, decimal.NewFromInt(100)),
+			Customers: domain.NearCustomers{
+				domain.NewNearCustomer(domain.NewCustomer(100, "Tony Tester", domain.DublinLocation), decimal.RequireFromString("5.12345")),
+			},
+		},
+		{
+			Office:    domain.NewOffice("cork", cork, decimal.RequireFromString("50.5")),
+			Customers: domain.NearCustomers{},
+		},
+	}
+
+	got, err := officesToJSONOutput(groups, domain.RejectedLines{}, []int{100}, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"offices":[`+
+		`{"name":"dublin","radius_km":100,"count":1,"customers":[{"id":100,"name":"Tony Tester","distance_km":5.123}]},`+
+		`{"name":"cork","radius_km":50.5,"count":0,"customers":[]}],`+
+		`"rejected":[],"warnings":{"duplicated_user_ids":[100]}}`, string(got))
+}
+
+func Test_customersToLineOrientedOutputs(t *testing.T) {
+	t.Parallel()
+
+	var input = []domain.NearCustomer{
+		domain.NewNearCustomer(
+			domain.NewCustomer(100, "Tony Tester", domain.DublinLocation),
+			decimal.RequireFromString("5.12345"),
+		),
+		domain.NewNearCustomer(
+			// built directly, as NewCustomer would trim the tab the outputs must sanitise
+			domain.Customer{ID: 200, Name: "Doe, Jon\t", Location: domain.DublinLocation},
+			decimal.RequireFromString("95"),
+		),
+	}
+
+	tests := []struct {
+		name      string
+		output    func(domain.NearCustomers, int32) ([]byte, error)
+		want      string
+		wantEmpty string
+	}{
+		{
+			name:      "json lines",
+			output:    customersToJSONLinesOutput,
+			want:      "{\"id\":100,\"name\":\"Tony Tester\",\"distance_km\":5.123}\n{\"id\":200,\"name\":\"Doe, Jon\\t\",\"distance_km\":95.000}\n",
+			wantEmpty: "",
+		},
+		{
+			name:      "csv",
+			output:    customersToCSVOutput,
+			want:      "id,name,distance_km\n100,Tony Tester,5.123\n200,\"Doe, Jon\t\",95.000\n",
+			wantEmpty: "id,name,distance_km\n",
+		},
+		{
+			name:      "plain text",
+			output:    customersToTextOutput,
+			want:      "100\tTony Tester\t5.123\n200\tDoe, Jon \t95.000\n",
+			wantEmpty: "",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run("should encode customers as "+tt.name, func(t *testing.T) {
+			got, err := tt.output(input, 3)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+
+		t.Run("should encode an empty list as "+tt.name, func(t *testing.T) {
+			got, err := tt.output([]domain.NearCustomer{}, 3)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEmpty, string(got))
+		})
+	}
+}
+
+func Test_customersToGeoJSONOutput(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		input             domain.NearCustomers
+		rejected          domain.RejectedLines
+		duplicatedIDs     []int
+		baseLocation      *domain.Coordinate
+		officeName        string
+		radius            decimal.Decimal
+		distancePrecision int32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name: "should return only the office feature when customers is empty",
+			args: args{
+				input:             []domain.NearCustomer{},
+				baseLocation:      domain.DublinLocation,
+				officeName:        "dublin",
+				radius:            decimal.NewFromInt32(100),
+				distancePrecision: 3,
+			},
+			want:    []byte(`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[-6.257664,53.339428]},"properties":{"kind":"office","name":"dublin","radius_km":100}}]}`),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should omit the radius of the office when there's none, on nearest mode",
+			args: args{
+				input:             []domain.NearCustomer{},
+				baseLocation:      domain.DublinLocation,
+				officeName:        "dublin",
+				radius:            decimal.Zero,
+				distancePrecision: 3,
+			},
+			want:    []byte(`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[-6.257664,53.339428]},"properties":{"kind":"office","name":"dublin"}}]}`),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should return the rejected lines as a foreign member",
+			args: args{
+				input: []domain.NearCustomer{},
+				rejected: []domain.RejectedLine{
+					{Line: 3, Content: `{}`, Reason: "missing geometry"},
+				},
+				baseLocation:      domain.DublinLocation,
+				radius:            decimal.NewFromInt32(100),
+				distancePrecision: 3,
+			},
+			want:    []byte(`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[-6.257664,53.339428]},"properties":{"kind":"office","radius_km":100}}],"rejected":[{"line":3,"content":"{}","reason":"missing geometry"}]}`),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should return the duplicated IDs as a warnings foreign member",
+			args: args{
+				input:             []domain.NearCustomer{},
+				duplicatedIDs:     []int{7},
+				baseLocation:      domain.DublinLocation,
+				radius:            decimal.NewFromInt32(100),
+				distancePrecision: 3,
+			},
+			want:    []byte(`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[-6.257664,53.339428]},"properties":{"kind":"office","radius_km":100}}],"warnings":{"duplicated_user_ids":[7]}}`),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should return the office and customers features",
+			args: args{
+				input: []domain.NearCustomer{
+					domain.NewNearCustomer(
+						domain.NewCustomer(100, "Tony Tester", &domain.Coordinate{
+							Latitude:  decimal.RequireFromString("53.2451022"),
+							Longitude: decimal.RequireFromString("-6.238335"),
+						}),
+						decimal.RequireFromString("10.56693"),
+					),
+				},
+				baseLocation:      domain.DublinLocation,
+				radius:            decimal.RequireFromString("50.5"),
+				distancePrecision: 2,
+			},
+			want:    []byte(`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[-6.257664,53.339428]},"properties":{"kind":"office","radius_km":50.5}},{"type":"Feature","geometry":{"type":"Point","coordinates":[-6.238335,53.2451022]},"properties":{"kind":"customer","user_id":100,"name":"Tony Tester","distance_km":10.57}}]}`),
 			wantErr: assert.NoError,
 		},
 	}
@@ -54,7 +370,15 @@ func Test_customersToJSONOutput(t *testing.T) {
 		tt := tt
 
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := customersToJSONOutput(tt.args.input)
+			got, err := customersToGeoJSONOutput(
+				tt.args.input,
+				tt.args.rejected,
+				tt.args.duplicatedIDs,
+				tt.args.baseLocation,
+				tt.args.officeName,
+				tt.args.radius,
+				tt.args.distancePrecision,
+			)
 
 			tt.wantErr(t, err)
 			if err != nil {
@@ -84,6 +408,13 @@ func Test_errToHttpErrorCode(t *testing.T) {
 			},
 			want: http.StatusUnprocessableEntity,
 		},
+		{
+			name: "should return StatusNotFound http status code",
+			args: args{
+				err: errors.Wrap(domain.NewErrNotFound("dataset", "1"), "error to read dataset"),
+			},
+			want: http.StatusNotFound,
+		},
 		{
 			name: "should return StatusGatewayTimeout http status code",
 			args: args{
@@ -91,6 +422,20 @@ func Test_errToHttpErrorCode(t *testing.T) {
 			},
 			want: http.StatusGatewayTimeout,
 		},
+		{
+			name: "should return StatusGatewayTimeout http status code when the deadline is exceeded",
+			args: args{
+				err: context.DeadlineExceeded,
+			},
+			want: http.StatusGatewayTimeout,
+		},
+		{
+			name: "should return StatusNotAcceptable http status code",
+			args: args{
+				err: errNotAcceptable,
+			},
+			want: http.StatusNotAcceptable,
+		},
 		{
 			name: "should return StatusInternalServerError http status code for unknown errors",
 			args: args{
@@ -14,15 +14,18 @@ type Server struct {
 	httpServer *http.Server
 
 	filterCustomersHandler *FilterCustomersHandler
+	datasetsHandler        *DatasetsHandler
 }
 
 func NewServer(
 	log logger.Logger,
 	filterCustomersHandler *FilterCustomersHandler,
+	datasetsHandler *DatasetsHandler,
 ) *Server {
 	return &Server{
 		log:                    log,
 		filterCustomersHandler: filterCustomersHandler,
+		datasetsHandler:        datasetsHandler,
 	}
 }
 
@@ -30,6 +33,8 @@ func (s *Server) Start(port int) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.healthHandler)
 	mux.HandleFunc("/filter-customers", s.filterCustomersHandler.Handle)
+	mux.HandleFunc(datasetsPath, s.datasetsHandler.Handle)
+	mux.HandleFunc(datasetsPath+"/", s.datasetsHandler.Handle)
 
 	s.log.Infof("Starting HTTP Server on port %d", port)
 
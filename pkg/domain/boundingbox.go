@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// BoundingBox is a latitude/longitude rectangle, in degrees, enclosing every location within a radius from a center.
+// It's a cheap way to discard locations before calculating their exact distance: a location outside the box is
+// always farther than the radius, while a location inside it may still be farther.
+type BoundingBox struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+// NewBoundingBox builds the bounding box of the given radius, in kilometers, around the center. When the box crosses
+// the antimeridian, MinLongitude is greater than MaxLongitude, and when it reaches a pole it covers all longitudes.
+func NewBoundingBox(center *Coordinate, radius decimal.Decimal) BoundingBox {
+	const (
+		maxLatitude  = 90
+		maxLongitude = 180
+		angle        = 180
+	)
+
+	var (
+		latitude  = center.Latitude.InexactFloat64()
+		longitude = center.Longitude.InexactFloat64()
+		distance  = radius.InexactFloat64() * ellipsoidMargin / earthRadiusInKm // angular distance, in radians
+		box       = BoundingBox{
+			MinLatitude:  latitude - distance*angle/math.Pi,
+			MaxLatitude:  latitude + distance*angle/math.Pi,
+			MinLongitude: -maxLongitude,
+			MaxLongitude: maxLongitude,
+		}
+	)
+
+	if box.MinLatitude <= -maxLatitude || box.MaxLatitude >= maxLatitude {
+		// a pole is within the radius, so are all longitudes
+		box.MinLatitude = math.Max(box.MinLatitude, -maxLatitude)
+		box.MaxLatitude = math.Min(box.MaxLatitude, maxLatitude)
+
+		return box
+	}
+
+	// the meridians tangent to the circle, see http://janmatuschek.de/LatitudeLongitudeBoundingCoordinates
+	var deltaLongitude = math.Asin(math.Sin(distance)/math.Cos(latitude*math.Pi/angle)) * angle / math.Pi
+
+	box.MinLongitude = longitude - deltaLongitude
+	box.MaxLongitude = longitude + deltaLongitude
+
+	if box.MinLongitude < -maxLongitude {
+		box.MinLongitude += 2 * maxLongitude
+	}
+	if box.MaxLongitude > maxLongitude {
+		box.MaxLongitude -= 2 * maxLongitude
+	}
+
+	return box
+}
+
+// Contains tells whether the location is inside the box.
+func (b BoundingBox) Contains(location *Coordinate) bool {
+	var latitude = location.Latitude.InexactFloat64()
+
+	if latitude < b.MinLatitude || latitude > b.MaxLatitude {
+		return false
+	}
+
+	var longitude = location.Longitude.InexactFloat64()
+
+	if b.MinLongitude > b.MaxLongitude { // crosses the antimeridian
+		return longitude >= b.MinLongitude || longitude <= b.MaxLongitude
+	}
+
+	return longitude >= b.MinLongitude && longitude <= b.MaxLongitude
+}
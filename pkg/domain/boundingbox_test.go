@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundingBox_Contains(t *testing.T) {
+	t.Parallel()
+
+	var coordinate = func(latitude, longitude string) *Coordinate {
+		c, err := NewCoordinate(latitude, longitude)
+		if err != nil {
+			t.Fatal("failed to build coordinate")
+		}
+
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		center   *Coordinate
+		radius   decimal.Decimal
+		location *Coordinate
+		want     bool
+	}{
+		{
+			name:     "should contain the center",
+			center:   DublinLocation,
+			radius:   decimal.NewFromInt32(100),
+			location: DublinLocation,
+			want:     true,
+		},
+		{
+			name:     "should contain a location near the north edge",
+			center:   DublinLocation,
+			radius:   decimal.NewFromInt32(100),
+			location: coordinate("54.2385", "-6.257664"),
+			want:     true,
+		},
+		{
+			name:     "should not contain a location beyond the north edge",
+			center:   DublinLocation,
+			radius:   decimal.NewFromInt32(100),
+			location: coordinate("54.3", "-6.257664"),
+			want:     false,
+		},
+		{
+			name:     "should not contain a location beyond the east edge",
+			center:   DublinLocation,
+			radius:   decimal.NewFromInt32(100),
+			location: coordinate("53.339428", "-4.7"),
+			want:     false,
+		},
+		{
+			name:     "should contain a location across the antimeridian",
+			center:   coordinate("-16.5", "179.9"),
+			radius:   decimal.NewFromInt32(100),
+			location: coordinate("-16.5", "-179.5"),
+			want:     true,
+		},
+		{
+			name:     "should not contain a location on the other side of the world across the antimeridian",
+			center:   coordinate("-16.5", "179.9"),
+			radius:   decimal.NewFromInt32(100),
+			location: coordinate("-16.5", "0"),
+			want:     false,
+		},
+		{
+			name:     "should contain any longitude when a pole is within the radius",
+			center:   coordinate("89.5", "0"),
+			radius:   decimal.NewFromInt32(100),
+			location: coordinate("89.5", "180"),
+			want:     true,
+		},
+		{
+			name:     "should contain the whole world for a radius longer than half the earth circumference",
+			center:   DublinLocation,
+			radius:   decimal.NewFromInt32(25_000),
+			location: coordinate("-53.339428", "173.742336"),
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewBoundingBox(tt.center, tt.radius).Contains(tt.location)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestBoundingBox_neverDiscardsNearLocations checks, for every algorithm, that random locations within the radius
+// are always inside the box, so prefiltering by the box never changes a result.
+func TestBoundingBox_neverDiscardsNearLocations(t *testing.T) {
+	t.Parallel()
+
+	var (
+		random  = rand.New(rand.NewSource(42)) //nolint:gosec // deterministic test data
+		centers = []*Coordinate{
+			DublinLocation,
+			{Latitude: decimal.NewFromInt(0), Longitude: decimal.NewFromInt(0)},
+			{Latitude: decimal.RequireFromString("-16.5"), Longitude: decimal.RequireFromString("179.9")},
+			{Latitude: decimal.RequireFromString("78.2"), Longitude: decimal.RequireFromString("15.6")},
+			{Latitude: decimal.RequireFromString("-89.1"), Longitude: decimal.RequireFromString("-120")},
+		}
+		calculators = []DistanceCalculator{
+			HaversineDistance{},
+			SphericalLawOfCosinesDistance{},
+			VincentyDistance{},
+			FloatHaversineDistance{},
+		}
+		radiuses = []decimal.Decimal{decimal.NewFromInt(10), decimal.NewFromInt(100), decimal.NewFromInt(1_000)}
+	)
+
+	randomAround := func(center *Coordinate, spread float64) *Coordinate {
+		var (
+			latitude  = center.Latitude.InexactFloat64() + (random.Float64()*2-1)*spread
+			longitude = center.Longitude.InexactFloat64() + (random.Float64()*2-1)*spread*2
+		)
+
+		latitude = max(-90, min(90, latitude))
+		if longitude > 180 {
+			longitude -= 360
+		}
+		if longitude < -180 {
+			longitude += 360
+		}
+
+		location, err := NewCoordinate(
+			strconv.FormatFloat(latitude, 'f', 6, 64),
+			strconv.FormatFloat(longitude, 'f', 6, 64),
+		)
+		if err != nil {
+			t.Fatal("failed to build coordinate")
+		}
+
+		return location
+	}
+
+	for _, center := range centers {
+		for _, radius := range radiuses {
+			var (
+				box    = NewBoundingBox(center, radius)
+				spread = radius.InexactFloat64() / 100 // a bit farther than the radius, in degrees
+			)
+
+			for i := 0; i < 200; i++ {
+				location := randomAround(center, spread)
+
+				for _, calculator := range calculators {
+					if calculator.Distance(center, location).GreaterThan(radius) {
+						continue
+					}
+
+					assert.Truef(
+						t,
+						box.Contains(location),
+						"%T: %v within %s km from %v but outside %+v",
+						calculator, location, radius, center, box,
+					)
+				}
+			}
+		}
+	}
+}
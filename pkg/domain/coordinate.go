@@ -1,16 +1,30 @@
 package domain
 
 import (
-	"github.com/pkg/errors"
+	"fmt"
+
 	"github.com/shopspring/decimal"
 )
 
+var (
+	maxLatitude  = decimal.NewFromInt(90)  //nolint:gomnd
+	maxLongitude = decimal.NewFromInt(180) //nolint:gomnd
+)
+
 type Coordinate struct {
 	Latitude  decimal.Decimal
 	Longitude decimal.Decimal
 }
 
 func NewCoordinate(latitude string, longitude string) (*Coordinate, error) {
+	if latitude == "" {
+		return nil, NewErrInvalidArgument("is required", "invalid latitude")
+	}
+
+	if longitude == "" {
+		return nil, NewErrInvalidArgument("is required", "invalid longitude")
+	}
+
 	latitudeDecimal, err := decimal.NewFromString(latitude)
 	if err != nil {
 		return nil, NewErrInvalidArgument(err.Error(), "invalid latitude")
@@ -18,7 +32,15 @@ func NewCoordinate(latitude string, longitude string) (*Coordinate, error) {
 
 	longitudeDecimal, err := decimal.NewFromString(longitude)
 	if err != nil {
-		return nil, errors.Wrapf(err, "invalid longitude (%s) value as number", longitude)
+		return nil, NewErrInvalidArgument(err.Error(), "invalid longitude")
+	}
+
+	if err = validateRange(latitudeDecimal, maxLatitude); err != nil {
+		return nil, NewErrInvalidArgument(err.Error(), "invalid latitude")
+	}
+
+	if err = validateRange(longitudeDecimal, maxLongitude); err != nil {
+		return nil, NewErrInvalidArgument(err.Error(), "invalid longitude")
 	}
 
 	return &Coordinate{
@@ -27,8 +49,18 @@ func NewCoordinate(latitude string, longitude string) (*Coordinate, error) {
 	}, nil
 }
 
+// validateRange checks the value is within [-limit, limit], boundaries included.
+func validateRange(value decimal.Decimal, limit decimal.Decimal) error {
+	if value.Abs().GreaterThan(limit) {
+		return fmt.Errorf("%s is out of range [%s, %s]", value.String(), limit.Neg().String(), limit.String())
+	}
+
+	return nil
+}
+
+// Difference calculates the distance, in kilometers, to another coordinate applying the Haversine formula.
 func (c *Coordinate) Difference(c2 *Coordinate) decimal.Decimal {
-	return distance(c, c2)
+	return haversine(c, c2)
 }
 
 var (
@@ -1,10 +1,10 @@
 package domain
 
 import (
-	"github.com/pkg/errors"
+	"testing"
+
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
-	"testing"
 )
 
 // To validate the outputs was used the https://latlongdata.com/distance-calculator/ website.
@@ -132,7 +132,7 @@ func TestNewCoordinate(t *testing.T) {
 			},
 			want: nil,
 			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
-				return assert.ErrorContains(t, err, "invalid latitude") && errors.As(err, &ErrInvalidArgument{})
+				return isInvalidArgument(t, err, "invalid latitude")
 			},
 		},
 		{
@@ -143,7 +143,97 @@ func TestNewCoordinate(t *testing.T) {
 			},
 			want: nil,
 			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
-				return assert.ErrorContains(t, err, "invalid longitude") && errors.Is(err, &ErrInvalidArgument{})
+				return isInvalidArgument(t, err, "invalid longitude")
+			},
+		},
+		{
+			name: "should error on missing latitude",
+			args: args{
+				latitude:  "",
+				longitude: "-46.625290",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid latitude: is required")
+			},
+		},
+		{
+			name: "should error on missing longitude",
+			args: args{
+				latitude:  "-23.533773",
+				longitude: "",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid longitude: is required")
+			},
+		},
+		{
+			name: "should accept the boundary values",
+			args: args{
+				latitude:  "-90",
+				longitude: "180",
+			},
+			want: &Coordinate{
+				Latitude:  decimal.RequireFromString("-90"),
+				Longitude: decimal.RequireFromString("180"),
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should accept the opposite boundary values",
+			args: args{
+				latitude:  "90",
+				longitude: "-180",
+			},
+			want: &Coordinate{
+				Latitude:  decimal.RequireFromString("90"),
+				Longitude: decimal.RequireFromString("-180"),
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should error on latitude above the range",
+			args: args{
+				latitude:  "90.000001",
+				longitude: "-46.625290",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid latitude: 90.000001 is out of range [-90, 90]")
+			},
+		},
+		{
+			name: "should error on latitude below the range",
+			args: args{
+				latitude:  "-123",
+				longitude: "-46.625290",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid latitude: -123 is out of range [-90, 90]")
+			},
+		},
+		{
+			name: "should error on longitude above the range",
+			args: args{
+				latitude:  "-23.533773",
+				longitude: "180.5",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid longitude: 180.5 is out of range [-180, 180]")
+			},
+		},
+		{
+			name: "should error on longitude below the range",
+			args: args{
+				latitude:  "-23.533773",
+				longitude: "-500",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid longitude: -500 is out of range [-180, 180]")
 			},
 		},
 	}
@@ -159,3 +249,9 @@ func TestNewCoordinate(t *testing.T) {
 		})
 	}
 }
+
+func isInvalidArgument(t assert.TestingT, err error, message string) bool {
+	var invalidArgumentErr *ErrInvalidArgument
+
+	return assert.ErrorContains(t, err, message) && assert.ErrorAs(t, err, &invalidArgumentErr)
+}
@@ -1,13 +1,48 @@
 package domain
 
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxCustomerNameLength is the maximum number of characters of a customer name.
+const MaxCustomerNameLength = 128
+
 type Customer struct {
 	ID       int
 	Name     string
 	Location *Coordinate
 }
 
+// NewCustomer builds a customer, trimming the spaces around its name.
 func NewCustomer(id int, name string, location *Coordinate) Customer {
-	return Customer{ID: id, Name: name, Location: location}
+	return Customer{ID: id, Name: strings.TrimSpace(name), Location: location}
+}
+
+// Validate checks the customer has a positive ID, a non-empty name up to MaxCustomerNameLength characters, and a
+// location. The returned ErrInvalidArgument names the offending field.
+func (c Customer) Validate() error {
+	switch {
+	case c.ID <= 0:
+		return NewErrInvalidArgument(fmt.Sprintf("must be positive, got %d", c.ID), "invalid user_id")
+
+	case strings.TrimSpace(c.Name) == "":
+		return NewErrInvalidArgument("is required", "invalid name")
+
+	case utf8.RuneCountInString(c.Name) > MaxCustomerNameLength:
+		return NewErrInvalidArgument(
+			fmt.Sprintf("must have at most %d characters", MaxCustomerNameLength),
+			"invalid name",
+		)
+
+	case c.Location == nil:
+		return NewErrInvalidArgument("is required", "invalid location")
+	}
+
+	return nil
 }
 
 func (c Customer) WithLocation(location *Coordinate) Customer {
@@ -15,3 +50,15 @@ func (c Customer) WithLocation(location *Coordinate) Customer {
 }
 
 type Customers []Customer
+
+// NearCustomer is a customer together with its distance, in kilometers, from a base location.
+type NearCustomer struct {
+	Customer
+	Distance decimal.Decimal
+}
+
+func NewNearCustomer(customer Customer, distance decimal.Decimal) NearCustomer {
+	return NearCustomer{Customer: customer, Distance: distance}
+}
+
+type NearCustomers []NearCustomer
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomer_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		customer Customer
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "should accept a valid customer",
+			customer: NewCustomer(1, "Tony Tester", DublinLocation),
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "should accept a name with the maximum length",
+			customer: NewCustomer(1, strings.Repeat("ã", MaxCustomerNameLength), DublinLocation),
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "should error on zero id",
+			customer: NewCustomer(0, "Tony Tester", DublinLocation),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid user_id: must be positive, got 0")
+			},
+		},
+		{
+			name:     "should error on negative id",
+			customer: NewCustomer(-3, "Tony Tester", DublinLocation),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid user_id: must be positive, got -3")
+			},
+		},
+		{
+			name:     "should error on blank name",
+			customer: NewCustomer(1, " \t ", DublinLocation),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid name: is required")
+			},
+		},
+		{
+			name:     "should error on too long name",
+			customer: NewCustomer(1, strings.Repeat("a", MaxCustomerNameLength+1), DublinLocation),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid name: must have at most 128 characters")
+			},
+		},
+		{
+			name:     "should error on missing location",
+			customer: NewCustomer(1, "Tony Tester", nil),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid location: is required")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			tt.wantErr(t, tt.customer.Validate())
+		})
+	}
+}
+
+func TestNewCustomer(t *testing.T) {
+	t.Parallel()
+
+	customer := NewCustomer(1, "  Tony Tester\n", DublinLocation)
+
+	assert.Equal(t, "Tony Tester", customer.Name)
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// Dataset is a customers list uploaded once to be queried many times.
+type Dataset struct {
+	ID        string
+	FileName  string
+	CreatedAt time.Time
+	Customers Customers
+	Rejected  RejectedLines // only present when the file was parsed on lenient mode
+}
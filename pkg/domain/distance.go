@@ -1,11 +1,14 @@
 package domain
 
-// To calculate the distance between two points, we are using the Haversine formula described
+// To calculate the distance between two points, by default we are using the Haversine formula described
 // here https://en.wikipedia.org/wiki/Haversine_formula, and here https://en.wikipedia.org/wiki/Great-circle_distance
-// you can read more about the Great-circle Distance (shortest distance).
+// you can read more about the Great-circle Distance (shortest distance). The spherical law of cosines, from the same
+// article, and the Vincenty formulae over the WGS-84 ellipsoid, https://en.wikipedia.org/wiki/Vincenty%27s_formulae,
+// are available as alternatives.
 //
 // Instead of making all math using float numbers, we are using the shopspring/decimal package,
-// avoiding floating point number precision issues.
+// avoiding floating point number precision issues. Since it's costly, float implementations of the spherical
+// formulas are available as a fast path for large inputs, still precise to fractions of a meter.
 
 import (
 	"math"
@@ -15,14 +18,62 @@ import (
 
 const (
 	earthRadiusInKm = 6371
+
+	// ellipsoidMargin bounds the relative difference between the distances over the WGS-84 ellipsoid and over the
+	// sphere, of up to ~0.6% either way, enlarging the spherical radiuses used to discard locations cheaply.
+	ellipsoidMargin = 1.01
+
+	// DefaultDistancePrecision is the number of decimal places used to present distances.
+	DefaultDistancePrecision = 3
 )
 
 var (
 	pi = decimal.NewFromFloat(math.Pi)
 )
 
-// distance calculates the distance, in kilometers, between two coordinates applying the Haversine formula.
-func distance(c1 *Coordinate, c2 *Coordinate) decimal.Decimal {
+// DistanceCalculator calculates the distance, in kilometers, between two coordinates.
+type DistanceCalculator interface {
+	Distance(c1 *Coordinate, c2 *Coordinate) decimal.Decimal
+}
+
+// HaversineDistance calculates distances over a sphere applying the Haversine formula.
+type HaversineDistance struct{}
+
+func (HaversineDistance) Distance(c1 *Coordinate, c2 *Coordinate) decimal.Decimal {
+	return haversine(c1, c2)
+}
+
+// SphericalLawOfCosinesDistance calculates distances over a sphere applying the spherical law of cosines.
+type SphericalLawOfCosinesDistance struct{}
+
+func (SphericalLawOfCosinesDistance) Distance(c1 *Coordinate, c2 *Coordinate) decimal.Decimal {
+	return sphericalLawOfCosines(c1, c2)
+}
+
+// VincentyDistance calculates distances over the WGS-84 ellipsoid applying the Vincenty inverse formula.
+type VincentyDistance struct{}
+
+func (VincentyDistance) Distance(c1 *Coordinate, c2 *Coordinate) decimal.Decimal {
+	return vincenty(c1, c2)
+}
+
+// FloatHaversineDistance calculates distances over a sphere applying the Haversine formula with float numbers.
+type FloatHaversineDistance struct{}
+
+func (FloatHaversineDistance) Distance(c1 *Coordinate, c2 *Coordinate) decimal.Decimal {
+	return decimal.NewFromFloat(floatHaversine(c1, c2))
+}
+
+// FloatSphericalLawOfCosinesDistance calculates distances over a sphere applying the spherical law of cosines with
+// float numbers.
+type FloatSphericalLawOfCosinesDistance struct{}
+
+func (FloatSphericalLawOfCosinesDistance) Distance(c1 *Coordinate, c2 *Coordinate) decimal.Decimal {
+	return decimal.NewFromFloat(floatSphericalLawOfCosines(c1, c2))
+}
+
+// haversine calculates the distance, in kilometers, between two coordinates applying the Haversine formula.
+func haversine(c1 *Coordinate, c2 *Coordinate) decimal.Decimal {
 	var (
 		earthRadius     = decimal.NewFromInt32(earthRadiusInKm)
 		oneDecimalValue = decimal.NewFromInt32(1) // nolint:golint,gomnd // it's only a definition of the one as decimal type
@@ -50,6 +101,61 @@ func distance(c1 *Coordinate, c2 *Coordinate) decimal.Decimal {
 	return arcTangent.Mul(earthRadius)
 }
 
+// sphericalLawOfCosines calculates the distance, in kilometers, between two coordinates applying the spherical law
+// of cosines: d = r * acos(sin φ1 * sin φ2 + cos φ1 * cos φ2 * cos Δλ).
+func sphericalLawOfCosines(c1 *Coordinate, c2 *Coordinate) decimal.Decimal {
+	var (
+		earthRadius = decimal.NewFromInt32(earthRadiusInKm)
+
+		latitude1     = toRadians(c1.Latitude)
+		latitude2     = toRadians(c2.Latitude)
+		longitudeDiff = toRadians(c2.Longitude).Add(toRadians(c1.Longitude).Neg())
+
+		cosine = latitude1.Sin().Mul(latitude2.Sin()).
+			Add(latitude1.Cos().Mul(latitude2.Cos()).Mul(longitudeDiff.Cos()))
+	)
+
+	return decimalAcos(cosine).Mul(earthRadius)
+}
+
+// floatHaversine is the float version of haversine.
+func floatHaversine(c1 *Coordinate, c2 *Coordinate) float64 {
+	var (
+		latitude1, longitude1 = toFloatRadians(c1)
+		latitude2, longitude2 = toFloatRadians(c2)
+
+		sinLatitudeDiff  = math.Sin((latitude2 - latitude1) / 2)   //nolint:gomnd // half of the angle
+		sinLongitudeDiff = math.Sin((longitude2 - longitude1) / 2) //nolint:gomnd // half of the angle
+
+		a = sinLatitudeDiff*sinLatitudeDiff + math.Cos(latitude1)*math.Cos(latitude2)*sinLongitudeDiff*sinLongitudeDiff
+	)
+
+	return 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a)) * earthRadiusInKm //nolint:gomnd // twice the arc tangent
+}
+
+// floatSphericalLawOfCosines is the float version of sphericalLawOfCosines.
+func floatSphericalLawOfCosines(c1 *Coordinate, c2 *Coordinate) float64 {
+	var (
+		latitude1, longitude1 = toFloatRadians(c1)
+		latitude2, longitude2 = toFloatRadians(c2)
+
+		cosine = math.Sin(latitude1)*math.Sin(latitude2) +
+			math.Cos(latitude1)*math.Cos(latitude2)*math.Cos(longitude2-longitude1)
+	)
+
+	return math.Acos(math.Max(-1, math.Min(1, cosine))) * earthRadiusInKm
+}
+
+// toFloatRadians converts the coordinate latitude and longitude to radians as float numbers.
+func toFloatRadians(c *Coordinate) (float64, float64) {
+	const angle = 180
+
+	latitude, _ := c.Latitude.Float64()
+	longitude, _ := c.Longitude.Float64()
+
+	return latitude * math.Pi / angle, longitude * math.Pi / angle
+}
+
 // toRadians converts a degree value in radians.
 func toRadians(degreeValue decimal.Decimal) decimal.Decimal {
 	const angle = 180
@@ -63,7 +169,14 @@ func decimalAtan2(v1 decimal.Decimal, v2 decimal.Decimal) decimal.Decimal {
 	return decimal.NewFromFloat(math.Atan2(fSqrtV1, fSqrtV2))
 }
 
-// decimalAtan2 makes use of the native math.Sqrt (square root) converting its result to Decimal type.
+// decimalAcos makes use of the native math.Acos (arc cosine) converting its result to Decimal type. The value is
+// clamped to [-1, 1], since rounding errors may push it slightly out of the arc cosine domain.
+func decimalAcos(v decimal.Decimal) decimal.Decimal {
+	fValue, _ := v.Float64()
+	return decimal.NewFromFloat(math.Acos(math.Max(-1, math.Min(1, fValue))))
+}
+
+// decimalSqrt makes use of the native math.Sqrt (square root) converting its result to Decimal type.
 func decimalSqrt(v decimal.Decimal) decimal.Decimal {
 	fValue, _ := v.Float64() // ignoring loss precision here
 	return decimal.NewFromFloat(math.Sqrt(fValue))
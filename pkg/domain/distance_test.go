@@ -0,0 +1,257 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistanceCalculators(t *testing.T) {
+	t.Parallel()
+
+	const decimalPlaces = 3
+
+	var (
+		winnipeg = &Coordinate{
+			Latitude:  decimal.RequireFromString("49.895077"),
+			Longitude: decimal.RequireFromString("-97.138451"),
+		}
+		regina = &Coordinate{
+			Latitude:  decimal.RequireFromString("50.445210"),
+			Longitude: decimal.RequireFromString("-104.618896"),
+		}
+		// Flinders Peak and Buninyong, the reference points of the Vincenty paper.
+		flindersPeak = &Coordinate{
+			Latitude:  decimal.RequireFromString("-37.951033416666667"),
+			Longitude: decimal.RequireFromString("144.424867888888889"),
+		}
+		buninyong = &Coordinate{
+			Latitude:  decimal.RequireFromString("-37.652821138888889"),
+			Longitude: decimal.RequireFromString("143.926495527777778"),
+		}
+		equator1 = &Coordinate{Latitude: decimal.Zero, Longitude: decimal.Zero}
+		equator2 = &Coordinate{Latitude: decimal.Zero, Longitude: decimal.NewFromInt(1)}
+		antipode = &Coordinate{Latitude: decimal.NewFromFloat(0.5), Longitude: decimal.NewFromFloat(179.7)}
+	)
+
+	tests := []struct {
+		name       string
+		calculator DistanceCalculator
+		c1         *Coordinate
+		c2         *Coordinate
+		want       string
+	}{
+		{
+			name:       "haversine should calculate the distance between Winnipeg and Regina",
+			calculator: HaversineDistance{},
+			c1:         winnipeg,
+			c2:         regina,
+			want:       "536.036",
+		},
+		{
+			name:       "law of cosines should agree with haversine between Winnipeg and Regina",
+			calculator: SphericalLawOfCosinesDistance{},
+			c1:         winnipeg,
+			c2:         regina,
+			want:       "536.036",
+		},
+		{
+			name:       "law of cosines should return zero for the same coordinate",
+			calculator: SphericalLawOfCosinesDistance{},
+			c1:         DublinLocation,
+			c2:         DublinLocation,
+			want:       "0.000",
+		},
+		{
+			name:       "float haversine should calculate the distance between Winnipeg and Regina",
+			calculator: FloatHaversineDistance{},
+			c1:         winnipeg,
+			c2:         regina,
+			want:       "536.036",
+		},
+		{
+			name:       "float law of cosines should calculate the distance between Winnipeg and Regina",
+			calculator: FloatSphericalLawOfCosinesDistance{},
+			c1:         winnipeg,
+			c2:         regina,
+			want:       "536.036",
+		},
+		{
+			name:       "vincenty should calculate the distance between Flinders Peak and Buninyong",
+			calculator: VincentyDistance{},
+			c1:         flindersPeak,
+			c2:         buninyong,
+			want:       "54.972",
+		},
+		{
+			name:       "vincenty should calculate the distance between Winnipeg and Regina",
+			calculator: VincentyDistance{},
+			c1:         winnipeg,
+			c2:         regina,
+			want:       "537.680",
+		},
+		{
+			name:       "vincenty should calculate the distance of one degree along the equator",
+			calculator: VincentyDistance{},
+			c1:         equator1,
+			c2:         equator2,
+			want:       "111.319",
+		},
+		{
+			name:       "vincenty should return zero for the same coordinate",
+			calculator: VincentyDistance{},
+			c1:         DublinLocation,
+			c2:         DublinLocation,
+			want:       "0.000",
+		},
+		{
+			name:       "vincenty should fall back to haversine on nearly antipodal points",
+			calculator: VincentyDistance{},
+			c1:         equator1,
+			c2:         antipode,
+			want:       HaversineDistance{}.Distance(equator1, antipode).StringFixed(decimalPlaces),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.calculator.Distance(tt.c1, tt.c2)
+
+			assert.Equal(t, tt.want, got.StringFixed(decimalPlaces))
+		})
+	}
+}
+
+func TestNewDistanceAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name               string
+		input              string
+		want               DistanceAlgorithm
+		wantCalculator     DistanceCalculator
+		wantFastCalculator DistanceCalculator
+		wantErr            assert.ErrorAssertionFunc
+	}{
+		{
+			name:               "should parse haversine",
+			input:              "haversine",
+			want:               DistanceAlgorithmHaversine,
+			wantCalculator:     HaversineDistance{},
+			wantFastCalculator: FloatHaversineDistance{},
+			wantErr:            assert.NoError,
+		},
+		{
+			name:               "should parse the spherical law of cosines",
+			input:              "law_of_cosines",
+			want:               DistanceAlgorithmSphericalLawOfCosines,
+			wantCalculator:     SphericalLawOfCosinesDistance{},
+			wantFastCalculator: FloatSphericalLawOfCosinesDistance{},
+			wantErr:            assert.NoError,
+		},
+		{
+			name:               "should parse vincenty",
+			input:              "vincenty",
+			want:               DistanceAlgorithmVincenty,
+			wantCalculator:     VincentyDistance{},
+			wantFastCalculator: VincentyDistance{},
+			wantErr:            assert.NoError,
+		},
+		{
+			name:               "should error on unknown algorithm",
+			input:              "manhattan",
+			want:               0,
+			wantCalculator:     HaversineDistance{},
+			wantFastCalculator: FloatHaversineDistance{},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid distance algorithm")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDistanceAlgorithm(tt.input)
+
+			tt.wantErr(t, err)
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantCalculator, got.Calculator())
+			assert.Equal(t, tt.wantFastCalculator, got.FastCalculator())
+		})
+	}
+}
+
+// distanceFixtures are the pairs of coordinates used to compare the decimal and float implementations.
+var distanceFixtures = [][2]*Coordinate{
+	{DublinLocation, {Latitude: decimal.RequireFromString("53.2451022"), Longitude: decimal.RequireFromString("-6.238335")}},
+	{DublinLocation, {Latitude: decimal.RequireFromString("-23.533773"), Longitude: decimal.RequireFromString("-46.625290")}},
+	{
+		{Latitude: decimal.RequireFromString("49.895077"), Longitude: decimal.RequireFromString("-97.138451")},
+		{Latitude: decimal.RequireFromString("50.445210"), Longitude: decimal.RequireFromString("-104.618896")},
+	},
+	{
+		{Latitude: decimal.RequireFromString("-23.533773"), Longitude: decimal.RequireFromString("-46.625290")},
+		{Latitude: decimal.RequireFromString("2.2945"), Longitude: decimal.RequireFromString("48.8584")},
+	},
+	{DublinLocation, DublinLocation},
+}
+
+func TestFloatDistanceCalculators(t *testing.T) {
+	t.Parallel()
+
+	// a millimeter, in kilometers
+	var tolerance = decimal.RequireFromString("0.000001")
+
+	tests := []struct {
+		name    string
+		decimal DistanceCalculator
+		float   DistanceCalculator
+	}{
+		{name: "haversine", decimal: HaversineDistance{}, float: FloatHaversineDistance{}},
+		{name: "law of cosines", decimal: SphericalLawOfCosinesDistance{}, float: FloatSphericalLawOfCosinesDistance{}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run("float "+tt.name+" should agree with the decimal implementation", func(t *testing.T) {
+			for _, fixture := range distanceFixtures {
+				want := tt.decimal.Distance(fixture[0], fixture[1])
+				got := tt.float.Distance(fixture[0], fixture[1])
+
+				assert.True(t, want.Sub(got).Abs().LessThanOrEqual(tolerance), "want %s, got %s", want, got)
+			}
+		})
+	}
+}
+
+func BenchmarkDistanceCalculators(b *testing.B) {
+	calculators := []struct {
+		name       string
+		calculator DistanceCalculator
+	}{
+		{name: "haversine/decimal", calculator: HaversineDistance{}},
+		{name: "haversine/float", calculator: FloatHaversineDistance{}},
+		{name: "law_of_cosines/decimal", calculator: SphericalLawOfCosinesDistance{}},
+		{name: "law_of_cosines/float", calculator: FloatSphericalLawOfCosinesDistance{}},
+		{name: "vincenty/float", calculator: VincentyDistance{}},
+	}
+
+	for _, c := range calculators {
+		c := c
+
+		b.Run(c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, fixture := range distanceFixtures {
+					c.calculator.Distance(fixture[0], fixture[1])
+				}
+			}
+		})
+	}
+}
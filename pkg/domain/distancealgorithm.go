@@ -0,0 +1,72 @@
+package domain
+
+import "fmt"
+
+// DistanceAlgorithm identifies the formula used to calculate distances between coordinates.
+type DistanceAlgorithm int
+
+const (
+	// DistanceAlgorithmHaversine applies the Haversine formula over a sphere.
+	DistanceAlgorithmHaversine DistanceAlgorithm = iota
+	// DistanceAlgorithmSphericalLawOfCosines applies the spherical law of cosines over a sphere.
+	DistanceAlgorithmSphericalLawOfCosines
+	// DistanceAlgorithmVincenty applies the Vincenty inverse formula over the WGS-84 ellipsoid.
+	DistanceAlgorithmVincenty
+)
+
+var distanceAlgorithmNames = map[DistanceAlgorithm]string{
+	DistanceAlgorithmHaversine:             "haversine",
+	DistanceAlgorithmSphericalLawOfCosines: "law_of_cosines",
+	DistanceAlgorithmVincenty:              "vincenty",
+}
+
+var distanceCalculators = map[DistanceAlgorithm]DistanceCalculator{
+	DistanceAlgorithmHaversine:             HaversineDistance{},
+	DistanceAlgorithmSphericalLawOfCosines: SphericalLawOfCosinesDistance{},
+	DistanceAlgorithmVincenty:              VincentyDistance{},
+}
+
+// fastDistanceCalculators holds the float implementations. Vincenty is already calculated with float numbers.
+var fastDistanceCalculators = map[DistanceAlgorithm]DistanceCalculator{
+	DistanceAlgorithmHaversine:             FloatHaversineDistance{},
+	DistanceAlgorithmSphericalLawOfCosines: FloatSphericalLawOfCosinesDistance{},
+	DistanceAlgorithmVincenty:              VincentyDistance{},
+}
+
+// NewDistanceAlgorithm converts a distance algorithm name, like "vincenty", into its DistanceAlgorithm value.
+func NewDistanceAlgorithm(name string) (DistanceAlgorithm, error) {
+	for algorithm, algorithmName := range distanceAlgorithmNames {
+		if algorithmName == name {
+			return algorithm, nil
+		}
+	}
+
+	return 0, NewErrInvalidArgument(fmt.Sprintf("'%s' is not a known algorithm", name), "invalid distance algorithm")
+}
+
+func (a DistanceAlgorithm) String() string {
+	if name, ok := distanceAlgorithmNames[a]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("DistanceAlgorithm(%d)", int(a))
+}
+
+// Calculator returns the calculator implementing the algorithm, falling back to the Haversine one.
+func (a DistanceAlgorithm) Calculator() DistanceCalculator {
+	if calculator, ok := distanceCalculators[a]; ok {
+		return calculator
+	}
+
+	return HaversineDistance{}
+}
+
+// FastCalculator returns the float implementation of the algorithm, falling back to the Haversine one. It trades
+// the arbitrary precision of decimals, which is beyond what the formulas themselves provide, for speed.
+func (a DistanceAlgorithm) FastCalculator() DistanceCalculator {
+	if calculator, ok := fastDistanceCalculators[a]; ok {
+		return calculator
+	}
+
+	return FloatHaversineDistance{}
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// distanceBandsSeparator separates the upper bounds of the bands, like "25,50,100".
+const distanceBandsSeparator = ","
+
+// DistanceBand is a ring around the base location, holding the customers farther than From and up to To kilometers
+// away from it, the first band including the base location itself.
+type DistanceBand struct {
+	From      decimal.Decimal
+	To        decimal.Decimal
+	Customers NearCustomers
+}
+
+// DistanceBands are consecutive rings around the base location, the nearest one first.
+type DistanceBands []DistanceBand
+
+// NewDistanceBands builds the bands from their upper bounds, in kilometers, which must be positive and ascending. The
+// first band starts on the base location, and every other one where the previous one ends.
+func NewDistanceBands(bounds []decimal.Decimal) (DistanceBands, error) {
+	if len(bounds) == 0 {
+		return nil, NewErrInvalidArgument("must have at least one upper bound", "invalid distance bands")
+	}
+
+	var (
+		bands = make(DistanceBands, 0, len(bounds))
+		from  = decimal.Zero
+	)
+
+	for _, to := range bounds {
+		if !to.GreaterThan(from) {
+			return nil, NewErrInvalidArgument(
+				fmt.Sprintf("upper bound %s must be greater than %s", to, from),
+				"invalid distance bands",
+			)
+		}
+
+		bands = append(bands, DistanceBand{From: from, To: to})
+		from = to
+	}
+
+	return bands, nil
+}
+
+// ParseDistanceBands builds the bands from their upper bounds separated by commas, like "25,50,100" for the bands
+// 0-25km, 25-50km and 50-100km.
+func ParseDistanceBands(value string) (DistanceBands, error) {
+	var bounds = make([]decimal.Decimal, 0)
+
+	for _, field := range strings.Split(value, distanceBandsSeparator) {
+		bound, err := decimal.NewFromString(strings.TrimSpace(field))
+		if err != nil {
+			return nil, NewErrInvalidArgument(fmt.Sprintf("'%s' is not a number", field), "invalid distance bands")
+		}
+
+		bounds = append(bounds, bound)
+	}
+
+	return NewDistanceBands(bounds)
+}
+
+// Outer returns the upper bound of the farthest band, beyond which no customer is within any band.
+func (b DistanceBands) Outer() decimal.Decimal {
+	if len(b) == 0 {
+		return decimal.Zero
+	}
+
+	return b[len(b)-1].To
+}
+
+// String presents the upper bounds of the bands separated by commas, as parsed by ParseDistanceBands.
+func (b DistanceBands) String() string {
+	var bounds = make([]string, 0, len(b))
+
+	for _, band := range b {
+		bounds = append(bounds, band.To.String())
+	}
+
+	return strings.Join(bounds, distanceBandsSeparator)
+}
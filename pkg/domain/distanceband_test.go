@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDistanceBands(t *testing.T) {
+	t.Parallel()
+
+	var band = func(from decimal.Decimal, to string) DistanceBand {
+		return DistanceBand{From: from, To: decimal.RequireFromString(to)}
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    DistanceBands
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:  "should parse consecutive bands from their upper bounds",
+			value: "25, 50,100",
+			want: DistanceBands{
+				band(decimal.Zero, "25"),
+				band(decimal.RequireFromString("25"), "50"),
+				band(decimal.RequireFromString("50"), "100"),
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should parse a single band with a decimal upper bound",
+			value:   "12.5",
+			want:    DistanceBands{band(decimal.Zero, "12.5")},
+			wantErr: assert.NoError,
+		},
+		{
+			name:  "should error on invalid number",
+			value: "25,fifty",
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid distance bands: 'fifty' is not a number")
+			},
+		},
+		{
+			name:  "should error on empty upper bound",
+			value: "25,,50",
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid distance bands: '' is not a number")
+			},
+		},
+		{
+			name:  "should error on upper bounds not ascending",
+			value: "25,25",
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid distance bands: upper bound 25 must be greater than 25")
+			},
+		},
+		{
+			name:  "should error on upper bound not positive",
+			value: "0,25",
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid distance bands: upper bound 0 must be greater than 0")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDistanceBands(tt.value)
+
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+
+			// the string representation is parsed back into the same bands
+			again, err := ParseDistanceBands(got.String())
+			assert.NoError(t, err)
+			assert.Equal(t, got, again)
+		})
+	}
+}
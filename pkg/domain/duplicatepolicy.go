@@ -0,0 +1,251 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// DuplicatePolicy defines which customer is kept when the same user_id is found more than once.
+type DuplicatePolicy int
+
+const (
+	// DuplicatePolicyKeepFirst keeps the first occurrence of the customer.
+	DuplicatePolicyKeepFirst DuplicatePolicy = iota
+	// DuplicatePolicyKeepLast keeps the last occurrence of the customer.
+	DuplicatePolicyKeepLast
+	// DuplicatePolicyKeepNearest keeps the occurrence nearest to the base location, or the first one on ties.
+	DuplicatePolicyKeepNearest
+	// DuplicatePolicyReject refuses the whole list.
+	DuplicatePolicyReject
+)
+
+var duplicatePolicyNames = map[DuplicatePolicy]string{
+	DuplicatePolicyKeepFirst:   "keep_first",
+	DuplicatePolicyKeepLast:    "keep_last",
+	DuplicatePolicyKeepNearest: "keep_nearest",
+	DuplicatePolicyReject:      "reject",
+}
+
+// NewDuplicatePolicy converts a duplicate policy name, like "keep_last", into its DuplicatePolicy value.
+func NewDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	for policy, policyName := range duplicatePolicyNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+
+	return 0, NewErrInvalidArgument(fmt.Sprintf("'%s' is not a known policy", name), "invalid duplicate policy")
+}
+
+func (p DuplicatePolicy) String() string {
+	if name, ok := duplicatePolicyNames[p]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("DuplicatePolicy(%d)", int(p))
+}
+
+// DuplicatesResolver resolves the customers sharing the same ID while they are streamed, in any order, along with
+// their distances. It only remembers the position and distance of the winning occurrence of every ID, and keeps the
+// winners accepted by the filter, so its memory grows with the distinct IDs rather than with the customers. The
+// positions are expected to be numbered from zero, with no gaps.
+type DuplicatesResolver struct {
+	policy     DuplicatePolicy
+	winners    map[int]occurrence
+	accepted   map[int]NearCustomer
+	duplicated map[int]bool
+
+	unseen int          // the lowest position not added yet
+	seen   map[int]bool // the positions added beyond the unseen one
+}
+
+// occurrence is the position, on the original list, and the distance of a customer. Far away occurrences are known
+// to be beyond the filter distance, without having it calculated.
+type occurrence struct {
+	position int
+	distance decimal.Decimal
+	farAway  bool
+}
+
+func NewDuplicatesResolver(policy DuplicatePolicy) (*DuplicatesResolver, error) {
+	if _, ok := duplicatePolicyNames[policy]; !ok {
+		return nil, NewErrInvalidArgument(policy.String(), "unexpected duplicate policy")
+	}
+
+	return &DuplicatesResolver{
+		policy:     policy,
+		winners:    make(map[int]occurrence),
+		accepted:   make(map[int]NearCustomer),
+		duplicated: make(map[int]bool),
+		seen:       make(map[int]bool),
+	}, nil
+}
+
+// Add records the customer found on the given position of the original list, telling whether it's accepted by the
+// filter. It replaces the previous occurrence of the same ID when it wins according to the policy, telling whether it
+// did, so it's the winner so far.
+func (r *DuplicatesResolver) Add(position int, customer NearCustomer, accepted bool) bool {
+	if !r.record(customer.ID, occurrence{position: position, distance: customer.Distance}) {
+		return false
+	}
+
+	if accepted {
+		r.accepted[customer.ID] = customer
+	} else {
+		delete(r.accepted, customer.ID)
+	}
+
+	return true
+}
+
+// AddFarAway records a customer known to be beyond the filter distance, like one outside its BoundingBox, whose
+// distance wasn't calculated. It never wins over a nearer occurrence on DuplicatePolicyKeepNearest.
+func (r *DuplicatesResolver) AddFarAway(position int, id int) {
+	if r.record(id, occurrence{position: position, farAway: true}) {
+		delete(r.accepted, id)
+	}
+}
+
+// record keeps the occurrence as the winner of its ID when it's the first one or wins over the current winner,
+// telling whether it was kept.
+func (r *DuplicatesResolver) record(id int, o occurrence) bool {
+	r.see(o.position)
+
+	if winner, found := r.winners[id]; found {
+		r.duplicated[id] = true
+
+		if !r.wins(o, winner) {
+			return false
+		}
+	}
+
+	r.winners[id] = o
+
+	return true
+}
+
+// see keeps track of the lowest position not added yet, as every occurrence before it is known.
+func (r *DuplicatesResolver) see(position int) {
+	if position != r.unseen {
+		r.seen[position] = true
+		return
+	}
+
+	for r.unseen++; r.seen[r.unseen]; r.unseen++ {
+		delete(r.seen, r.unseen)
+	}
+}
+
+// final tells whether the winner can't lose its place to an occurrence still to be added. On DuplicatePolicyKeepFirst
+// it's final once every earlier position was added, while on DuplicatePolicyKeepNearest a winner may only be replaced
+// by a nearer one, and on DuplicatePolicyReject any duplicate fails the whole list, so they're always final.
+func (r *DuplicatesResolver) final(winner occurrence) bool {
+	switch r.policy {
+	case DuplicatePolicyKeepFirst:
+		return winner.position < r.unseen
+
+	case DuplicatePolicyKeepLast:
+		return false
+
+	default:
+		return true
+	}
+}
+
+// wins tells whether the occurrence takes the place of the current winner, the first one on ties.
+func (r *DuplicatesResolver) wins(o, winner occurrence) bool {
+	switch r.policy {
+	case DuplicatePolicyKeepLast:
+		return o.position > winner.position
+
+	case DuplicatePolicyKeepNearest:
+		if o.farAway || winner.farAway {
+			return !o.farAway || (winner.farAway && o.position < winner.position)
+		}
+
+		return o.distance.LessThan(winner.distance) ||
+			(o.distance.Equal(winner.distance) && o.position < winner.position)
+
+	default:
+		return o.position < winner.position
+	}
+}
+
+// TrimToNearest drops the accepted customers farther than the n nearest final ones, ties broken by ID, once more
+// than twice n are accepted, besides the ones whose earlier positions are still to be added, so it's cheap to call
+// after every customer. Dropped customers never come back among the n nearest, as those final ones keep their
+// places, while a winner that isn't final yet is kept until it is, as it may still be replaced by a farther, or not
+// accepted, occurrence. Nothing is dropped on DuplicatePolicyKeepLast, as no winner is final before the end.
+func (r *DuplicatesResolver) TrimToNearest(n int) {
+	if r.policy == DuplicatePolicyKeepLast || len(r.accepted) <= 2*n+len(r.seen) { //nolint:gomnd // twice, to trim seldom
+		return
+	}
+
+	var nearest = make(NearCustomers, 0, len(r.accepted))
+	for _, customer := range r.accepted {
+		nearest = append(nearest, customer)
+	}
+
+	sort.Slice(nearest, func(i, j int) bool {
+		if cmp := nearest[i].Distance.Cmp(nearest[j].Distance); cmp != 0 {
+			return cmp < 0
+		}
+
+		return nearest[i].ID < nearest[j].ID
+	})
+
+	var final = 0
+
+	for i, customer := range nearest {
+		if final == n {
+			for _, dropped := range nearest[i:] {
+				delete(r.accepted, dropped.ID)
+			}
+
+			return
+		}
+
+		if r.final(r.winners[customer.ID]) {
+			final++
+		}
+	}
+}
+
+// Resolve returns, in no particular order, the accepted customers that won over their duplicates, and, in ascending
+// order, the IDs found more than once. On DuplicatePolicyReject it errors when any ID was found more than once.
+func (r *DuplicatesResolver) Resolve() (NearCustomers, []int, error) {
+	var duplicatedIDs = make([]int, 0, len(r.duplicated))
+	for id := range r.duplicated {
+		duplicatedIDs = append(duplicatedIDs, id)
+	}
+
+	sort.Ints(duplicatedIDs)
+
+	if r.policy == DuplicatePolicyReject && len(duplicatedIDs) > 0 {
+		return nil, duplicatedIDs, NewErrInvalidArgument(
+			fmt.Sprintf("user_id %s found more than once", joinIDs(duplicatedIDs)),
+			"duplicated customers",
+		)
+	}
+
+	var result = make([]NearCustomer, 0, len(r.accepted))
+	for _, customer := range r.accepted {
+		result = append(result, customer)
+	}
+
+	return result, duplicatedIDs, nil
+}
+
+func joinIDs(ids []int) string {
+	var values = make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		values = append(values, strconv.Itoa(id))
+	}
+
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,287 @@
+package domain
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDuplicatePolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    DuplicatePolicy
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "should parse keep first",
+			input:   "keep_first",
+			want:    DuplicatePolicyKeepFirst,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should parse keep nearest",
+			input:   "keep_nearest",
+			want:    DuplicatePolicyKeepNearest,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should parse reject",
+			input:   "reject",
+			want:    DuplicatePolicyReject,
+			wantErr: assert.NoError,
+		},
+		{
+			name:  "should error on unknown policy",
+			input: "merge",
+			want:  0,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid duplicate policy")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDuplicatePolicy(tt.input)
+
+			tt.wantErr(t, err)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDuplicatesResolver(t *testing.T) {
+	t.Parallel()
+
+	var (
+		radius     = decimal.NewFromInt32(100)
+		calculator = HaversineDistance{}
+
+		near = &Coordinate{Latitude: decimal.RequireFromString("53.3"), Longitude: decimal.RequireFromString("-6.2")}
+		far  = &Coordinate{Latitude: decimal.RequireFromString("51.8"), Longitude: decimal.RequireFromString("-8.4")}
+
+		farFirst   = NewCustomer(1, "First", far)
+		second     = NewCustomer(2, "Second", near)
+		nearFirst  = NewCustomer(1, "Near First", near)
+		lastSecond = NewCustomer(2, "Last Second", far)
+		third      = NewCustomer(3, "Third", near)
+		customers  = Customers{farFirst, second, nearFirst, lastSecond, third}
+	)
+
+	nearCustomer := func(customer Customer) NearCustomer {
+		return NewNearCustomer(customer, calculator.Distance(DublinLocation, customer.Location))
+	}
+
+	tests := []struct {
+		name              string
+		policy            DuplicatePolicy
+		want              NearCustomers
+		wantDuplicatedIDs []int
+		wantErr           assert.ErrorAssertionFunc
+	}{
+		{
+			name:              "should keep the first occurrence, even when it isn't accepted",
+			policy:            DuplicatePolicyKeepFirst,
+			want:              NearCustomers{nearCustomer(second), nearCustomer(third)},
+			wantDuplicatedIDs: []int{1, 2},
+			wantErr:           assert.NoError,
+		},
+		{
+			name:              "should keep the last occurrence, even when it isn't accepted",
+			policy:            DuplicatePolicyKeepLast,
+			want:              NearCustomers{nearCustomer(nearFirst), nearCustomer(third)},
+			wantDuplicatedIDs: []int{1, 2},
+			wantErr:           assert.NoError,
+		},
+		{
+			name:              "should keep the nearest occurrence",
+			policy:            DuplicatePolicyKeepNearest,
+			want:              NearCustomers{nearCustomer(nearFirst), nearCustomer(second), nearCustomer(third)},
+			wantDuplicatedIDs: []int{1, 2},
+			wantErr:           assert.NoError,
+		},
+		{
+			name:              "should reject the list",
+			policy:            DuplicatePolicyReject,
+			want:              nil,
+			wantDuplicatedIDs: []int{1, 2},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "duplicated customers: user_id 1, 2 found more than once")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		for _, skipFarAway := range []bool{false, true} {
+			skipFarAway := skipFarAway
+
+			t.Run(fmt.Sprintf("%s, skipping far away customers: %t", tt.name, skipFarAway), func(t *testing.T) {
+				resolver, err := NewDuplicatesResolver(tt.policy)
+				if err != nil {
+					t.Fatal("failed to build resolver")
+				}
+
+				// customers are added backwards, as they may arrive in any order
+				for i := len(customers) - 1; i >= 0; i-- {
+					customer := nearCustomer(customers[i])
+					accepted := customer.Distance.LessThanOrEqual(radius)
+
+					if skipFarAway && !accepted {
+						resolver.AddFarAway(i, customer.ID)
+						continue
+					}
+
+					resolver.Add(i, customer, accepted)
+				}
+
+				got, duplicatedIDs, err := resolver.Resolve()
+
+				tt.wantErr(t, err)
+
+				sort.Slice(got, func(i, j int) bool { return got[i].ID < got[j].ID })
+
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.wantDuplicatedIDs, duplicatedIDs)
+			})
+		}
+	}
+
+	t.Run("should tell whether the added occurrence won", func(t *testing.T) {
+		resolver, err := NewDuplicatesResolver(DuplicatePolicyKeepNearest)
+		if err != nil {
+			t.Fatal("failed to build resolver")
+		}
+
+		assert.True(t, resolver.Add(1, nearCustomer(farFirst), true))
+		assert.False(t, resolver.Add(2, nearCustomer(farFirst), true))
+		assert.True(t, resolver.Add(0, nearCustomer(nearFirst), false))
+	})
+
+	t.Run("should error on unexpected policy", func(t *testing.T) {
+		_, err := NewDuplicatesResolver(DuplicatePolicy(99))
+
+		isInvalidArgument(t, err, "unexpected duplicate policy")
+	})
+}
+
+// TestDuplicatesResolver_TrimToNearest checks, for every policy, that trimming while customers are added, in order or
+// not, keeps the same nearest customers as not trimming at all.
+func TestDuplicatesResolver_TrimToNearest(t *testing.T) {
+	t.Parallel()
+
+	const nearest = 5
+
+	var (
+		random    = rand.New(rand.NewSource(7)) //nolint:gosec // deterministic test data
+		customers = make(NearCustomers, 0, 300)
+	)
+
+	// few IDs, so most customers are duplicated
+	for i := 0; i < cap(customers); i++ {
+		customers = append(customers, NewNearCustomer(
+			NewCustomer(random.Intn(60)+1, "User name", DublinLocation),
+			decimal.NewFromInt(random.Int63n(50)),
+		))
+	}
+
+	resolveNearest := func(resolver *DuplicatesResolver) NearCustomers {
+		result, _, _ := resolver.Resolve()
+
+		sort.Slice(result, func(i, j int) bool {
+			if cmp := result[i].Distance.Cmp(result[j].Distance); cmp != 0 {
+				return cmp < 0
+			}
+
+			return result[i].ID < result[j].ID
+		})
+
+		return result[:min(nearest, len(result))]
+	}
+
+	// the positions in order, and shuffled within windows of 8, the way the workers of the usecase add them
+	var inOrder, outOfOrder = make([]int, 0, len(customers)), make([]int, 0, len(customers))
+
+	for i := range customers {
+		inOrder = append(inOrder, i)
+		outOfOrder = append(outOfOrder, i)
+	}
+
+	for start := 0; start < len(outOfOrder); start += 8 {
+		window := outOfOrder[start:min(start+8, len(outOfOrder))]
+		random.Shuffle(len(window), func(i, j int) { window[i], window[j] = window[j], window[i] })
+	}
+
+	for policy := range duplicatePolicyNames {
+		for name, positions := range map[string][]int{"in order": inOrder, "out of order": outOfOrder} {
+			policy, positions := policy, positions
+
+			t.Run(policy.String()+", "+name, func(t *testing.T) {
+				trimmed, err := NewDuplicatesResolver(policy)
+				if err != nil {
+					t.Fatal("failed to build resolver")
+				}
+
+				untrimmed, err := NewDuplicatesResolver(policy)
+				if err != nil {
+					t.Fatal("failed to build resolver")
+				}
+
+				for _, i := range positions {
+					// the farthest ones are known to be far away, without their distances
+					for _, resolver := range []*DuplicatesResolver{trimmed, untrimmed} {
+						if customers[i].Distance.GreaterThanOrEqual(decimal.NewFromInt(45)) {
+							resolver.AddFarAway(i, customers[i].ID)
+						} else {
+							resolver.Add(i, customers[i], true)
+						}
+					}
+
+					trimmed.TrimToNearest(nearest)
+				}
+
+				assert.Equal(t, resolveNearest(untrimmed), resolveNearest(trimmed))
+			})
+		}
+	}
+
+	t.Run("should not trim the customers beaten by a winner that isn't final yet", func(t *testing.T) {
+		var (
+			x = func(distance int64) NearCustomer {
+				return NewNearCustomer(NewCustomer(1, "X", DublinLocation), decimal.NewFromInt(distance))
+			}
+			y = NewNearCustomer(NewCustomer(2, "Y", DublinLocation), decimal.NewFromInt(5))
+			z = NewNearCustomer(NewCustomer(3, "Z", DublinLocation), decimal.NewFromInt(30))
+		)
+
+		for _, earlierX := range []func(*DuplicatesResolver){
+			func(r *DuplicatesResolver) { r.Add(0, x(90), true) },
+			func(r *DuplicatesResolver) { r.AddFarAway(0, 1) },
+		} {
+			resolver, err := NewDuplicatesResolver(DuplicatePolicyKeepFirst)
+			if err != nil {
+				t.Fatal("failed to build resolver")
+			}
+
+			resolver.Add(5, x(1), true)
+			resolver.Add(1, y, true)
+			resolver.Add(2, z, true)
+			resolver.TrimToNearest(1)
+
+			earlierX(resolver)
+
+			assert.Equal(t, NearCustomers{y}, resolveNearest(resolver)[:1])
+		}
+	})
+}
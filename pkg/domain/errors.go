@@ -14,3 +14,16 @@ func NewErrInvalidArgument(originalErr string, description string) *ErrInvalidAr
 func (e ErrInvalidArgument) Error() string {
 	return fmt.Sprintf("%s: %s", e.Description, e.OriginalErr)
 }
+
+type ErrNotFound struct {
+	Resource string
+	ID       string
+}
+
+func NewErrNotFound(resource string, id string) *ErrNotFound {
+	return &ErrNotFound{Resource: resource, ID: id}
+}
+
+func (e ErrNotFound) Error() string {
+	return fmt.Sprintf("%s '%s' not found", e.Resource, e.ID)
+}
@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// minRingPositions is the number of positions of the simplest ring, a triangle closed by repeating its first vertex.
+const minRingPositions = 4
+
+// Geofence is an area made of one or more polygons, each one an outer ring optionally followed by holes, whose
+// vertices are longitude/latitude pairs, in degrees. Rings are treated as planar over longitude and latitude, so
+// polygons crossing the antimeridian must be split on it, as GeoJSON requires.
+type Geofence struct {
+	polygons []geofencePolygon
+	box      BoundingBox
+}
+
+// geofencePolygon is an outer ring followed by its holes.
+type geofencePolygon []geofenceRing
+
+type geofenceRing []geofenceVertex
+
+type geofenceVertex struct {
+	longitude float64
+	latitude  float64
+}
+
+// newGeofence builds a geofence from polygons of rings of [longitude, latitude] positions, checking every ring is
+// closed and has at least 4 positions, and every position is a valid coordinate.
+func newGeofence(polygons [][][][2]float64) (*Geofence, error) {
+	if len(polygons) == 0 {
+		return nil, NewErrInvalidArgument("must have at least one polygon", "invalid geofence")
+	}
+
+	var geofence = &Geofence{
+		polygons: make([]geofencePolygon, 0, len(polygons)),
+		box: BoundingBox{
+			MinLatitude:  math.Inf(1),
+			MaxLatitude:  math.Inf(-1),
+			MinLongitude: math.Inf(1),
+			MaxLongitude: math.Inf(-1),
+		},
+	}
+
+	for _, rings := range polygons {
+		if len(rings) == 0 {
+			return nil, NewErrInvalidArgument("polygon must have an outer ring", "invalid geofence")
+		}
+
+		var polygon = make(geofencePolygon, 0, len(rings))
+
+		for _, positions := range rings {
+			ring, err := newGeofenceRing(positions)
+			if err != nil {
+				return nil, err
+			}
+
+			polygon = append(polygon, ring)
+		}
+
+		for _, vertex := range polygon[0] {
+			geofence.box.MinLatitude = math.Min(geofence.box.MinLatitude, vertex.latitude)
+			geofence.box.MaxLatitude = math.Max(geofence.box.MaxLatitude, vertex.latitude)
+			geofence.box.MinLongitude = math.Min(geofence.box.MinLongitude, vertex.longitude)
+			geofence.box.MaxLongitude = math.Max(geofence.box.MaxLongitude, vertex.longitude)
+		}
+
+		geofence.polygons = append(geofence.polygons, polygon)
+	}
+
+	return geofence, nil
+}
+
+func newGeofenceRing(positions [][2]float64) (geofenceRing, error) {
+	const (
+		maxLatitude  = 90
+		maxLongitude = 180
+	)
+
+	if len(positions) < minRingPositions {
+		return nil, NewErrInvalidArgument(
+			fmt.Sprintf("ring must have at least %d positions, got %d", minRingPositions, len(positions)),
+			"invalid geofence",
+		)
+	}
+
+	if positions[0] != positions[len(positions)-1] {
+		return nil, NewErrInvalidArgument("ring must end on its first position", "invalid geofence")
+	}
+
+	var ring = make(geofenceRing, 0, len(positions))
+
+	for _, position := range positions {
+		if math.Abs(position[0]) > maxLongitude || math.Abs(position[1]) > maxLatitude {
+			return nil, NewErrInvalidArgument(
+				fmt.Sprintf("position [%g, %g] must be a [longitude, latitude] pair", position[0], position[1]),
+				"invalid geofence",
+			)
+		}
+
+		ring = append(ring, geofenceVertex{longitude: position[0], latitude: position[1]})
+	}
+
+	return ring, nil
+}
+
+// Contains tells whether the location is inside any of the polygons, borders included, and outside their holes.
+func (g *Geofence) Contains(location *Coordinate) bool {
+	if location == nil {
+		return false
+	}
+
+	var point = geofenceVertex{longitude: location.Longitude.InexactFloat64(), latitude: location.Latitude.InexactFloat64()}
+
+	if point.latitude < g.box.MinLatitude || point.latitude > g.box.MaxLatitude ||
+		point.longitude < g.box.MinLongitude || point.longitude > g.box.MaxLongitude {
+		return false
+	}
+
+	for _, polygon := range g.polygons {
+		if polygon.contains(point) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String presents the geofence as a WKT MULTIPOLYGON, which identifies it regardless of how it was informed.
+func (g *Geofence) String() string {
+	var polygons = make([]string, 0, len(g.polygons))
+
+	for _, polygon := range g.polygons {
+		var rings = make([]string, 0, len(polygon))
+
+		for _, ring := range polygon {
+			var positions = make([]string, 0, len(ring))
+
+			for _, vertex := range ring {
+				positions = append(positions, strconv.FormatFloat(vertex.longitude, 'f', -1, 64)+" "+
+					strconv.FormatFloat(vertex.latitude, 'f', -1, 64))
+			}
+
+			rings = append(rings, "("+strings.Join(positions, ", ")+")")
+		}
+
+		polygons = append(polygons, "("+strings.Join(rings, ", ")+")")
+	}
+
+	return wktMultiPolygon + " (" + strings.Join(polygons, ", ") + ")"
+}
+
+// contains tells whether the point is inside the outer ring and not strictly inside any hole, as the border of a
+// hole is a border of the polygon too.
+func (p geofencePolygon) contains(point geofenceVertex) bool {
+	if inside, _ := p[0].contains(point); !inside {
+		return false
+	}
+
+	for _, hole := range p[1:] {
+		if inside, onBorder := hole.contains(point); inside && !onBorder {
+			return false
+		}
+	}
+
+	return true
+}
+
+// contains tells whether the point is inside the ring, by casting a ray from it towards the east and counting how
+// many edges it crosses, and whether it's on the border, when it's considered inside.
+func (r geofenceRing) contains(point geofenceVertex) (bool, bool) {
+	var inside = false
+
+	for i := 1; i < len(r); i++ {
+		a, b := r[i-1], r[i]
+
+		if onSegment(point, a, b) {
+			return true, true
+		}
+
+		if (a.latitude > point.latitude) != (b.latitude > point.latitude) {
+			crossing := a.longitude + (point.latitude-a.latitude)*(b.longitude-a.longitude)/(b.latitude-a.latitude)
+			if point.longitude < crossing {
+				inside = !inside
+			}
+		}
+	}
+
+	return inside, false
+}
+
+// onSegment tells whether the point lies on the segment from a to b.
+func onSegment(point, a, b geofenceVertex) bool {
+	const tolerance = 1e-12
+
+	cross := (b.longitude-a.longitude)*(point.latitude-a.latitude) - (b.latitude-a.latitude)*(point.longitude-a.longitude)
+	if math.Abs(cross) > tolerance {
+		return false
+	}
+
+	return point.longitude >= math.Min(a.longitude, b.longitude) && point.longitude <= math.Max(a.longitude, b.longitude) &&
+		point.latitude >= math.Min(a.latitude, b.latitude) && point.latitude <= math.Max(a.latitude, b.latitude)
+}
@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeofence_Contains(t *testing.T) {
+	t.Parallel()
+
+	var coordinate = func(latitude, longitude string) *Coordinate {
+		c, err := NewCoordinate(latitude, longitude)
+		if err != nil {
+			t.Fatal("failed to build coordinate")
+		}
+
+		return c
+	}
+
+	// an L shaped area around Dublin, with a hole, plus a square around Cork
+	geofence, err := NewGeofenceFromWKT(`MULTIPOLYGON (
+		((-6.5 53.2, -6.0 53.2, -6.0 53.3, -6.3 53.3, -6.3 53.5, -6.5 53.5, -6.5 53.2), (-6.45 53.4, -6.35 53.4, -6.35 53.45, -6.45 53.45, -6.45 53.4)),
+		((-8.6 51.8, -8.3 51.8, -8.3 52.0, -8.6 52.0, -8.6 51.8))
+	)`)
+	if err != nil {
+		t.Fatalf("failed to build geofence: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		location *Coordinate
+		want     bool
+	}{
+		{name: "should contain a location inside the first polygon", location: coordinate("53.25", "-6.2"), want: true},
+		{name: "should contain a location inside the second polygon", location: coordinate("51.9", "-8.47"), want: true},
+		{name: "should not contain a location on the notch of the L shape", location: coordinate("53.4", "-6.2"), want: false},
+		{name: "should not contain a location outside every polygon", location: DublinLocation, want: false},
+		{name: "should contain a location on an edge", location: coordinate("53.2", "-6.25"), want: true},
+		{name: "should contain a location on a vertex", location: coordinate("53.3", "-6.3"), want: true},
+		{name: "should not contain a location inside the hole", location: coordinate("53.42", "-6.4"), want: false},
+		{name: "should contain a location on the border of the hole", location: coordinate("53.4", "-6.4"), want: true},
+		{name: "should not contain a location without coordinate", location: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, geofence.Contains(tt.location))
+		})
+	}
+}
+
+func TestParseGeofence(t *testing.T) {
+	t.Parallel()
+
+	const square = "MULTIPOLYGON (((-6.5 53.2, -6 53.2, -6 53.5, -6.5 53.5, -6.5 53.2)))"
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "should parse a WKT polygon",
+			value:   "POLYGON((-6.5 53.2,-6 53.2,-6 53.5,-6.5 53.5,-6.5 53.2))",
+			want:    square,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should parse a WKT multipolygon in lower case, with altitudes",
+			value:   " multipolygon ( ( ( -6.5 53.2 10, -6 53.2 10, -6 53.5 10, -6.5 53.5 10, -6.5 53.2 10 ) ) ) ",
+			want:    square,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should parse a GeoJSON polygon",
+			value:   `{"type": "Polygon", "coordinates": [[[-6.5, 53.2], [-6, 53.2], [-6, 53.5], [-6.5, 53.5], [-6.5, 53.2]]]}`,
+			want:    square,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should parse a GeoJSON feature collection of polygons and multipolygons",
+			value:   `{"type": "FeatureCollection", "features": [{"type": "Feature", "properties": {"name": "Dublin"}, "geometry": {"type": "Polygon", "coordinates": [[[-6.5, 53.2], [-6, 53.2], [-6, 53.5], [-6.5, 53.5], [-6.5, 53.2]]]}}, {"type": "Feature", "geometry": {"type": "MultiPolygon", "coordinates": [[[[-8.6, 51.8], [-8.3, 51.8], [-8.3, 52], [-8.6, 51.8]]]]}}]}`,
+			want:    "MULTIPOLYGON (((-6.5 53.2, -6 53.2, -6 53.5, -6.5 53.5, -6.5 53.2)), ((-8.6 51.8, -8.3 51.8, -8.3 52, -8.6 51.8)))",
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should error on unclosed ring",
+			value:   "POLYGON ((-6.5 53.2, -6 53.2, -6 53.5, -6.5 53.5))",
+			wantErr: invalidGeofence("ring must end on its first position"),
+		},
+		{
+			name:    "should error on ring with too few positions",
+			value:   "POLYGON ((-6.5 53.2, -6 53.2, -6.5 53.2))",
+			wantErr: invalidGeofence("ring must have at least 4 positions, got 3"),
+		},
+		{
+			name:    "should error on latitude/longitude order",
+			value:   "POLYGON ((53.2 -6.5, 53.2 -6, 53.5 -96, 53.2 -6.5))",
+			wantErr: invalidGeofence("position [53.5, -96] must be a [longitude, latitude] pair"),
+		},
+		{
+			name:    "should error on unsupported WKT type",
+			value:   "POINT (-6.5 53.2)",
+			wantErr: invalidGeofence("unsupported type 'POINT', must be a POLYGON or a MULTIPOLYGON"),
+		},
+		{
+			name:    "should error on unsupported GeoJSON type",
+			value:   `{"type": "Point", "coordinates": [-6.5, 53.2]}`,
+			wantErr: invalidGeofence("unsupported type 'Point', must be a Polygon or a MultiPolygon"),
+		},
+		{
+			name:    "should error on malformed WKT",
+			value:   "POLYGON ((-6.5 53.2, -6 53.2, -6 53.5, -6.5 53.2)",
+			wantErr: invalidGeofence("expected ',' or ')' at offset 49"),
+		},
+		{
+			name:    "should error on content after the WKT geometry",
+			value:   "POLYGON ((-6.5 53.2, -6 53.2, -6 53.5, -6.5 53.2)) extra",
+			wantErr: invalidGeofence("unexpected 'extra' after the geometry"),
+		},
+		{
+			name:    "should error on WKT lists nested deeper than a multipolygon",
+			value:   "MULTIPOLYGON ((((-6.5 53.2, -6 53.2, -6 53.5, -6.5 53.2))))",
+			wantErr: invalidGeofence("lists nested deeper than 3 at offset 16"),
+		},
+		{
+			name:    "should error on deeply nested WKT without recursing through it",
+			value:   "POLYGON " + strings.Repeat("(", 100_000),
+			wantErr: invalidGeofence("lists nested deeper than 3 at offset 11"),
+		},
+		{
+			name:    "should error on WKT larger than the limit",
+			value:   "POLYGON " + strings.Repeat("(", 9_000_000),
+			wantErr: invalidGeofence("must have at most 1048576 bytes"),
+		},
+		{
+			name:    "should error on malformed GeoJSON",
+			value:   `{"type": "Polygon", "coordinates": [[[-6.5, 53.2]]`,
+			wantErr: invalidGeofence("unexpected end of JSON input"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGeofence(tt.value)
+
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.want, got.String())
+
+			// the string representation is a WKT identifying the same geofence
+			again, err := ParseGeofence(got.String())
+			assert.NoError(t, err)
+			assert.Equal(t, got, again)
+		})
+	}
+}
+
+func invalidGeofence(message string) assert.ErrorAssertionFunc {
+	return func(t assert.TestingT, err error, _ ...interface{}) bool {
+		return isInvalidArgument(t, err, "invalid geofence: "+message)
+	}
+}
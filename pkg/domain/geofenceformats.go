@@ -0,0 +1,342 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	wktPolygon      = "POLYGON"
+	wktMultiPolygon = "MULTIPOLYGON"
+
+	geoJSONPolygon           = "Polygon"
+	geoJSONMultiPolygon      = "MultiPolygon"
+	geoJSONFeature           = "Feature"
+	geoJSONFeatureCollection = "FeatureCollection"
+
+	// minPositionValues are the longitude and latitude of a position, which may be followed by an altitude.
+	minPositionValues = 2
+
+	// maxWKTDepth is the nesting of the lists of a MULTIPOLYGON, made of polygons made of rings made of positions,
+	// so deeper inputs are refused before recursing any further.
+	maxWKTDepth = 3
+	// maxWKTLength bounds the size of a WKT geometry, in bytes.
+	maxWKTLength = 1 << 20 // 1mb
+)
+
+// ParseGeofence parses a geofence informed as GeoJSON, when it starts with "{", or as WKT otherwise.
+func ParseGeofence(value string) (*Geofence, error) {
+	value = strings.TrimSpace(value)
+
+	if strings.HasPrefix(value, "{") {
+		return NewGeofenceFromGeoJSON([]byte(value))
+	}
+
+	return NewGeofenceFromWKT(value)
+}
+
+// geoJSONObject holds the members of the GeoJSON objects a geofence may be made of.
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+	Features    []geoJSONObject `json:"features"`
+}
+
+// NewGeofenceFromGeoJSON builds a geofence from a GeoJSON Polygon or MultiPolygon, or from a Feature or a
+// FeatureCollection of them, when the geofence is made of all their polygons.
+func NewGeofenceFromGeoJSON(content []byte) (*Geofence, error) {
+	var object geoJSONObject
+
+	if err := json.Unmarshal(content, &object); err != nil {
+		return nil, NewErrInvalidArgument(err.Error(), "invalid geofence")
+	}
+
+	polygons, err := object.polygons()
+	if err != nil {
+		return nil, err
+	}
+
+	return newGeofence(polygons)
+}
+
+func (o *geoJSONObject) polygons() ([][][][2]float64, error) {
+	switch o.Type {
+	case geoJSONPolygon:
+		var rings [][][]float64
+		if err := json.Unmarshal(o.Coordinates, &rings); err != nil {
+			return nil, NewErrInvalidArgument(err.Error(), "invalid geofence")
+		}
+
+		polygon, err := toPolygon(rings)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][][][2]float64{polygon}, nil
+
+	case geoJSONMultiPolygon:
+		var multiPolygon [][][][]float64
+		if err := json.Unmarshal(o.Coordinates, &multiPolygon); err != nil {
+			return nil, NewErrInvalidArgument(err.Error(), "invalid geofence")
+		}
+
+		var polygons = make([][][][2]float64, 0, len(multiPolygon))
+
+		for _, rings := range multiPolygon {
+			polygon, err := toPolygon(rings)
+			if err != nil {
+				return nil, err
+			}
+
+			polygons = append(polygons, polygon)
+		}
+
+		return polygons, nil
+
+	case geoJSONFeature:
+		if o.Geometry == nil {
+			return nil, NewErrInvalidArgument("feature must have a geometry", "invalid geofence")
+		}
+
+		return o.Geometry.polygons()
+
+	case geoJSONFeatureCollection:
+		var polygons [][][][2]float64
+
+		for i := range o.Features {
+			featurePolygons, err := o.Features[i].polygons()
+			if err != nil {
+				return nil, err
+			}
+
+			polygons = append(polygons, featurePolygons...)
+		}
+
+		return polygons, nil
+
+	default:
+		return nil, NewErrInvalidArgument(
+			fmt.Sprintf("unsupported type '%s', must be a %s or a %s", o.Type, geoJSONPolygon, geoJSONMultiPolygon),
+			"invalid geofence",
+		)
+	}
+}
+
+// toPolygon converts the rings of positions of variable length to rings of [longitude, latitude] pairs.
+func toPolygon(rings [][][]float64) ([][][2]float64, error) {
+	var polygon = make([][][2]float64, 0, len(rings))
+
+	for _, positions := range rings {
+		var ring = make([][2]float64, 0, len(positions))
+
+		for _, position := range positions {
+			if len(position) < minPositionValues {
+				return nil, NewErrInvalidArgument("position must have a longitude and a latitude", "invalid geofence")
+			}
+
+			ring = append(ring, [2]float64{position[0], position[1]})
+		}
+
+		polygon = append(polygon, ring)
+	}
+
+	return polygon, nil
+}
+
+// NewGeofenceFromWKT builds a geofence from a WKT POLYGON or MULTIPOLYGON, whose positions are "longitude latitude".
+func NewGeofenceFromWKT(value string) (*Geofence, error) {
+	var (
+		text  = strings.TrimSpace(value)
+		start = strings.Index(text, "(")
+	)
+
+	if start < 0 {
+		return nil, NewErrInvalidArgument("must be a WKT POLYGON or MULTIPOLYGON", "invalid geofence")
+	}
+
+	if len(text) > maxWKTLength {
+		return nil, NewErrInvalidArgument(fmt.Sprintf("must have at most %d bytes", maxWKTLength), "invalid geofence")
+	}
+
+	var parser = &wktParser{input: text, position: start}
+
+	tree, err := parser.parseList(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.skipSpaces(); parser.position < len(parser.input) {
+		return nil, NewErrInvalidArgument(
+			fmt.Sprintf("unexpected '%s' after the geometry", parser.input[parser.position:]),
+			"invalid geofence",
+		)
+	}
+
+	var polygons [][][][2]float64
+
+	switch keyword := strings.ToUpper(strings.TrimSpace(text[:start])); keyword {
+	case wktPolygon:
+		polygon, err := tree.rings()
+		if err != nil {
+			return nil, err
+		}
+
+		polygons = append(polygons, polygon)
+
+	case wktMultiPolygon:
+		for _, child := range tree.children {
+			polygon, err := child.rings()
+			if err != nil {
+				return nil, err
+			}
+
+			polygons = append(polygons, polygon)
+		}
+
+	default:
+		return nil, NewErrInvalidArgument(
+			fmt.Sprintf("unsupported type '%s', must be a %s or a %s", keyword, wktPolygon, wktMultiPolygon),
+			"invalid geofence",
+		)
+	}
+
+	return newGeofence(polygons)
+}
+
+// wktNode is either a position or a parenthesized list of nodes.
+type wktNode struct {
+	position []float64
+	children []wktNode
+}
+
+// rings converts a list of lists of positions to rings of [longitude, latitude] pairs.
+func (n wktNode) rings() ([][][2]float64, error) {
+	var rings = make([][][2]float64, 0, len(n.children))
+
+	for _, child := range n.children {
+		if child.position != nil {
+			return nil, NewErrInvalidArgument("polygon must be a list of rings", "invalid geofence")
+		}
+
+		var ring = make([][2]float64, 0, len(child.children))
+
+		for _, position := range child.children {
+			if position.position == nil {
+				return nil, NewErrInvalidArgument("ring must be a list of positions", "invalid geofence")
+			}
+
+			ring = append(ring, [2]float64{position.position[0], position.position[1]})
+		}
+
+		rings = append(rings, ring)
+	}
+
+	return rings, nil
+}
+
+// wktParser reads the nested lists of positions of a WKT geometry.
+type wktParser struct {
+	input    string
+	position int
+}
+
+// parseList reads a parenthesized list of positions or lists, separated by commas, at the given depth, the outermost
+// list being at depth 1.
+func (p *wktParser) parseList(depth int) (wktNode, error) {
+	var node wktNode
+
+	if depth > maxWKTDepth {
+		return node, NewErrInvalidArgument(
+			fmt.Sprintf("lists nested deeper than %d at offset %d", maxWKTDepth, p.position),
+			"invalid geofence",
+		)
+	}
+
+	if p.skipSpaces(); !p.consume('(') {
+		return node, p.unexpected("'('")
+	}
+
+	for {
+		var (
+			child wktNode
+			err   error
+		)
+
+		if p.skipSpaces(); p.position < len(p.input) && p.input[p.position] == '(' {
+			child, err = p.parseList(depth + 1)
+		} else {
+			child.position, err = p.parsePosition()
+		}
+
+		if err != nil {
+			return node, err
+		}
+
+		node.children = append(node.children, child)
+
+		p.skipSpaces()
+
+		switch {
+		case p.consume(','):
+			continue
+		case p.consume(')'):
+			return node, nil
+		default:
+			return node, p.unexpected("',' or ')'")
+		}
+	}
+}
+
+// parsePosition reads the numbers of a position, up to the next comma or closing parenthesis.
+func (p *wktParser) parsePosition() ([]float64, error) {
+	var end = strings.IndexAny(p.input[p.position:], ",)")
+	if end < 0 {
+		return nil, p.unexpected("a position")
+	}
+
+	var fields = strings.Fields(p.input[p.position : p.position+end])
+	if len(fields) < minPositionValues {
+		return nil, p.unexpected("a position")
+	}
+
+	var position = make([]float64, 0, len(fields))
+
+	for _, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, NewErrInvalidArgument(fmt.Sprintf("invalid number '%s'", field), "invalid geofence")
+		}
+
+		position = append(position, value)
+	}
+
+	p.position += end
+
+	return position, nil
+}
+
+func (p *wktParser) skipSpaces() {
+	for p.position < len(p.input) && strings.ContainsRune(" \t\r\n", rune(p.input[p.position])) {
+		p.position++
+	}
+}
+
+func (p *wktParser) consume(char byte) bool {
+	if p.position < len(p.input) && p.input[p.position] == char {
+		p.position++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *wktParser) unexpected(expected string) error {
+	return NewErrInvalidArgument(
+		fmt.Sprintf("expected %s at offset %d", expected, p.position),
+		"invalid geofence",
+	)
+}
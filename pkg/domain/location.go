@@ -0,0 +1,16 @@
+package domain
+
+import "fmt"
+
+// Locations is a registry of named locations, like offices, indexed by their name.
+type Locations map[string]*Coordinate
+
+// Get returns the coordinate of a registered location.
+func (l Locations) Get(name string) (*Coordinate, error) {
+	location, ok := l[name]
+	if !ok {
+		return nil, NewErrInvalidArgument(fmt.Sprintf("location '%s' is not registered", name), "unknown location")
+	}
+
+	return location, nil
+}
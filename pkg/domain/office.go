@@ -0,0 +1,20 @@
+package domain
+
+import "github.com/shopspring/decimal"
+
+// Office is a base location hosting a party for the customers within its radius, in kilometers.
+type Office struct {
+	Name     string
+	Location *Coordinate
+	Radius   decimal.Decimal
+}
+
+func NewOffice(name string, location *Coordinate, radius decimal.Decimal) Office {
+	return Office{Name: name, Location: location, Radius: radius}
+}
+
+// OfficeCustomers are the customers assigned to an office, along with their distances from it.
+type OfficeCustomers struct {
+	Office    Office
+	Customers NearCustomers
+}
@@ -1,7 +1,42 @@
 package domain
 
+import "fmt"
+
 type OrderBy int
 
 const (
 	OrderByCustomerID OrderBy = iota
+	OrderByCustomerIDDesc
+	OrderByDistance
+	OrderByDistanceDesc
+	OrderByName
+	OrderByNameDesc
 )
+
+var orderByNames = map[OrderBy]string{
+	OrderByCustomerID:     "user_id",
+	OrderByCustomerIDDesc: "user_id_desc",
+	OrderByDistance:       "distance",
+	OrderByDistanceDesc:   "distance_desc",
+	OrderByName:           "name",
+	OrderByNameDesc:       "name_desc",
+}
+
+// ParseOrderBy converts an order by name, like "distance" or "name_desc", into its OrderBy value.
+func ParseOrderBy(name string) (OrderBy, error) {
+	for orderBy, orderByName := range orderByNames {
+		if orderByName == name {
+			return orderBy, nil
+		}
+	}
+
+	return 0, NewErrInvalidArgument(fmt.Sprintf("'%s' is not a known order", name), "invalid order by")
+}
+
+func (o OrderBy) String() string {
+	if name, ok := orderByNames[o]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("OrderBy(%d)", int(o))
+}
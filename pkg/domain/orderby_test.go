@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOrderBy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    OrderBy
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "should parse order by customer ID",
+			input:   "user_id",
+			want:    OrderByCustomerID,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should parse order by distance descending",
+			input:   "distance_desc",
+			want:    OrderByDistanceDesc,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "should parse order by name",
+			input:   "name",
+			want:    OrderByName,
+			wantErr: assert.NoError,
+		},
+		{
+			name:  "should error on unknown order by",
+			input: "age",
+			want:  0,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "invalid order by")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOrderBy(tt.input)
+
+			tt.wantErr(t, err)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "fmt"
+
+// ParseMode defines how a customers file parser reacts to invalid lines.
+type ParseMode int
+
+const (
+	// ParseModeStrict aborts the parsing on the first invalid line.
+	ParseModeStrict ParseMode = iota
+	// ParseModeLenient skips invalid lines, reporting them as rejected.
+	ParseModeLenient
+)
+
+var parseModeNames = map[ParseMode]string{
+	ParseModeStrict:  "strict",
+	ParseModeLenient: "lenient",
+}
+
+// NewParseMode converts a parse mode name, like "lenient", into its ParseMode value.
+func NewParseMode(name string) (ParseMode, error) {
+	for mode, modeName := range parseModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+
+	return 0, NewErrInvalidArgument(fmt.Sprintf("'%s' is not a known mode", name), "invalid parse mode")
+}
+
+func (m ParseMode) String() string {
+	if name, ok := parseModeNames[m]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("ParseMode(%d)", int(m))
+}
+
+// RejectedLine describes a line of a customers file skipped by the lenient parse mode.
+type RejectedLine struct {
+	Line    int
+	Content string
+	Reason  string
+}
+
+type RejectedLines []RejectedLine
@@ -0,0 +1,239 @@
+package domain
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// SpatialIndex is a k-d tree over the customers' locations, telling which customers may be within a radius or among
+// the nearest ones without calculating the distance of every customer. Locations are indexed as points of the unit
+// sphere, whose straight line distances grow along with the great-circle ones, so the antimeridian and the poles need
+// no special handling. It's immutable once built, so it's safe for concurrent queries.
+type SpatialIndex struct {
+	points []indexedPoint
+}
+
+// indexedPoint is the position of a customer on the indexed list along with its location as a point of the unit
+// sphere.
+type indexedPoint struct {
+	index int
+	point [3]float64
+}
+
+// NewSpatialIndex indexes the customers. Customers without location are left out.
+func NewSpatialIndex(customers Customers) *SpatialIndex {
+	var points = make([]indexedPoint, 0, len(customers))
+
+	for i, customer := range customers {
+		if customer.Location == nil {
+			continue
+		}
+
+		points = append(points, indexedPoint{index: i, point: toUnitSphere(customer.Location)})
+	}
+
+	buildKDTree(points, 0)
+
+	return &SpatialIndex{points: points}
+}
+
+// Len returns the number of indexed customers.
+func (s *SpatialIndex) Len() int {
+	return len(s.points)
+}
+
+// Around returns, in no particular order, the positions on the indexed list of the customers within the radius, in
+// kilometers, from the center, as measured by any calculator. Their distances aren't calculated, so customers beyond
+// the radius by less than the margin between the calculators are returned as well.
+func (s *SpatialIndex) Around(center *Coordinate, radius decimal.Decimal) []int {
+	var (
+		result = make([]int, 0)
+		chord  = chordLength(radius.InexactFloat64() * ellipsoidMargin)
+	)
+
+	s.searchWithin(0, len(s.points), 0, toUnitSphere(center), chord*chord, func(point indexedPoint) {
+		result = append(result, point.index)
+	})
+
+	return result
+}
+
+// AroundNearest returns, in no particular order, the positions on the indexed list of the customers among which are
+// the n nearest to the center, ties included, as measured by any calculator. Their distances aren't calculated, so
+// customers a bit farther than the n nearest are returned as well.
+func (s *SpatialIndex) AroundNearest(center *Coordinate, n int) []int {
+	if n <= 0 || len(s.points) == 0 {
+		return make([]int, 0)
+	}
+
+	var (
+		target  = toUnitSphere(center)
+		nearest = &pointsMaxHeap{}
+	)
+
+	s.searchNearest(0, len(s.points), 0, target, n, nearest)
+
+	// the n-th nearest over the sphere bounds the search radius, as the calculators may slightly reorder them. The
+	// margin applies twice, since a customer may be nearer over the ellipsoid while the n-th nearest is farther
+	var (
+		farthest = math.Sqrt(nearest.distances[0])
+		radius   = chordToDistance(farthest) * ellipsoidMargin * ellipsoidMargin
+		chord    = chordLength(radius)
+		result   = make([]int, 0, n)
+	)
+
+	s.searchWithin(0, len(s.points), 0, target, chord*chord, func(point indexedPoint) {
+		result = append(result, point.index)
+	})
+
+	return result
+}
+
+// buildKDTree arranges the points so the median of every range, by the axis of its depth, is its node, with the
+// points before it on the left subtree and the ones after it on the right one.
+func buildKDTree(points []indexedPoint, depth int) {
+	if len(points) <= 1 {
+		return
+	}
+
+	var (
+		axis   = depth % len(points[0].point)
+		median = len(points) / 2 //nolint:gomnd // half of the points
+	)
+
+	sort.Slice(points, func(i, j int) bool { return points[i].point[axis] < points[j].point[axis] })
+
+	buildKDTree(points[:median], depth+1)
+	buildKDTree(points[median+1:], depth+1)
+}
+
+// searchWithin visits the points of the range [from, to) whose squared straight line distance to the target is up
+// to maxDistance, skipping the subtrees that can't have any.
+func (s *SpatialIndex) searchWithin(
+	from, to, depth int,
+	target [3]float64,
+	maxDistance float64,
+	visit func(indexedPoint),
+) {
+	if from >= to {
+		return
+	}
+
+	var (
+		median = (from + to) / 2 //nolint:gomnd // half of the range
+		point  = s.points[median]
+		axis   = depth % len(target)
+		diff   = target[axis] - point.point[axis]
+	)
+
+	if squaredDistance(target, point.point) <= maxDistance {
+		visit(point)
+	}
+
+	if diff <= 0 || diff*diff <= maxDistance {
+		s.searchWithin(from, median, depth+1, target, maxDistance, visit)
+	}
+	if diff >= 0 || diff*diff <= maxDistance {
+		s.searchWithin(median+1, to, depth+1, target, maxDistance, visit)
+	}
+}
+
+// searchNearest keeps on the heap the n points of the range [from, to) nearest to the target.
+func (s *SpatialIndex) searchNearest(from, to, depth int, target [3]float64, n int, nearest *pointsMaxHeap) {
+	if from >= to {
+		return
+	}
+
+	var (
+		median = (from + to) / 2 //nolint:gomnd // half of the range
+		point  = s.points[median]
+		axis   = depth % len(target)
+		diff   = target[axis] - point.point[axis]
+	)
+
+	if distance := squaredDistance(target, point.point); nearest.Len() < n {
+		heap.Push(nearest, distance)
+	} else if distance < nearest.distances[0] {
+		nearest.distances[0] = distance
+		heap.Fix(nearest, 0)
+	}
+
+	var (
+		nearSide = [2]int{from, median}
+		farSide  = [2]int{median + 1, to}
+	)
+
+	if diff > 0 {
+		nearSide, farSide = farSide, nearSide
+	}
+
+	s.searchNearest(nearSide[0], nearSide[1], depth+1, target, n, nearest)
+
+	if nearest.Len() < n || diff*diff <= nearest.distances[0] {
+		s.searchNearest(farSide[0], farSide[1], depth+1, target, n, nearest)
+	}
+}
+
+// toUnitSphere converts the location to the cartesian coordinates of a point of the unit sphere.
+func toUnitSphere(location *Coordinate) [3]float64 {
+	latitude, longitude := toFloatRadians(location)
+
+	return [3]float64{
+		math.Cos(latitude) * math.Cos(longitude),
+		math.Cos(latitude) * math.Sin(longitude),
+		math.Sin(latitude),
+	}
+}
+
+// chordLength converts a great-circle distance, in kilometers, to the straight line distance over the unit sphere.
+func chordLength(distance float64) float64 {
+	var angle = math.Min(distance/earthRadiusInKm, math.Pi)
+
+	return 2 * math.Sin(angle/2) //nolint:gomnd // twice the sine of half the angle
+}
+
+// chordToDistance converts a straight line distance over the unit sphere to the great-circle one, in kilometers.
+func chordToDistance(chord float64) float64 {
+	return 2 * math.Asin(math.Min(chord/2, 1)) * earthRadiusInKm //nolint:gomnd // twice the arc sine of half the chord
+}
+
+func squaredDistance(p1, p2 [3]float64) float64 {
+	var sum float64
+
+	for i := range p1 {
+		sum += (p1[i] - p2[i]) * (p1[i] - p2[i])
+	}
+
+	return sum
+}
+
+// pointsMaxHeap holds squared distances, the greatest one on top.
+type pointsMaxHeap struct {
+	distances []float64
+}
+
+func (h *pointsMaxHeap) Len() int {
+	return len(h.distances)
+}
+
+func (h *pointsMaxHeap) Less(i, j int) bool {
+	return h.distances[i] > h.distances[j]
+}
+
+func (h *pointsMaxHeap) Swap(i, j int) {
+	h.distances[i], h.distances[j] = h.distances[j], h.distances[i]
+}
+
+func (h *pointsMaxHeap) Push(x any) {
+	h.distances = append(h.distances, x.(float64))
+}
+
+func (h *pointsMaxHeap) Pop() any {
+	var last = h.distances[len(h.distances)-1]
+	h.distances = h.distances[:len(h.distances)-1]
+
+	return last
+}
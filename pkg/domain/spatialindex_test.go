@@ -0,0 +1,185 @@
+package domain
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpatialIndex(t *testing.T) {
+	t.Parallel()
+
+	var (
+		random    = rand.New(rand.NewSource(3)) //nolint:gosec // deterministic test data
+		customers = make(Customers, 0, 600)
+	)
+
+	// customers all around the world, most of them around Dublin and around the antimeridian
+	for i := 0; i < cap(customers); i++ {
+		var latitude, longitude float64
+
+		switch i % 3 {
+		case 0:
+			latitude, longitude = 53.339428+(random.Float64()*2-1)*3, -6.257664+(random.Float64()*2-1)*5
+		case 1:
+			latitude, longitude = -16.5+(random.Float64()*2-1)*3, 180-random.Float64()*2
+			if random.Intn(2) == 0 {
+				longitude = -longitude // on the other side of the antimeridian
+			}
+		default:
+			latitude, longitude = (random.Float64()*2-1)*90, (random.Float64()*2-1)*180
+		}
+
+		location, err := NewCoordinate(
+			strconv.FormatFloat(latitude, 'f', 6, 64),
+			strconv.FormatFloat(longitude, 'f', 6, 64),
+		)
+		if err != nil {
+			t.Fatalf("failed to build coordinate %f,%f", latitude, longitude)
+		}
+
+		customers = append(customers, NewCustomer(i+1, fmt.Sprintf("User name %d", i+1), location))
+	}
+
+	var (
+		index   = NewSpatialIndex(customers)
+		centers = []*Coordinate{
+			DublinLocation,
+			{Latitude: decimal.RequireFromString("-16.5"), Longitude: decimal.RequireFromString("179.95")},
+			{Latitude: decimal.RequireFromString("89.9"), Longitude: decimal.RequireFromString("0")},
+		}
+		calculators = []DistanceCalculator{HaversineDistance{}, VincentyDistance{}, FloatHaversineDistance{}}
+	)
+
+	assert.Equal(t, len(customers), index.Len())
+
+	// bruteForce measures every customer, sorting them by distance and ID
+	bruteForce := func(center *Coordinate, calculator DistanceCalculator) NearCustomers {
+		var result = make(NearCustomers, 0, len(customers))
+
+		for _, customer := range customers {
+			result = append(result, NewNearCustomer(customer, calculator.Distance(center, customer.Location)))
+		}
+
+		sortByDistance(result)
+
+		return result
+	}
+
+	// within tells, by their positions, the customers within the radius from the center, checking none of them is
+	// farther than the margin the index allows for
+	within := func(t *testing.T, center *Coordinate, positions []int, radius decimal.Decimal) []int {
+		var (
+			ids   = make([]int, 0, len(positions))
+			bound = radius.Mul(decimal.NewFromFloat(ellipsoidMargin * ellipsoidMargin)).Add(decimal.NewFromInt(1))
+		)
+
+		for _, position := range positions {
+			var customer = customers[position]
+
+			ids = append(ids, customer.ID)
+			assert.Truef(t, HaversineDistance{}.Distance(center, customer.Location).LessThanOrEqual(bound),
+				"customer %d beyond %s", customer.ID, bound)
+		}
+
+		return ids
+	}
+
+	for _, center := range centers {
+		for _, calculator := range calculators {
+			center, calculator := center, calculator
+			all := bruteForce(center, calculator)
+
+			t.Run(fmt.Sprintf("should find the customers within the radius from %v by %T", center, calculator), func(t *testing.T) {
+				for _, radius := range []int64{0, 100, 500, 30_000} {
+					var want = make([]int, 0)
+					for _, customer := range all {
+						if !customer.Distance.GreaterThan(decimal.NewFromInt(radius)) {
+							want = append(want, customer.ID)
+						}
+					}
+
+					got := within(t, center, index.Around(center, decimal.NewFromInt(radius)), decimal.NewFromInt(radius))
+
+					assert.Subsetf(t, got, want, "radius=%d", radius)
+				}
+			})
+
+			t.Run(fmt.Sprintf("should find the customers nearest to %v by %T", center, calculator), func(t *testing.T) {
+				for _, n := range []int{0, 1, 20, len(customers) + 1} {
+					var (
+						want     = make([]int, 0, n)
+						farthest = decimal.Zero
+					)
+
+					for _, customer := range all[:min(n, len(all))] {
+						want = append(want, customer.ID)
+						farthest = customer.Distance
+					}
+
+					got := within(t, center, index.AroundNearest(center, n), farthest)
+
+					assert.Subsetf(t, got, want, "n=%d", n)
+					assert.Equalf(t, n == 0, len(got) == 0, "n=%d", n)
+				}
+			})
+		}
+	}
+
+	t.Run("should find nothing on an empty index", func(t *testing.T) {
+		var empty = NewSpatialIndex(nil)
+
+		assert.Empty(t, empty.Around(DublinLocation, decimal.NewFromInt(100)))
+		assert.Empty(t, empty.AroundNearest(DublinLocation, 10))
+	})
+
+	t.Run("should leave out the customers without location", func(t *testing.T) {
+		var partial = NewSpatialIndex(Customers{{ID: 1}, NewCustomer(2, "Dublin", DublinLocation)})
+
+		assert.Equal(t, 1, partial.Len())
+		assert.Equal(t, []int{1}, partial.Around(DublinLocation, decimal.NewFromInt(1)))
+	})
+}
+
+func BenchmarkSpatialIndex(b *testing.B) {
+	var (
+		random    = rand.New(rand.NewSource(3)) //nolint:gosec // deterministic test data
+		customers = make(Customers, 0, 100_000)
+	)
+
+	for i := 0; i < cap(customers); i++ {
+		customers = append(customers, NewCustomer(i+1, "User name", &Coordinate{
+			Latitude:  decimal.NewFromFloat((random.Float64()*2 - 1) * 90).Round(6),
+			Longitude: decimal.NewFromFloat((random.Float64()*2 - 1) * 180).Round(6),
+		}))
+	}
+
+	var index = NewSpatialIndex(customers)
+
+	b.Run("within 100km", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			index.Around(DublinLocation, decimal.NewFromInt(100))
+		}
+	})
+
+	b.Run("nearest 20", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			index.AroundNearest(DublinLocation, 20)
+		}
+	})
+}
+
+func sortByDistance(customers NearCustomers) {
+	sort.Slice(customers, func(i, j int) bool {
+		if cmp := customers[i].Distance.Cmp(customers[j].Distance); cmp != 0 {
+			return cmp < 0
+		}
+
+		return customers[i].ID < customers[j].ID
+	})
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	wgs84SemiMajorAxisInKm = 6378.137
+	wgs84Flattening        = 1 / 298.257223563
+
+	vincentyMaxIterations = 200
+	vincentyConvergence   = 1e-12
+)
+
+// vincenty calculates the distance, in kilometers, between two coordinates over the WGS-84 ellipsoid applying the
+// Vincenty inverse formula, accurate to less than a millimeter. The iterations are made with float numbers, since
+// they already hold more precision than the formula itself. For nearly antipodal points, where the formula doesn't
+// converge, it falls back to the Haversine formula.
+//
+//nolint:gomnd // coefficients of the formula
+func vincenty(c1 *Coordinate, c2 *Coordinate) decimal.Decimal {
+	var (
+		a = wgs84SemiMajorAxisInKm
+		f = wgs84Flattening
+		b = (1 - f) * a
+
+		latitude1, _  = toRadians(c1.Latitude).Float64()
+		latitude2, _  = toRadians(c2.Latitude).Float64()
+		longitudeD, _ = toRadians(c2.Longitude).Add(toRadians(c1.Longitude).Neg()).Float64()
+
+		u1 = math.Atan((1 - f) * math.Tan(latitude1))
+		u2 = math.Atan((1 - f) * math.Tan(latitude2))
+
+		sinU1, cosU1 = math.Sincos(u1)
+		sinU2, cosU2 = math.Sincos(u2)
+
+		lambda                                        = longitudeD
+		sinSigma, cosSigma, sigma, cosSqAlpha, cos2Sm float64
+		converged                                     bool
+	)
+
+	for i := 0; i < vincentyMaxIterations && !converged; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return decimal.Zero // coincident points
+		}
+
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+
+		cos2Sm = 0 // both points on the equator
+		if cosSqAlpha != 0 {
+			cos2Sm = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+
+		c := f / 16 * cosSqAlpha * (4 + f*(4-3*cosSqAlpha))
+
+		previousLambda := lambda
+		lambda = longitudeD + (1-c)*f*sinAlpha*(sigma+c*sinSigma*(cos2Sm+c*cosSigma*(-1+2*cos2Sm*cos2Sm)))
+
+		converged = math.Abs(lambda-previousLambda) < vincentyConvergence
+	}
+
+	if !converged {
+		return haversine(c1, c2)
+	}
+
+	var (
+		uSq        = cosSqAlpha * (a*a - b*b) / (b * b)
+		bigA       = 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+		bigB       = uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+		deltaSigma = bigB * sinSigma * (cos2Sm + bigB/4*(cosSigma*(-1+2*cos2Sm*cos2Sm)-
+			bigB/6*cos2Sm*(-3+4*sinSigma*sinSigma)*(-3+4*cos2Sm*cos2Sm)))
+	)
+
+	return decimal.NewFromFloat(b * bigA * (sigma - deltaSigma))
+}
@@ -0,0 +1,485 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+const (
+	cacheFileExtension = ".cache"
+	cacheTempPrefix    = ".tmp-"
+	cacheDirPerm       = 0o750
+
+	// cacheFileMagic starts every cache file, identifying its format and version.
+	cacheFileMagic = "PICACHE1"
+
+	// The header of every cache file is the magic, the expiration time and the response length, as 64 bits
+	// integers, and the response checksum, followed by the response itself.
+	cacheFileExpiresOffset  = len(cacheFileMagic)
+	cacheFileLengthOffset   = cacheFileExpiresOffset + 8
+	cacheFileChecksumOffset = cacheFileLengthOffset + 8
+	cacheFileHeaderSize     = cacheFileChecksumOffset + sha256.Size
+)
+
+var errCorruptedCacheFile = errors.New("corrupted cache file")
+
+// FileSystemFilterCustomersCache stores every filter customers response as a file on the given directory, named by
+// the hash of its key, so the cached responses survive restarts. It's bounded by both its number of files and their
+// total size, in bytes, evicting the least recently used ones, and its responses expire once their TTL elapses.
+// Files are written to a temporary file renamed over the final one, so they're never read half-written, and carry
+// the checksum of the response, so a corrupted file is dropped rather than served. The directory must not be shared
+// with other processes, as the usage is tracked in memory.
+type FileSystemFilterCustomersCache struct {
+	log        logger.Logger
+	dir        string
+	maxEntries int
+	maxBytes   int64
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	files   map[string]*list.Element
+	recency *list.List // of *cacheFile, the most recently used first
+	bytes   int64
+	metrics CacheMetrics
+}
+
+// cacheFile is the name and size of a file of the cache directory.
+type cacheFile struct {
+	name string
+	size int64
+}
+
+// NewFileSystemFilterCustomersCache builds a cache on the directory, creating it when it doesn't exist, holding up to
+// maxEntries files and maxBytes, whose responses expire after the ttl, or never when it's zero. The files already on
+// the directory are kept, the most recently used first, while the temporary ones left by interrupted writes are
+// removed.
+func NewFileSystemFilterCustomersCache(
+	log logger.Logger,
+	dir string,
+	maxEntries int,
+	maxBytes int64,
+	ttl time.Duration,
+) (*FileSystemFilterCustomersCache, error) {
+	if err := os.MkdirAll(dir, cacheDirPerm); err != nil {
+		return nil, errors.Wrapf(err, "error to create cache directory, path: %s", dir)
+	}
+
+	var cache = &FileSystemFilterCustomersCache{
+		log:        log,
+		dir:        dir,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ttl:        ttl,
+		now:        time.Now,
+		files:      make(map[string]*list.Element),
+		recency:    list.New(),
+	}
+
+	if err := cache.load(); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// load indexes the cache files on the directory by their modification time, which is updated on every hit.
+func (f *FileSystemFilterCustomersCache) load() error {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return errors.Wrapf(err, "error to read cache directory, path: %s", f.dir)
+	}
+
+	type indexedFile struct {
+		cacheFile
+		modTime time.Time
+	}
+
+	var indexed = make([]indexedFile, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		if strings.HasPrefix(entry.Name(), cacheTempPrefix) {
+			os.Remove(filepath.Join(f.dir, entry.Name())) //nolint:errcheck,gosec // left by an interrupted write
+			continue
+		}
+
+		if filepath.Ext(entry.Name()) != cacheFileExtension {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed meanwhile
+		}
+
+		indexed = append(indexed, indexedFile{
+			cacheFile: cacheFile{name: entry.Name(), size: info.Size()},
+			modTime:   info.ModTime(),
+		})
+	}
+
+	sort.Slice(indexed, func(i, j int) bool { return indexed[i].modTime.Before(indexed[j].modTime) })
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range indexed {
+		f.files[indexed[i].name] = f.recency.PushFront(&indexed[i].cacheFile)
+		f.bytes += indexed[i].size
+	}
+
+	f.metrics.Evictions += uint64(f.evict())
+
+	return nil
+}
+
+// Get returns the cached response of the key, or nil when it's not cached, has expired or its file is corrupted.
+func (f *FileSystemFilterCustomersCache) Get(ctx context.Context, key string) ([]byte, error) {
+	var (
+		log  = f.log.FromContext(ctx)
+		name = cacheFileName(key)
+		path = filepath.Join(f.dir, name)
+	)
+
+	f.mu.Lock()
+	element, ok := f.files[name]
+	f.mu.Unlock()
+
+	if !ok {
+		return f.miss(log, key)
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		f.drop(element, nil) // evicted meanwhile
+
+		return f.miss(log, key)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error to read cache file")
+	}
+
+	expiresAt, response, err := decodeCacheFile(content)
+	if err != nil {
+		if f.drop(element, func(m *CacheMetrics) { m.Corruptions++ }) {
+			log.Errorf("Cache file dropped, key=%s path=%s err=%v", key, path, err)
+		}
+
+		return f.miss(log, key)
+	}
+
+	if !expiresAt.IsZero() && !f.now().Before(expiresAt) {
+		f.drop(element, func(m *CacheMetrics) { m.Expirations++ })
+
+		return f.miss(log, key)
+	}
+
+	f.mu.Lock()
+	if element, ok := f.files[name]; ok {
+		f.recency.MoveToFront(element)
+	}
+	f.metrics.Hits++
+	f.mu.Unlock()
+
+	// makes the file the most recently used one after a restart too
+	if err = os.Chtimes(path, f.now(), f.now()); err != nil {
+		log.Errorf("Error to touch cache file, path=%s err=%v", path, err)
+	}
+
+	log.Infof("Cache hit, key=%s", key)
+
+	return response, nil
+}
+
+func (f *FileSystemFilterCustomersCache) miss(log logger.Logger, key string) ([]byte, error) {
+	f.mu.Lock()
+	f.metrics.Misses++
+	f.mu.Unlock()
+
+	log.Infof("Cache miss, key=%s", key)
+
+	return nil, nil
+}
+
+// Save writes the response as the most recently used file, evicting the least recently used ones until the cache
+// is within its bounds again. A response bigger than the bytes bound alone isn't stored.
+func (f *FileSystemFilterCustomersCache) Save(ctx context.Context, key string, response []byte) error {
+	var (
+		log       = f.log.FromContext(ctx)
+		name      = cacheFileName(key)
+		expiresAt time.Time
+	)
+
+	if f.ttl > 0 {
+		expiresAt = f.now().Add(f.ttl)
+	}
+
+	var content = encodeCacheFile(expiresAt, response)
+
+	if int64(len(content)) > f.maxBytes {
+		f.mu.Lock()
+		f.metrics.Rejections++
+		f.mu.Unlock()
+
+		log.Infof("Cache skipped, response bigger than the cache, key=%s bytes=%d", key, len(content))
+
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(f.dir, cacheTempPrefix+"*")
+	if err != nil {
+		return errors.Wrap(err, "error to create cache file")
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // already renamed on success
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close() //nolint:errcheck,gosec // the write error is the relevant one
+		return errors.Wrap(err, "error to write cache file")
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close() //nolint:errcheck,gosec // the sync error is the relevant one
+		return errors.Wrap(err, "error to write cache file")
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "error to write cache file")
+	}
+
+	// the modification time keeps the recency across restarts
+	if err = os.Chtimes(tmp.Name(), f.now(), f.now()); err != nil {
+		return errors.Wrap(err, "error to write cache file")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err = os.Rename(tmp.Name(), filepath.Join(f.dir, name)); err != nil {
+		return errors.Wrap(err, "error to write cache file")
+	}
+
+	if element, ok := f.files[name]; ok {
+		f.unindex(element)
+	}
+
+	f.files[name] = f.recency.PushFront(&cacheFile{name: name, size: int64(len(content))})
+	f.bytes += int64(len(content))
+
+	var evicted = f.evict()
+
+	f.metrics.Evictions += uint64(evicted)
+
+	log.Infof("Cache updated, key=%s evicted=%d entries=%d bytes=%d", key, evicted, len(f.files), f.bytes)
+
+	return nil
+}
+
+// Metrics returns a snapshot of the cache metrics.
+func (f *FileSystemFilterCustomersCache) Metrics() CacheMetrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var metrics = f.metrics
+	metrics.Entries = len(f.files)
+	metrics.Bytes = f.bytes
+
+	return metrics
+}
+
+// StartJanitor removes the expired and corrupted files on every interval, logging the cache metrics, until the
+// context is done.
+func (f *FileSystemFilterCustomersCache) StartJanitor(ctx context.Context, interval time.Duration) {
+	startJanitor(ctx, f.log, interval, f.removeExpired, f.Metrics)
+}
+
+// removeExpired removes every expired or corrupted file, reading only their headers, returning how many were removed.
+func (f *FileSystemFilterCustomersCache) removeExpired() int {
+	f.mu.Lock()
+	var elements = make([]*list.Element, 0, len(f.files))
+	for _, element := range f.files {
+		elements = append(elements, element)
+	}
+	f.mu.Unlock()
+
+	var removed = 0
+
+	for _, element := range elements {
+		var path = filepath.Join(f.dir, element.Value.(*cacheFile).name)
+
+		expiresAt, err := readCacheFileExpiration(path)
+
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			f.drop(element, nil)
+
+		case errors.Is(err, errCorruptedCacheFile), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+			if f.drop(element, func(m *CacheMetrics) { m.Corruptions++ }) {
+				f.log.Errorf("Cache file dropped, path=%s err=%v", path, err)
+				removed++
+			}
+
+		case err != nil:
+			f.log.Errorf("Error to read cache file, path=%s err=%v", path, err)
+
+		case !expiresAt.IsZero() && !f.now().Before(expiresAt):
+			if f.drop(element, func(m *CacheMetrics) { m.Expirations++ }) {
+				removed++
+			}
+		}
+	}
+
+	return removed
+}
+
+// drop removes the file of the element, read while the lock wasn't held, and unindexes it, counting it on the
+// metrics through the count func, when it's not nil. It tells whether the file was removed, which it isn't when the
+// element was dropped or replaced meanwhile, as the file on its path is no longer the one read, but maybe a fresh one
+// renamed by a concurrent Save, which holds the lock while renaming.
+func (f *FileSystemFilterCustomersCache) drop(element *list.Element, count func(*CacheMetrics)) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.files[element.Value.(*cacheFile).name] != element {
+		return false
+	}
+
+	f.remove(element)
+
+	if count != nil {
+		count(&f.metrics)
+	}
+
+	return true
+}
+
+// evict removes the least recently used files until the cache is within its bounds, returning how many were removed.
+// The lock must be held by the caller.
+func (f *FileSystemFilterCustomersCache) evict() int {
+	var evicted = 0
+
+	for len(f.files) > f.maxEntries || f.bytes > f.maxBytes {
+		f.remove(f.recency.Back())
+		evicted++
+	}
+
+	return evicted
+}
+
+// remove deletes the file of the element and unindexes it. The lock must be held by the caller.
+func (f *FileSystemFilterCustomersCache) remove(element *list.Element) {
+	var path = filepath.Join(f.dir, element.Value.(*cacheFile).name)
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		f.log.Errorf("Error to remove cache file, path=%s err=%v", path, err)
+	}
+
+	f.unindex(element)
+}
+
+// unindex forgets the file of the element, without deleting it. The lock must be held by the caller.
+func (f *FileSystemFilterCustomersCache) unindex(element *list.Element) {
+	var file = f.recency.Remove(element).(*cacheFile)
+
+	delete(f.files, file.name)
+	f.bytes -= file.size
+}
+
+// cacheFileName names the file of the key by its hash, so any key maps to a plain file name.
+func cacheFileName(key string) string {
+	var sum = sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:]) + cacheFileExtension
+}
+
+// encodeCacheFile prefixes the response with the header: the magic, the expiration time, as Unix nanoseconds or zero
+// when it never expires, the response length and its checksum.
+func encodeCacheFile(expiresAt time.Time, response []byte) []byte {
+	var (
+		content  = make([]byte, 0, cacheFileHeaderSize+len(response))
+		checksum = sha256.Sum256(response)
+		expires  int64
+	)
+
+	if !expiresAt.IsZero() {
+		expires = expiresAt.UnixNano()
+	}
+
+	content = append(content, cacheFileMagic...)
+	content = binary.BigEndian.AppendUint64(content, uint64(expires))
+	content = binary.BigEndian.AppendUint64(content, uint64(len(response)))
+	content = append(content, checksum[:]...)
+	content = append(content, response...)
+
+	return content
+}
+
+// decodeCacheFile returns the expiration time and the response of the cache file, checking its integrity.
+func decodeCacheFile(content []byte) (time.Time, []byte, error) {
+	if len(content) < cacheFileHeaderSize || !bytes.HasPrefix(content, []byte(cacheFileMagic)) {
+		return time.Time{}, nil, errors.Wrap(errCorruptedCacheFile, "invalid header")
+	}
+
+	var (
+		expires  = int64(binary.BigEndian.Uint64(content[cacheFileExpiresOffset:cacheFileLengthOffset]))
+		length   = binary.BigEndian.Uint64(content[cacheFileLengthOffset:cacheFileChecksumOffset])
+		checksum = content[cacheFileChecksumOffset:cacheFileHeaderSize]
+		response = content[cacheFileHeaderSize:]
+	)
+
+	if uint64(len(response)) != length {
+		return time.Time{}, nil, errors.Wrapf(errCorruptedCacheFile, "expected %d bytes, got %d", length, len(response))
+	}
+
+	if sum := sha256.Sum256(response); !bytes.Equal(sum[:], checksum) {
+		return time.Time{}, nil, errors.Wrap(errCorruptedCacheFile, "checksum mismatch")
+	}
+
+	if expires == 0 {
+		return time.Time{}, response, nil
+	}
+
+	return time.Unix(0, expires), response, nil
+}
+
+// readCacheFileExpiration reads the expiration time from the header of the cache file, without reading the response.
+func readCacheFileExpiration(path string) (time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close() //nolint:errcheck // read only
+
+	var header = make([]byte, cacheFileLengthOffset)
+	if _, err = io.ReadFull(file, header); err != nil {
+		return time.Time{}, err
+	}
+
+	if !bytes.HasPrefix(header, []byte(cacheFileMagic)) {
+		return time.Time{}, errors.Wrap(errCorruptedCacheFile, "invalid header")
+	}
+
+	if expires := int64(binary.BigEndian.Uint64(header[cacheFileExpiresOffset:])); expires != 0 {
+		return time.Unix(0, expires), nil
+	}
+
+	return time.Time{}, nil
+}
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+func TestFileSystemFilterCustomersCache(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx = context.Background()
+		// every file takes 64 bytes, a 56 bytes header along with an 8 bytes response
+		entrySize = int64(cacheFileHeaderSize + len("response"))
+	)
+
+	newCache := func(t *testing.T, dir string, maxEntries int, maxBytes int64, ttl time.Duration, now *clock) *FileSystemFilterCustomersCache {
+		c, err := NewFileSystemFilterCustomersCache(logger.NewEmptyLogger(), dir, maxEntries, maxBytes, ttl)
+		if err != nil {
+			t.Fatalf("failed to build cache: %v", err)
+		}
+
+		c.now = now.Now
+
+		return c
+	}
+	// saves the keys one second apart from each other
+	save := func(c *FileSystemFilterCustomersCache, now *clock, keys ...string) {
+		for _, key := range keys {
+			now.now = now.now.Add(time.Second)
+			assert.NoError(t, c.Save(ctx, key, []byte("response")))
+		}
+	}
+	cached := func(c *FileSystemFilterCustomersCache, keys ...string) []string {
+		var found = make([]string, 0)
+
+		for _, key := range keys {
+			response, err := c.Get(ctx, key)
+			assert.NoError(t, err)
+
+			if response != nil {
+				assert.Equal(t, []byte("response"), response)
+				found = append(found, key)
+			}
+		}
+
+		return found
+	}
+
+	t.Run("should keep the cached responses and their recency across restarts", func(t *testing.T) {
+		var (
+			dir = t.TempDir()
+			now = &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+			c   = newCache(t, dir, 3, 1024, 0, now)
+		)
+
+		save(c, now, "k1", "k2", "k3")
+		now.now = now.now.Add(time.Second)
+		assert.Equal(t, []string{"k1"}, cached(c, "k1")) // k1 becomes the most recently used
+
+		// a write interrupted before its rename, and a file unrelated to the cache
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, cacheTempPrefix+"123"), []byte("half"), 0o600))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("unrelated"), 0o600))
+
+		var restarted = newCache(t, dir, 3, 1024, 0, now)
+
+		assert.Equal(t, CacheMetrics{Entries: 3, Bytes: 3 * entrySize}, restarted.Metrics())
+		assert.NoFileExists(t, filepath.Join(dir, cacheTempPrefix+"123"))
+		assert.FileExists(t, filepath.Join(dir, "README"))
+
+		save(restarted, now, "k4")
+
+		assert.Equal(t, []string{"k1", "k3", "k4"}, cached(restarted, "k1", "k2", "k3", "k4"))
+		assert.Equal(t, CacheMetrics{Hits: 3, Misses: 1, Evictions: 1, Entries: 3, Bytes: 3 * entrySize}, restarted.Metrics())
+	})
+
+	t.Run("should evict the least recently used files beyond the max bytes", func(t *testing.T) {
+		var (
+			now = &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+			c   = newCache(t, t.TempDir(), 10, 2*entrySize, 0, now)
+		)
+
+		save(c, now, "k1", "k2", "k3")
+		assert.NoError(t, c.Save(ctx, "k4", make([]byte, 2*entrySize)))
+
+		assert.Equal(t, []string{"k2", "k3"}, cached(c, "k1", "k2", "k3"))
+		assert.Equal(t, CacheMetrics{Hits: 2, Misses: 1, Evictions: 1, Rejections: 1, Entries: 2, Bytes: 2 * entrySize}, c.Metrics())
+	})
+
+	t.Run("should drop the corrupted files", func(t *testing.T) {
+		var (
+			dir = t.TempDir()
+			now = &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+			c   = newCache(t, dir, 10, 1024, 0, now)
+		)
+
+		save(c, now, "flipped", "truncated", "garbage", "intact")
+
+		corrupt := func(key string, change func([]byte) []byte) {
+			path := filepath.Join(dir, cacheFileName(key))
+
+			content, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.NoError(t, os.WriteFile(path, change(content), 0o600))
+		}
+
+		corrupt("flipped", func(content []byte) []byte { content[len(content)-1] ^= 1; return content })
+		corrupt("truncated", func(content []byte) []byte { return content[:len(content)-1] })
+		corrupt("garbage", func([]byte) []byte { return []byte("garbage") })
+
+		assert.Equal(t, []string{"intact"}, cached(c, "flipped", "truncated", "intact"))
+		assert.NoFileExists(t, filepath.Join(dir, cacheFileName("flipped")))
+		assert.NoFileExists(t, filepath.Join(dir, cacheFileName("truncated")))
+
+		// the janitor only reads the header, so it finds the garbage one but not a flipped response
+		assert.Equal(t, 1, c.removeExpired())
+		assert.NoFileExists(t, filepath.Join(dir, cacheFileName("garbage")))
+
+		assert.Equal(t, CacheMetrics{Hits: 1, Misses: 2, Corruptions: 3, Entries: 1, Bytes: entrySize}, c.Metrics())
+	})
+
+	t.Run("should expire the files once their ttl elapses", func(t *testing.T) {
+		var (
+			dir = t.TempDir()
+			now = &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+			c   = newCache(t, dir, 10, 1024, time.Minute, now)
+		)
+
+		save(c, now, "k1", "k2", "k3")
+		now.now = now.now.Add(59 * time.Second)
+
+		// k1 and k2 were saved a minute or more ago, while k3 was saved 59 seconds ago
+		assert.Empty(t, cached(c, "k1"))
+		assert.Equal(t, 1, c.removeExpired())
+		assert.Equal(t, []string{"k3"}, cached(c, "k3"))
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+
+		assert.Equal(t, CacheMetrics{Hits: 1, Misses: 1, Expirations: 2, Entries: 1, Bytes: entrySize}, c.Metrics())
+	})
+
+	t.Run("should keep the file saved while an expired one is read", func(t *testing.T) {
+		var (
+			dir   = t.TempDir()
+			now   = &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+			c     = newCache(t, dir, 10, 1024, time.Minute, now)
+			saved = false
+		)
+
+		save(c, now, "k1")
+		now.now = now.now.Add(time.Minute)
+
+		// the fresh file is saved right after the expired one is read, as its expiration is checked
+		c.now = func() time.Time {
+			if !saved {
+				saved = true
+				assert.NoError(t, c.Save(ctx, "k1", []byte("fresh")))
+			}
+
+			return now.Now()
+		}
+
+		assert.Empty(t, cached(c, "k1"))
+
+		response, err := c.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("fresh"), response)
+		assert.FileExists(t, filepath.Join(dir, cacheFileName("k1")))
+
+		// nor the janitor finds anything to remove
+		assert.Equal(t, 0, c.removeExpired())
+		assert.Equal(t, 1, c.Metrics().Entries)
+	})
+}
+
+func Test_decodeCacheFile(t *testing.T) {
+	t.Parallel()
+
+	var expiresAt = time.Unix(0, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+
+	gotExpiresAt, gotResponse, err := decodeCacheFile(encodeCacheFile(expiresAt, []byte("response")))
+	assert.NoError(t, err)
+	assert.Equal(t, expiresAt, gotExpiresAt)
+	assert.Equal(t, []byte("response"), gotResponse)
+
+	gotExpiresAt, gotResponse, err = decodeCacheFile(encodeCacheFile(time.Time{}, []byte{}))
+	assert.NoError(t, err)
+	assert.True(t, gotExpiresAt.IsZero())
+	assert.Empty(t, gotResponse)
+
+	_, _, err = decodeCacheFile(append(encodeCacheFile(time.Time{}, []byte("response")), 'x'))
+	assert.ErrorIs(t, err, errCorruptedCacheFile)
+	assert.ErrorContains(t, err, "expected 8 bytes, got 9")
+}
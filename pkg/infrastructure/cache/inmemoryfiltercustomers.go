@@ -1,59 +1,179 @@
 package cache
 
 import (
+	"container/list"
 	"context"
-	"crypto/md5"
-	"fmt"
 	"sync"
-
-	"github.com/pkg/errors"
+	"time"
 
 	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
 )
 
+// InMemoryFilterCustomersCache is a least recently used cache of filter customers responses, bounded by both its
+// number of entries and their total size, in bytes, whose entries expire once their TTL elapses. Expired entries are
+// dropped when found by Get, and periodically by the janitor, so they don't hold memory until they're evicted.
 type InMemoryFilterCustomersCache struct {
-	data sync.Map
-	log  logger.Logger
+	log        logger.Logger
+	maxEntries int
+	maxBytes   int64
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	recency *list.List // of *cacheEntry, the most recently used first
+	bytes   int64
+	metrics CacheMetrics
+}
+
+// cacheEntry is a cached response along with its key, so the evicted entries can be unindexed.
+type cacheEntry struct {
+	key       string
+	response  []byte
+	expiresAt time.Time // zero when the entry never expires
+}
+
+// size is what the entry counts towards the bytes bound.
+func (e *cacheEntry) size() int64 {
+	return int64(len(e.key) + len(e.response))
 }
 
-func NewInMemoryFilterCustomersCache(log logger.Logger) *InMemoryFilterCustomersCache {
+// NewInMemoryFilterCustomersCache builds a cache holding up to maxEntries responses and maxBytes, counting the sizes
+// of their keys and contents, whose entries expire after the ttl, or never when it's zero.
+func NewInMemoryFilterCustomersCache(
+	log logger.Logger,
+	maxEntries int,
+	maxBytes int64,
+	ttl time.Duration,
+) *InMemoryFilterCustomersCache {
 	return &InMemoryFilterCustomersCache{
-		data: sync.Map{},
-		log:  log,
+		log:        log,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ttl:        ttl,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		recency:    list.New(),
 	}
 }
 
-func (f *InMemoryFilterCustomersCache) Get(ctx context.Context, fileContents []byte) ([]byte, error) {
+func (f *InMemoryFilterCustomersCache) Get(ctx context.Context, key string) ([]byte, error) {
 	log := f.log.FromContext(ctx)
 
-	key := fileMd5(fileContents)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	element, ok := f.entries[key]
+	if ok && f.expired(element.Value.(*cacheEntry)) {
+		f.remove(element)
+		f.metrics.Expirations++
+
+		ok = false
+	}
 
-	content, ok := f.data.Load(key)
 	if !ok {
+		f.metrics.Misses++
 		log.Infof("Cache miss, key=%s", key)
 
 		return nil, nil
 	}
 
-	if v, ok := content.([]byte); ok {
-		log.Infof("Cache hit, key=%s", key)
+	f.recency.MoveToFront(element)
+	f.metrics.Hits++
+	log.Infof("Cache hit, key=%s", key)
+
+	return element.Value.(*cacheEntry).response, nil
+}
+
+// Save stores the response as the most recently used entry, evicting the least recently used ones until the cache
+// is within its bounds again. A response bigger than the bytes bound alone isn't stored.
+func (f *InMemoryFilterCustomersCache) Save(ctx context.Context, key string, response []byte) error {
+	log := f.log.FromContext(ctx)
+
+	var entry = &cacheEntry{key: key, response: response}
+	if f.ttl > 0 {
+		entry.expiresAt = f.now().Add(f.ttl)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
 
-		return v, nil
+	if element, ok := f.entries[key]; ok {
+		f.remove(element)
 	}
 
-	return nil, errors.New("error to load content on cache")
-}
+	if entry.size() > f.maxBytes {
+		f.metrics.Rejections++
+		log.Infof("Cache skipped, response bigger than the cache, key=%s bytes=%d", key, entry.size())
+
+		return nil
+	}
 
-func (f *InMemoryFilterCustomersCache) Save(ctx context.Context, fileContents []byte, response []byte) error {
-	key := fileMd5(fileContents)
+	f.entries[key] = f.recency.PushFront(entry)
+	f.bytes += entry.size()
 
-	f.data.Store(key, response)
+	var evicted = 0
+
+	for len(f.entries) > f.maxEntries || f.bytes > f.maxBytes {
+		f.remove(f.recency.Back())
+		evicted++
+	}
 
-	f.log.FromContext(ctx).Infof("Cache updated, key=%s", key)
+	f.metrics.Evictions += uint64(evicted)
+
+	log.Infof("Cache updated, key=%s evicted=%d entries=%d bytes=%d", key, evicted, len(f.entries), f.bytes)
 
 	return nil
 }
 
-func fileMd5(content []byte) string {
-	return fmt.Sprintf("%x", md5.Sum(content))
+// Metrics returns a snapshot of the cache metrics.
+func (f *InMemoryFilterCustomersCache) Metrics() CacheMetrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var metrics = f.metrics
+	metrics.Entries = len(f.entries)
+	metrics.Bytes = f.bytes
+
+	return metrics
+}
+
+// StartJanitor drops the expired entries on every interval, logging the cache metrics, until the context is done.
+func (f *InMemoryFilterCustomersCache) StartJanitor(ctx context.Context, interval time.Duration) {
+	startJanitor(ctx, f.log, interval, f.removeExpired, f.Metrics)
+}
+
+// removeExpired drops every expired entry, returning how many were dropped.
+func (f *InMemoryFilterCustomersCache) removeExpired() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var expired = 0
+
+	for element := f.recency.Back(); element != nil; {
+		previous := element.Prev()
+
+		if f.expired(element.Value.(*cacheEntry)) {
+			f.remove(element)
+			expired++
+		}
+
+		element = previous
+	}
+
+	f.metrics.Expirations += uint64(expired)
+
+	return expired
+}
+
+func (f *InMemoryFilterCustomersCache) expired(entry *cacheEntry) bool {
+	return !entry.expiresAt.IsZero() && !f.now().Before(entry.expiresAt)
+}
+
+// remove drops the entry of the element. The lock must be held by the caller.
+func (f *InMemoryFilterCustomersCache) remove(element *list.Element) {
+	var entry = f.recency.Remove(element).(*cacheEntry)
+
+	delete(f.entries, entry.key)
+	f.bytes -= entry.size()
 }
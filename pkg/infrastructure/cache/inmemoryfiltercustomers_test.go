@@ -2,10 +2,13 @@ package cache
 
 import (
 	"context"
-	"github.com/stretchr/testify/assert"
-	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
 )
 
 func TestNewInMemoryFilterCustomersCache(t *testing.T) {
@@ -13,17 +16,122 @@ func TestNewInMemoryFilterCustomersCache(t *testing.T) {
 
 	var ctx = context.Background()
 	var log = logger.NewLogger(os.Stdout)
-	var c = NewInMemoryFilterCustomersCache(log)
-	var reader1 = []byte(`{1}`)
+	var c = NewInMemoryFilterCustomersCache(log, 10, 1024, time.Minute)
+	var key1 = "d41d8cd98f00b204e9800998ecf8427e"
 
-	result1, err1 := c.Get(ctx, reader1)
+	result1, err1 := c.Get(ctx, key1)
 	assert.Nil(t, err1)
 	assert.Nil(t, result1)
 
-	err2 := c.Save(ctx, reader1, []byte(`response 1`))
+	err2 := c.Save(ctx, key1, []byte(`response 1`))
 	assert.Nil(t, err2)
 
-	result3, err2 := c.Get(ctx, reader1)
+	result3, err2 := c.Get(ctx, key1)
 	assert.Nil(t, err2)
 	assert.Equal(t, result3, []byte(`response 1`))
 }
+
+// clock is a manually advanced time source.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestInMemoryFilterCustomersCache_bounds(t *testing.T) {
+	t.Parallel()
+
+	var ctx = context.Background()
+
+	// every entry takes 10 bytes, a 2 bytes key along with an 8 bytes response
+	save := func(c *InMemoryFilterCustomersCache, keys ...string) {
+		for _, key := range keys {
+			assert.NoError(t, c.Save(ctx, key, []byte("response")))
+		}
+	}
+	cached := func(c *InMemoryFilterCustomersCache, keys ...string) []string {
+		var found = make([]string, 0)
+
+		for _, key := range keys {
+			if response, _ := c.Get(ctx, key); response != nil {
+				found = append(found, key)
+			}
+		}
+
+		return found
+	}
+
+	t.Run("should evict the least recently used entries beyond the max entries", func(t *testing.T) {
+		var c = NewInMemoryFilterCustomersCache(logger.NewEmptyLogger(), 3, 1024, 0)
+
+		save(c, "k1", "k2", "k3")
+		assert.Equal(t, []string{"k1"}, cached(c, "k1")) // k1 becomes the most recently used
+		save(c, "k4", "k5")
+
+		assert.Equal(t, []string{"k1", "k4", "k5"}, cached(c, "k1", "k2", "k3", "k4", "k5"))
+		assert.Equal(t, CacheMetrics{Hits: 4, Misses: 2, Evictions: 2, Entries: 3, Bytes: 30}, c.Metrics())
+	})
+
+	t.Run("should evict the least recently used entries beyond the max bytes", func(t *testing.T) {
+		var c = NewInMemoryFilterCustomersCache(logger.NewEmptyLogger(), 10, 25, 0)
+
+		save(c, "k1", "k2", "k3")
+
+		assert.Equal(t, []string{"k2", "k3"}, cached(c, "k1", "k2", "k3"))
+		assert.Equal(t, CacheMetrics{Hits: 2, Misses: 1, Evictions: 1, Entries: 2, Bytes: 20}, c.Metrics())
+	})
+
+	t.Run("should replace an entry, not storing a response bigger than the max bytes", func(t *testing.T) {
+		var c = NewInMemoryFilterCustomersCache(logger.NewEmptyLogger(), 10, 25, 0)
+
+		save(c, "k1", "k2", "k1")
+		assert.NoError(t, c.Save(ctx, "k2", []byte("a response too big to be cached")))
+
+		assert.Equal(t, []string{"k1"}, cached(c, "k1", "k2"))
+		assert.Equal(t, CacheMetrics{Hits: 1, Misses: 1, Rejections: 1, Entries: 1, Bytes: 10}, c.Metrics())
+	})
+
+	t.Run("should expire the entries once their ttl elapses", func(t *testing.T) {
+		var (
+			now = &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+			c   = NewInMemoryFilterCustomersCache(logger.NewEmptyLogger(), 10, 1024, time.Minute)
+		)
+
+		c.now = now.Now
+
+		save(c, "k1", "k2")
+		now.now = now.now.Add(30 * time.Second)
+		save(c, "k3", "k1") // k1 is saved again, so it lives longer
+
+		now.now = now.now.Add(30 * time.Second)
+		assert.Equal(t, []string{"k1", "k3"}, cached(c, "k1", "k3"))
+		assert.Equal(t, 1, c.removeExpired())
+
+		now.now = now.now.Add(time.Minute)
+		assert.Empty(t, cached(c, "k1"))
+		assert.Equal(t, 1, c.removeExpired())
+
+		assert.Equal(t, CacheMetrics{Hits: 2, Misses: 1, Expirations: 3}, c.Metrics())
+	})
+}
+
+func TestInMemoryFilterCustomersCache_StartJanitor(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		c           = NewInMemoryFilterCustomersCache(logger.NewEmptyLogger(), 10, 1024, time.Millisecond)
+	)
+
+	defer cancel()
+
+	assert.NoError(t, c.Save(ctx, "k1", []byte("response")))
+
+	c.StartJanitor(ctx, time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return c.Metrics() == CacheMetrics{Expirations: 1}
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+// CacheMetrics counts what happened to the cache entries since it was built, along with its current usage.
+type CacheMetrics struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64 // entries dropped to keep the cache within its bounds
+	Expirations uint64 // entries dropped once their TTL elapsed
+	Rejections  uint64 // responses not cached for being bigger than the bytes bound alone
+	Corruptions uint64 // entries dropped for failing their integrity check, only on persistent caches
+	Entries     int
+	Bytes       int64
+}
+
+// startJanitor calls removeExpired on every interval, logging how many entries it removed along with the metrics,
+// until the context is done.
+func startJanitor(
+	ctx context.Context,
+	log logger.Logger,
+	interval time.Duration,
+	removeExpired func() int,
+	metrics func() CacheMetrics,
+) {
+	go func() {
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+				removed := removeExpired()
+				current := metrics()
+
+				log.Infof(
+					"Cache janitor, removed=%d entries=%d bytes=%d hits=%d misses=%d evictions=%d expirations=%d corruptions=%d",
+					removed,
+					current.Entries,
+					current.Bytes,
+					current.Hits,
+					current.Misses,
+					current.Evictions,
+					current.Expirations,
+					current.Corruptions,
+				)
+			}
+		}
+	}()
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+const (
+	// redisMaxIdleConns is how many connections are kept open between commands.
+	redisMaxIdleConns = 8
+
+	// redisRetryInterval is how long the server isn't reached after a failure, so requests aren't slowed down by
+	// timeouts while it's down.
+	redisRetryInterval = 5 * time.Second
+)
+
+var errRedisUnavailable = errors.New("redis unavailable")
+
+// RedisFilterCustomersCache stores the filter customers responses on a server speaking the Redis protocol, so they're
+// shared by every instance of the application. Keys are prefixed, so several applications may share the server, and
+// responses expire once their TTL elapses, while the server bounds its own memory. When the server can't be reached
+// every response is answered as a miss, and it's only tried again after a while.
+type RedisFilterCustomersCache struct {
+	log     logger.Logger
+	addr    string
+	prefix  string
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	idle      []*redisConn
+	downUntil time.Time
+}
+
+// redisConn is a connection to the server along with its buffers.
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// NewRedisFilterCustomersCache builds a cache on the server listening on addr, like "localhost:6379", whose keys
+// start with the prefix and a colon, and whose responses expire after the ttl, or never when it's zero. Every command
+// must be answered within the timeout. The server is only reached on the first command.
+func NewRedisFilterCustomersCache(
+	log logger.Logger,
+	addr string,
+	prefix string,
+	ttl time.Duration,
+	timeout time.Duration,
+) *RedisFilterCustomersCache {
+	return &RedisFilterCustomersCache{
+		log:     log,
+		addr:    addr,
+		prefix:  prefix,
+		ttl:     ttl,
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// Get returns the cached response of the key, or nil when it's not cached or the server is unavailable.
+func (f *RedisFilterCustomersCache) Get(ctx context.Context, key string) ([]byte, error) {
+	log := f.log.FromContext(ctx)
+
+	reply, err := f.do(ctx, []byte("GET"), []byte(f.prefix+":"+key))
+	if err != nil {
+		log.Errorf("Cache unavailable, answered as a miss, key=%s err=%v", key, err)
+		return nil, nil
+	}
+
+	response, ok := reply.([]byte)
+	if !ok {
+		log.Infof("Cache miss, key=%s", key)
+		return nil, nil
+	}
+
+	log.Infof("Cache hit, key=%s", key)
+
+	return response, nil
+}
+
+// Save stores the response, replacing any response of the same key.
+func (f *RedisFilterCustomersCache) Save(ctx context.Context, key string, response []byte) error {
+	log := f.log.FromContext(ctx)
+
+	var args = [][]byte{[]byte("SET"), []byte(f.prefix + ":" + key), response}
+	if f.ttl > 0 {
+		args = append(args, []byte("PX"), []byte(strconv.FormatInt(f.ttl.Milliseconds(), 10)))
+	}
+
+	if _, err := f.do(ctx, args...); err != nil {
+		return errors.Wrap(err, "error to store cached response")
+	}
+
+	log.Infof("Cache updated, key=%s bytes=%d", key, len(response))
+
+	return nil
+}
+
+// do sends the command on an idle connection, or a new one, returning its reply. Error replies are returned as
+// errors, while any other failure closes the connection and keeps the server from being reached for a while.
+func (f *RedisFilterCustomersCache) do(ctx context.Context, args ...[]byte) (any, error) {
+	conn, err := f.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var deadline = time.Now().Add(f.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	if err = conn.conn.SetDeadline(deadline); err != nil {
+		return nil, f.fail(ctx, conn, errors.Wrap(err, "error to set deadline"))
+	}
+
+	if err = writeRESP(conn.writer, args...); err != nil {
+		return nil, f.fail(ctx, conn, err)
+	}
+
+	reply, err := readRESP(conn.reader)
+	if err != nil {
+		return nil, f.fail(ctx, conn, err)
+	}
+
+	f.release(conn)
+
+	if replyErr, ok := reply.(respError); ok {
+		return nil, replyErr
+	}
+
+	return reply, nil
+}
+
+// conn takes an idle connection, or dials a new one unless the server failed recently.
+func (f *RedisFilterCustomersCache) conn(ctx context.Context) (*redisConn, error) {
+	f.mu.Lock()
+
+	if f.now().Before(f.downUntil) {
+		f.mu.Unlock()
+		return nil, errRedisUnavailable
+	}
+
+	if last := len(f.idle) - 1; last >= 0 {
+		var conn = f.idle[last]
+		f.idle = f.idle[:last]
+		f.mu.Unlock()
+
+		return conn, nil
+	}
+
+	f.mu.Unlock()
+
+	var dialer = net.Dialer{Timeout: f.timeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", f.addr)
+	if err != nil {
+		return nil, f.fail(ctx, nil, errors.Wrapf(err, "error to connect to %s", f.addr))
+	}
+
+	return &redisConn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}, nil
+}
+
+// release keeps the connection open for the next commands, unless there are enough idle connections already.
+func (f *RedisFilterCustomersCache) release(conn *redisConn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.idle) < redisMaxIdleConns {
+		f.idle = append(f.idle, conn)
+		return
+	}
+
+	conn.conn.Close() //nolint:errcheck,gosec // nothing else to do
+}
+
+// fail closes the connection, when there's one, and the idle ones, which likely failed too, so the server is only
+// reached again after the retry interval. Failures caused by the context being done, like a client going away,
+// only close the connection. It returns the given error.
+func (f *RedisFilterCustomersCache) fail(ctx context.Context, conn *redisConn, err error) error {
+	if conn != nil {
+		conn.conn.Close() //nolint:errcheck,gosec // it's failed already
+	}
+
+	if ctx.Err() != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, idle := range f.idle {
+		idle.conn.Close() //nolint:errcheck,gosec // it's likely failed already
+	}
+
+	f.idle = nil
+	f.downUntil = f.now().Add(redisRetryInterval)
+
+	return err
+}
@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+// fakeRedis is an in-process server speaking the Redis protocol, answering the GET, SET and PING commands.
+type fakeRedis struct {
+	listener net.Listener
+	now      func() time.Time
+
+	mu       sync.Mutex
+	values   map[string]fakeRedisValue
+	conns    []net.Conn
+	accepted int
+}
+
+type fakeRedisValue struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// startFakeRedis listens on the address, or on a random port when it's empty, until the test ends.
+func startFakeRedis(t *testing.T, addr string, now func() time.Time) *fakeRedis {
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	var server = &fakeRedis{listener: listener, now: now, values: make(map[string]fakeRedisValue)}
+
+	t.Cleanup(server.Close)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			server.mu.Lock()
+			server.conns = append(server.conns, conn)
+			server.accepted++
+			server.mu.Unlock()
+
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (s *fakeRedis) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) Accepted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.accepted
+}
+
+func (s *fakeRedis) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys = make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Close stops listening and drops every connection, like a server going down.
+func (s *fakeRedis) Close() {
+	s.listener.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	var (
+		reader = bufio.NewReader(conn)
+		writer = bufio.NewWriter(conn)
+	)
+
+	for {
+		command, err := readRESP(reader)
+		if err != nil {
+			return
+		}
+
+		writer.WriteString(s.reply(command.([]any)))
+		writer.Flush()
+	}
+}
+
+func (s *fakeRedis) reply(command []any) string {
+	var args = make([]string, 0, len(command))
+	for _, arg := range command {
+		args = append(args, string(arg.([]byte)))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.EqualFold(args[0], "PING"):
+		return "+PONG\r\n"
+
+	case strings.EqualFold(args[0], "GET") && len(args) == 2:
+		value, ok := s.values[args[1]]
+		if !ok || (!value.expiresAt.IsZero() && !s.now().Before(value.expiresAt)) {
+			return "$-1\r\n"
+		}
+
+		return "$" + strconv.Itoa(len(value.value)) + "\r\n" + string(value.value) + "\r\n"
+
+	case strings.EqualFold(args[0], "SET") && len(args) == 3:
+		s.values[args[1]] = fakeRedisValue{value: []byte(args[2])}
+		return "+OK\r\n"
+
+	case strings.EqualFold(args[0], "SET") && len(args) == 5 && strings.EqualFold(args[3], "PX"):
+		milliseconds, _ := strconv.Atoi(args[4])
+		s.values[args[1]] = fakeRedisValue{
+			value:     []byte(args[2]),
+			expiresAt: s.now().Add(time.Duration(milliseconds) * time.Millisecond),
+		}
+		return "+OK\r\n"
+
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func TestRedisFilterCustomersCache(t *testing.T) {
+	t.Parallel()
+
+	var ctx = context.Background()
+
+	newCache := func(addr string, ttl time.Duration, now *clock) *RedisFilterCustomersCache {
+		c := NewRedisFilterCustomersCache(logger.NewEmptyLogger(), addr, "party-invite", ttl, 100*time.Millisecond)
+		c.now = now.Now
+
+		return c
+	}
+
+	t.Run("should share the prefixed responses until their ttl elapses", func(t *testing.T) {
+		var (
+			now      = &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+			server   = startFakeRedis(t, "", now.Now)
+			replica1 = newCache(server.Addr(), time.Minute, now)
+			replica2 = newCache(server.Addr(), time.Minute, now)
+		)
+
+		response, err := replica1.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Nil(t, response)
+
+		assert.NoError(t, replica1.Save(ctx, "k1", []byte("response")))
+		assert.Equal(t, []string{"party-invite:k1"}, server.Keys())
+
+		for i := 0; i < 3; i++ {
+			response, err = replica2.Get(ctx, "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("response"), response)
+		}
+
+		// the connections are reused among commands
+		assert.Equal(t, 2, server.Accepted())
+
+		now.now = now.now.Add(time.Minute)
+
+		response, err = replica2.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Nil(t, response)
+	})
+
+	t.Run("should answer misses while the server is down", func(t *testing.T) {
+		var (
+			now    = &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+			server = startFakeRedis(t, "", now.Now)
+			c      = newCache(server.Addr(), 0, now)
+		)
+
+		assert.NoError(t, c.Save(ctx, "k1", []byte("response")))
+		server.Close()
+
+		response, err := c.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Nil(t, response)
+		assert.ErrorContains(t, c.Save(ctx, "k1", []byte("response")), "error to store cached response: redis unavailable")
+
+		// the server is back, but it's only reached once the retry interval elapses
+		var restarted = startFakeRedis(t, server.Addr(), now.Now)
+
+		response, err = c.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Nil(t, response)
+		assert.Equal(t, 0, restarted.Accepted())
+
+		now.now = now.now.Add(redisRetryInterval)
+
+		assert.NoError(t, c.Save(ctx, "k1", []byte("response")))
+
+		response, err = c.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("response"), response)
+		assert.Equal(t, 1, restarted.Accepted())
+	})
+
+	t.Run("should answer misses when the server doesn't reply in time", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		defer listener.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				defer conn.Close()
+				time.Sleep(time.Second)
+			}
+		}()
+
+		var (
+			now   = &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+			c     = newCache(listener.Addr().String(), 0, now)
+			start = time.Now()
+		)
+
+		response, err := c.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Nil(t, response)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("should return error replies without taking the server down", func(t *testing.T) {
+		var (
+			now    = &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+			server = startFakeRedis(t, "", now.Now)
+			c      = newCache(server.Addr(), 0, now)
+		)
+
+		_, err := c.do(ctx, []byte("DEL"), []byte("k1"))
+		assert.EqualError(t, err, "ERR unknown command 'DEL'")
+
+		reply, err := c.do(ctx, []byte("PING"))
+		assert.NoError(t, err)
+		assert.Equal(t, "PONG", reply)
+		assert.Equal(t, 1, server.Accepted())
+	})
+}
+
+func Test_readRESP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		reply   string
+		want    any
+		wantErr string
+	}{
+		{name: "simple string", reply: "+OK\r\n", want: "OK"},
+		{name: "error", reply: "-ERR wrong type\r\n", want: respError("ERR wrong type")},
+		{name: "integer", reply: ":-42\r\n", want: int64(-42)},
+		{name: "bulk string", reply: "$5\r\na\r\nbc\r\n", want: []byte("a\r\nbc")},
+		{name: "empty bulk string", reply: "$0\r\n\r\n", want: []byte{}},
+		{name: "null bulk string", reply: "$-1\r\n", want: nil},
+		{name: "array", reply: "*3\r\n$3\r\nGET\r\n:1\r\n*-1\r\n", want: []any{[]byte("GET"), int64(1), nil}},
+		{name: "unknown type", reply: "?1\r\n", wantErr: "invalid reply: unknown type '?'"},
+		{name: "invalid integer", reply: ":one\r\n", wantErr: "invalid reply: 'one' is not an integer"},
+		{name: "invalid length", reply: "$-2\r\n", wantErr: "invalid reply: '-2' is not a length"},
+		{name: "missing CR", reply: "+OK\n", wantErr: "invalid reply: line not terminated by CRLF"},
+		{name: "truncated bulk string", reply: "$5\r\nab", wantErr: "error to read bulk string"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readRESP(bufio.NewReader(strings.NewReader(tt.reply)))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
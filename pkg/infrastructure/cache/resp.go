@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// respError is an error reply of a server speaking the Redis protocol, like "ERR unknown command".
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+// writeRESP writes the command as an array of bulk strings, the way clients send commands to Redis.
+func writeRESP(w *bufio.Writer, args ...[]byte) error {
+	w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n") //nolint:errcheck // reported by Flush
+
+	for _, arg := range args {
+		w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n") //nolint:errcheck // reported by Flush
+		w.Write(arg)                                         //nolint:errcheck // reported by Flush
+		w.WriteString("\r\n")                                //nolint:errcheck // reported by Flush
+	}
+
+	return errors.Wrap(w.Flush(), "error to write command")
+}
+
+// readRESP reads a single reply, which is a string for simple strings, a respError for errors, an int64 for
+// integers, a []byte for bulk strings, a []any for arrays, or nil for the null bulk strings and arrays.
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, errors.New("invalid reply: empty line")
+	}
+
+	var payload = line[1:]
+
+	switch line[0] {
+	case '+':
+		return payload, nil
+
+	case '-':
+		return respError(payload), nil
+
+	case ':':
+		value, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid reply: '%s' is not an integer", payload)
+		}
+
+		return value, nil
+
+	case '$':
+		length, err := readRESPLength(payload)
+		if err != nil || length < 0 {
+			return nil, err
+		}
+
+		var bulk = make([]byte, length+len("\r\n"))
+		if _, err = io.ReadFull(r, bulk); err != nil {
+			return nil, errors.Wrap(err, "error to read bulk string")
+		}
+
+		return bulk[:length], nil
+
+	case '*':
+		length, err := readRESPLength(payload)
+		if err != nil || length < 0 {
+			return nil, err
+		}
+
+		var values = make([]any, 0, length)
+
+		for i := 0; i < length; i++ {
+			value, err := readRESP(r)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, value)
+		}
+
+		return values, nil
+
+	default:
+		return nil, errors.Errorf("invalid reply: unknown type '%c'", line[0])
+	}
+}
+
+// readRESPLine reads a line without its CRLF terminator.
+func readRESPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", errors.Wrap(err, "error to read reply")
+	}
+
+	if len(line) < len("\r\n") || line[len(line)-2] != '\r' {
+		return "", errors.Errorf("invalid reply: line not terminated by CRLF")
+	}
+
+	return line[:len(line)-2], nil
+}
+
+// readRESPLength parses the length of a bulk string or array, where -1 stands for null.
+func readRESPLength(payload string) (int, error) {
+	length, err := strconv.Atoi(payload)
+	if err != nil || length < -1 {
+		return 0, errors.Errorf("invalid reply: '%s' is not a length", payload)
+	}
+
+	return length, nil
+}
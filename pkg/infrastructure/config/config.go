@@ -1,6 +1,11 @@
 package config
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 
@@ -12,6 +17,20 @@ type CorrelationIDKey string
 const (
 	dublinLocationConfig = "dublin"
 
+	locationsSeparator          = ";"
+	locationNameSeparator       = ":"
+	locationCoordinateSeparator = ","
+
+	maxDistancePrecision = 6
+
+	defaultDatasetMaxIndexes = 16
+
+	defaultCacheMaxEntries      = 1_000
+	defaultCacheMaxBytes        = 64 << 20 // 64mb
+	defaultCacheTTL             = 10 * time.Minute
+	defaultCacheJanitorInterval = time.Minute
+	defaultCacheRedisTimeout    = 500 * time.Millisecond
+
 	CorrelationIDKeyName CorrelationIDKey = "correlation_id"
 )
 
@@ -20,6 +39,54 @@ type Config struct {
 	HTTPPort       int    `mapstructure:"HTTP_PORT"`
 	BaseLocation   string `mapstructure:"BASE_LOCATION"`
 	LocationNearTo int32  `mapstructure:"LOCATION_NEAR_TO"`
+
+	// DistancePrecision is the number of decimal places of the distances presented on responses.
+	DistancePrecision int32 `mapstructure:"DISTANCE_PRECISION"`
+
+	// Locations registers named locations, formatted as "name:latitude,longitude" and separated by ";".
+	Locations string `mapstructure:"LOCATIONS"`
+
+	// DuplicatePolicy is the default policy applied to customers sharing the same user_id, like "keep_first".
+	DuplicatePolicy string `mapstructure:"DUPLICATE_POLICY"`
+
+	// DistanceAlgorithm is the default algorithm used to calculate distances, like "haversine" or "vincenty".
+	DistanceAlgorithm string `mapstructure:"DISTANCE_ALGORITHM"`
+
+	// FastDistanceThreshold is the number of customers from which distances are calculated with float numbers,
+	// instead of decimals. Zero disables the fast path.
+	FastDistanceThreshold int `mapstructure:"FAST_DISTANCE_THRESHOLD"`
+
+	// FilterWorkers is the number of workers calculating distances concurrently. Zero means one per CPU.
+	FilterWorkers int `mapstructure:"FILTER_WORKERS"`
+
+	// DatasetsDir is the directory where uploaded datasets are stored as JSON files. Empty keeps them in memory.
+	DatasetsDir string `mapstructure:"DATASETS_DIR"`
+
+	// DatasetMaxIndexes bounds how many datasets have their spatial index kept in memory, the least recently queried
+	// ones being rebuilt on their next query.
+	DatasetMaxIndexes int `mapstructure:"DATASET_MAX_INDEXES"`
+
+	// CacheMaxEntries and CacheMaxBytes bound the number of cached responses and their total size, in bytes. The
+	// least recently used responses are evicted to keep the cache within both bounds.
+	CacheMaxEntries int   `mapstructure:"CACHE_MAX_ENTRIES"`
+	CacheMaxBytes   int64 `mapstructure:"CACHE_MAX_BYTES"`
+
+	// CacheTTL is how long a response stays cached, like "10m". Zero keeps it until it's evicted.
+	CacheTTL time.Duration `mapstructure:"CACHE_TTL"`
+
+	// CacheJanitorInterval is how often the expired responses are dropped from the cache, like "1m".
+	CacheJanitorInterval time.Duration `mapstructure:"CACHE_JANITOR_INTERVAL"`
+
+	// CacheDir is the directory where cached responses are stored as files, surviving restarts. Empty keeps them in
+	// memory.
+	CacheDir string `mapstructure:"CACHE_DIR"`
+
+	// CacheRedisAddr is the address of a server speaking the Redis protocol where cached responses are stored, shared
+	// by every instance, like "localhost:6379". Empty disables it.
+	CacheRedisAddr string `mapstructure:"CACHE_REDIS_ADDR"`
+
+	// CacheRedisTimeout is how long a command sent to the Redis server may take, like "500ms".
+	CacheRedisTimeout time.Duration `mapstructure:"CACHE_REDIS_TIMEOUT"`
 }
 
 func (c *Config) IsValid() error {
@@ -29,12 +96,52 @@ func (c *Config) IsValid() error {
 	if c.HTTPPort <= 0 {
 		return errors.Errorf("undefined or invalid HTTP_PORT env var")
 	}
-	if c.BaseLocation != dublinLocationConfig {
+	_, err := c.GetLocations()
+	if err != nil {
+		return errors.Wrap(err, "invalid LOCATIONS env var")
+	}
+	if _, err = c.GetBaseLocation(); err != nil {
 		return errors.Errorf("invalid BASE_LOCATION env var")
 	}
 	if c.LocationNearTo <= 0 {
 		return errors.Errorf("undefined or invalid LOCATION_NEAR_TO env var")
 	}
+	if c.DistancePrecision < 0 || c.DistancePrecision > maxDistancePrecision {
+		return errors.Errorf("invalid DISTANCE_PRECISION env var, it must be between 0 and %d", maxDistancePrecision)
+	}
+	if _, err = c.GetDuplicatePolicy(); err != nil {
+		return errors.Wrap(err, "invalid DUPLICATE_POLICY env var")
+	}
+	if _, err = c.GetDistanceAlgorithm(); err != nil {
+		return errors.Wrap(err, "invalid DISTANCE_ALGORITHM env var")
+	}
+	if c.FastDistanceThreshold < 0 {
+		return errors.Errorf("invalid FAST_DISTANCE_THRESHOLD env var, it must not be negative")
+	}
+	if c.FilterWorkers < 0 {
+		return errors.Errorf("invalid FILTER_WORKERS env var, it must not be negative")
+	}
+	if c.DatasetMaxIndexes <= 0 {
+		return errors.Errorf("invalid DATASET_MAX_INDEXES env var, it must be greater than zero")
+	}
+	if c.CacheMaxEntries <= 0 {
+		return errors.Errorf("invalid CACHE_MAX_ENTRIES env var, it must be greater than zero")
+	}
+	if c.CacheMaxBytes <= 0 {
+		return errors.Errorf("invalid CACHE_MAX_BYTES env var, it must be greater than zero")
+	}
+	if c.CacheTTL < 0 {
+		return errors.Errorf("invalid CACHE_TTL env var, it must not be negative")
+	}
+	if c.CacheJanitorInterval <= 0 {
+		return errors.Errorf("invalid CACHE_JANITOR_INTERVAL env var, it must be greater than zero")
+	}
+	if c.CacheRedisAddr != "" && c.CacheDir != "" {
+		return errors.Errorf("invalid CACHE_REDIS_ADDR env var, it's mutually exclusive with CACHE_DIR")
+	}
+	if c.CacheRedisTimeout <= 0 {
+		return errors.Errorf("invalid CACHE_REDIS_TIMEOUT env var, it must be greater than zero")
+	}
 
 	return nil
 }
@@ -46,6 +153,19 @@ func Load(path string) (*Config, error) {
 
 	viper.AutomaticEnv()
 
+	viper.SetDefault("DISTANCE_PRECISION", domain.DefaultDistancePrecision)
+	viper.SetDefault("DUPLICATE_POLICY", domain.DuplicatePolicyKeepFirst.String())
+	viper.SetDefault("DISTANCE_ALGORITHM", domain.DistanceAlgorithmHaversine.String())
+	viper.SetDefault("DATASETS_DIR", "")
+	viper.SetDefault("DATASET_MAX_INDEXES", defaultDatasetMaxIndexes)
+	viper.SetDefault("CACHE_MAX_ENTRIES", defaultCacheMaxEntries)
+	viper.SetDefault("CACHE_MAX_BYTES", defaultCacheMaxBytes)
+	viper.SetDefault("CACHE_TTL", defaultCacheTTL)
+	viper.SetDefault("CACHE_JANITOR_INTERVAL", defaultCacheJanitorInterval)
+	viper.SetDefault("CACHE_DIR", "")
+	viper.SetDefault("CACHE_REDIS_ADDR", "")
+	viper.SetDefault("CACHE_REDIS_TIMEOUT", defaultCacheRedisTimeout)
+
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, errors.Wrapf(err, "error to read config, path: %s", path)
 	}
@@ -58,12 +178,102 @@ func Load(path string) (*Config, error) {
 	return config, nil
 }
 
-func (c *Config) GetBaseLocation() *domain.Coordinate {
-	switch c.BaseLocation {
-	case dublinLocationConfig:
-		return domain.DublinLocation
+// GetBaseLocation returns the coordinate of the location configured as BASE_LOCATION.
+func (c *Config) GetBaseLocation() (*domain.Coordinate, error) {
+	return c.GetLocation(c.BaseLocation)
+}
+
+// GetDuplicatePolicy returns the configured DUPLICATE_POLICY, keeping the first occurrence when it's not defined.
+func (c *Config) GetDuplicatePolicy() (domain.DuplicatePolicy, error) {
+	if c.DuplicatePolicy == "" {
+		return domain.DuplicatePolicyKeepFirst, nil
+	}
+
+	return domain.NewDuplicatePolicy(strings.ToLower(strings.TrimSpace(c.DuplicatePolicy)))
+}
+
+// GetDistanceAlgorithm returns the configured DISTANCE_ALGORITHM, applying haversine when it's not defined.
+func (c *Config) GetDistanceAlgorithm() (domain.DistanceAlgorithm, error) {
+	if c.DistanceAlgorithm == "" {
+		return domain.DistanceAlgorithmHaversine, nil
+	}
+
+	return domain.NewDistanceAlgorithm(strings.ToLower(strings.TrimSpace(c.DistanceAlgorithm)))
+}
+
+// UseFastDistance reports whether the distances of the given number of customers should be calculated through the
+// float fast path.
+func (c *Config) UseFastDistance(customers int) bool {
+	return c.FastDistanceThreshold > 0 && customers >= c.FastDistanceThreshold
+}
+
+// ResponsesFingerprint identifies the settings the filter customers responses depend on, so instances sharing cached
+// responses only answer with the ones built under the same settings.
+func (c *Config) ResponsesFingerprint() string {
+	var settings = fmt.Sprintf(
+		"base=%q near=%d precision=%d locations=%q duplicates=%q algorithm=%q fast=%d",
+		c.BaseLocation,
+		c.LocationNearTo,
+		c.DistancePrecision,
+		c.Locations,
+		c.DuplicatePolicy,
+		c.DistanceAlgorithm,
+		c.FastDistanceThreshold,
+	)
+
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(settings)))[:16] //nolint:gomnd // short, yet hardly colliding
+}
+
+// GetLocation resolves a registered location by its name.
+func (c *Config) GetLocation(name string) (*domain.Coordinate, error) {
+	locations, err := c.GetLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	return locations.Get(normalizeLocationName(name))
+}
+
+// normalizeLocationName makes location names case and surrounding spaces insensitive, both where they're defined
+// and where they're referenced.
+func normalizeLocationName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
 
-	default:
-		return &domain.Coordinate{}
+// GetLocations returns the registry of all known locations: the built-in Dublin office plus the ones
+// defined on the LOCATIONS env var, which may also override the built-in one.
+func (c *Config) GetLocations() (domain.Locations, error) {
+	var locations = domain.Locations{
+		dublinLocationConfig: domain.DublinLocation,
 	}
+
+	for _, entry := range strings.Split(c.Locations, locationsSeparator) {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		name, coordinate, found := strings.Cut(entry, locationNameSeparator)
+		if !found {
+			return nil, errors.Errorf("location '%s' must be formatted as name:latitude,longitude", entry)
+		}
+
+		name = normalizeLocationName(name)
+		if name == "" {
+			return nil, errors.Errorf("location '%s' has an empty name", entry)
+		}
+
+		latitude, longitude, found := strings.Cut(coordinate, locationCoordinateSeparator)
+		if !found {
+			return nil, errors.Errorf("location '%s' must be formatted as name:latitude,longitude", entry)
+		}
+
+		location, err := domain.NewCoordinate(strings.TrimSpace(latitude), strings.TrimSpace(longitude))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid coordinate of location '%s'", name)
+		}
+
+		locations[name] = location
+	}
+
+	return locations, nil
 }
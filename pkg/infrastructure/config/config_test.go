@@ -1,9 +1,15 @@
 package config
 
 import (
-	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
 )
 
 func TestLoad(t *testing.T) {
@@ -20,16 +26,36 @@ func TestLoad(t *testing.T) {
 	}
 
 	assert.NotNil(t, cfg)
+	assert.Equal(t, int32(3), cfg.DistancePrecision)
+	assert.Equal(t, "keep_first", cfg.DuplicatePolicy)
+	assert.Equal(t, "haversine", cfg.DistanceAlgorithm)
+	assert.Equal(t, 10000, cfg.FastDistanceThreshold)
+	assert.Equal(t, 0, cfg.FilterWorkers)
+	assert.Equal(t, "", cfg.DatasetsDir)
+	assert.Equal(t, 16, cfg.DatasetMaxIndexes)
+	assert.Equal(t, 1000, cfg.CacheMaxEntries)
+	assert.Equal(t, int64(64<<20), cfg.CacheMaxBytes)
+	assert.Equal(t, 10*time.Minute, cfg.CacheTTL)
+	assert.Equal(t, time.Minute, cfg.CacheJanitorInterval)
+	assert.Equal(t, "", cfg.CacheDir)
+	assert.Equal(t, "", cfg.CacheRedisAddr)
+	assert.Equal(t, 500*time.Millisecond, cfg.CacheRedisTimeout)
 }
 
 func TestConfig_IsValid(t *testing.T) {
 	t.Parallel()
 
 	var validConfig = &Config{
-		AppName:        "test",
-		HTTPPort:       1000,
-		BaseLocation:   "dublin",
-		LocationNearTo: 100,
+		AppName:              "test",
+		HTTPPort:             1000,
+		BaseLocation:         "dublin",
+		LocationNearTo:       100,
+		DistancePrecision:    3,
+		DatasetMaxIndexes:    4,
+		CacheMaxEntries:      10,
+		CacheMaxBytes:        1024,
+		CacheJanitorInterval: time.Minute,
+		CacheRedisTimeout:    time.Second,
 	}
 
 	type fields struct {
@@ -87,6 +113,213 @@ func TestConfig_IsValid(t *testing.T) {
 				return assert.ErrorContains(t, err, "invalid BASE_LOCATION env var")
 			},
 		},
+		{
+			name: "should return no errors on a base location registered on LOCATIONS env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.BaseLocation = "cork"
+					c.Locations = "cork:51.897233,-8.470456"
+					return &c
+				}(),
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should return no errors on a base location differing in case and spaces",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.BaseLocation = " Cork "
+					c.Locations = "CORK:51.897233,-8.470456"
+					return &c
+				}(),
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should error on malformed LOCATIONS env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.Locations = "cork=51.897233,-8.470456"
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid LOCATIONS env var")
+			},
+		},
+		{
+			name: "should error on LOCATIONS env var containing an invalid coordinate",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.Locations = "cork:north,-8.470456"
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid LOCATIONS env var")
+			},
+		},
+		{
+			name: "should error on invalid DISTANCE_PRECISION env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.DistancePrecision = 10
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid DISTANCE_PRECISION env var")
+			},
+		},
+		{
+			name: "should error on unknown DUPLICATE_POLICY env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.DuplicatePolicy = "merge"
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid DUPLICATE_POLICY env var")
+			},
+		},
+		{
+			name: "should error on unknown DISTANCE_ALGORITHM env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.DistanceAlgorithm = "manhattan"
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid DISTANCE_ALGORITHM env var")
+			},
+		},
+		{
+			name: "should error on negative FAST_DISTANCE_THRESHOLD env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.FastDistanceThreshold = -1
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid FAST_DISTANCE_THRESHOLD env var")
+			},
+		},
+		{
+			name: "should error on negative FILTER_WORKERS env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.FilterWorkers = -1
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid FILTER_WORKERS env var")
+			},
+		},
+		{
+			name: "should error on missing DATASET_MAX_INDEXES env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.DatasetMaxIndexes = 0
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid DATASET_MAX_INDEXES env var")
+			},
+		},
+		{
+			name: "should error on missing CACHE_MAX_ENTRIES env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.CacheMaxEntries = 0
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid CACHE_MAX_ENTRIES env var")
+			},
+		},
+		{
+			name: "should error on missing CACHE_MAX_BYTES env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.CacheMaxBytes = 0
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid CACHE_MAX_BYTES env var")
+			},
+		},
+		{
+			name: "should error on negative CACHE_TTL env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.CacheTTL = -time.Second
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid CACHE_TTL env var")
+			},
+		},
+		{
+			name: "should error on missing CACHE_JANITOR_INTERVAL env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.CacheJanitorInterval = 0
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid CACHE_JANITOR_INTERVAL env var")
+			},
+		},
+		{
+			name: "should error on both CACHE_REDIS_ADDR and CACHE_DIR env vars",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.CacheRedisAddr = "localhost:6379"
+					c.CacheDir = "/tmp/cache"
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid CACHE_REDIS_ADDR env var")
+			},
+		},
+		{
+			name: "should error on missing CACHE_REDIS_TIMEOUT env var",
+			fields: fields{
+				Config: func() *Config {
+					c := *validConfig
+					c.CacheRedisTimeout = 0
+					return &c
+				}(),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "invalid CACHE_REDIS_TIMEOUT env var")
+			},
+		},
 		{
 			name: "should error on missing LOCATION_NEAR_TO env var",
 			fields: fields{
@@ -112,3 +345,119 @@ func TestConfig_IsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_GetLocation(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Locations: "cork:51.897233,-8.470456; Galway : 53.274203,-9.051389",
+	}
+
+	tests := []struct {
+		name     string
+		location string
+		want     *domain.Coordinate
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "should resolve the built-in dublin location",
+			location: "dublin",
+			want:     domain.DublinLocation,
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "should resolve a location registered on LOCATIONS env var",
+			location: "cork",
+			want: &domain.Coordinate{
+				Latitude:  decimal.RequireFromString("51.897233"),
+				Longitude: decimal.RequireFromString("-8.470456"),
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:     "should resolve a location ignoring case and spaces",
+			location: "GALWAY",
+			want: &domain.Coordinate{
+				Latitude:  decimal.RequireFromString("53.274203"),
+				Longitude: decimal.RequireFromString("-9.051389"),
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:     "should error on unknown location",
+			location: "atlanta",
+			want:     nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "unknown location") && errors.As(err, &domain.ErrInvalidArgument{})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cfg.GetLocation(tt.location)
+
+			tt.wantErr(t, err)
+
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_UseFastDistance(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		threshold int
+		customers int
+		want      bool
+	}{
+		{name: "should not use the fast path when disabled", threshold: 0, customers: 1_000_000, want: false},
+		{name: "should not use the fast path below the threshold", threshold: 1000, customers: 999, want: false},
+		{name: "should use the fast path on the threshold", threshold: 1000, customers: 1000, want: true},
+		{name: "should use the fast path above the threshold", threshold: 1000, customers: 5000, want: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{FastDistanceThreshold: tt.threshold}
+
+			assert.Equal(t, tt.want, c.UseFastDistance(tt.customers))
+		})
+	}
+}
+
+func TestConfig_ResponsesFingerprint(t *testing.T) {
+	t.Parallel()
+
+	var base = Config{
+		AppName:           "party-invite",
+		HTTPPort:          8080,
+		BaseLocation:      "dublin",
+		LocationNearTo:    100,
+		DistancePrecision: 3,
+	}
+
+	var (
+		otherPort      = base
+		otherPrecision = base
+		otherThreshold = base
+		otherLocations = base
+	)
+
+	otherPort.HTTPPort = 8081
+	otherPrecision.DistancePrecision = 5
+	otherThreshold.FastDistanceThreshold = 1000
+	otherLocations.Locations = "cork:51.8985,-8.4756"
+
+	assert.Len(t, base.ResponsesFingerprint(), 16)
+	assert.Equal(t, base.ResponsesFingerprint(), otherPort.ResponsesFingerprint(), "the port doesn't change responses")
+	assert.NotEqual(t, base.ResponsesFingerprint(), otherPrecision.ResponsesFingerprint())
+	assert.NotEqual(t, base.ResponsesFingerprint(), otherThreshold.ResponsesFingerprint())
+	assert.NotEqual(t, base.ResponsesFingerprint(), otherLocations.ResponsesFingerprint())
+}
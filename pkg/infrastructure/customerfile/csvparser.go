@@ -0,0 +1,209 @@
+package customerfile
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+const (
+	csvUserIDColumn    = "user_id"
+	csvNameColumn      = "name"
+	csvLatitudeColumn  = "latitude"
+	csvLongitudeColumn = "longitude"
+)
+
+var csvRequiredColumns = []string{csvUserIDColumn, csvNameColumn, csvLatitudeColumn, csvLongitudeColumn}
+
+type CSVCustomersFileParser struct {
+}
+
+func NewCSVCustomersFileParser() *CSVCustomersFileParser {
+	return &CSVCustomersFileParser{}
+}
+
+// Parse parses a CSV file into a list of customers. The first record must be a header naming the columns, which
+// are mapped by name, so their order doesn't matter and extra columns are ignored.
+// On lenient mode, invalid records are skipped and returned as rejected.
+func (c CSVCustomersFileParser) Parse(
+	ctx context.Context,
+	file io.Reader,
+	mode domain.ParseMode,
+) (domain.Customers, domain.RejectedLines, error) {
+	return collect(ctx, file, mode, c.Stream)
+}
+
+// Stream parses a CSV file record by record like Parse, sending each customer through the channel as soon as it's
+// read, and closing it once done. Only the rejected records are kept.
+func (c CSVCustomersFileParser) Stream(
+	ctx context.Context,
+	file io.Reader,
+	mode domain.ParseMode,
+	customers chan<- domain.Customer,
+) (domain.RejectedLines, error) {
+	defer close(customers)
+
+	var (
+		rejected = newRejections(mode)
+		reader   = csv.NewReader(file)
+	)
+
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, domain.NewErrInvalidArgument("empty file", "error to parse csv header")
+		}
+
+		return nil, domain.NewErrInvalidArgument(err.Error(), "error to parse csv header")
+	}
+
+	columns, err := csvColumnsIndex(header)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "context done while parsing file")
+		default:
+		}
+
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, errors.Wrap(err, "error to read csv file")
+			}
+
+			err = rejected.reject(
+				parseErr.StartLine,
+				strings.Join(record, ","),
+				parseErr.Err.Error(),
+				domain.NewErrInvalidArgument(err.Error(), "error to parse csv file"),
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		var (
+			line, _ = reader.FieldPos(0)
+			content = strings.Join(record, ",")
+		)
+
+		userID, err := csvUserID(record[columns[csvUserIDColumn]])
+		if err != nil {
+			err = rejected.reject(line, content, err.Error(), errors.Wrapf(
+				err,
+				"error to parse line=%d, content='%s'", line, content,
+			))
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		location, err := domain.NewCoordinate(
+			strings.TrimSpace(record[columns[csvLatitudeColumn]]),
+			strings.TrimSpace(record[columns[csvLongitudeColumn]]),
+		)
+		if err != nil {
+			err = rejected.reject(line, content, err.Error(), errors.Wrapf(
+				err,
+				"error to parse customers' location on line=%d", line,
+			))
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		customer := domain.NewCustomer(userID, record[columns[csvNameColumn]], location)
+
+		if err = customer.Validate(); err != nil {
+			err = rejected.reject(line, content, err.Error(), errors.Wrapf(err, "error to parse line=%d", line))
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if err = send(ctx, customers, customer); err != nil {
+			return nil, err
+		}
+	}
+
+	return rejected.lines, nil
+}
+
+func csvUserID(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, domain.NewErrInvalidArgument("is required", "invalid user_id")
+	}
+
+	userID, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, domain.NewErrInvalidArgument(err.Error(), "invalid user_id")
+	}
+
+	return userID, nil
+}
+
+// csvColumnsIndex maps each required column to its position on the header, matching names case-insensitively.
+func csvColumnsIndex(header []string) (map[string]int, error) {
+	var columns = make(map[string]int, len(header))
+
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) // strips a BOM, if any
+
+		if _, ok := columns[name]; ok && isCSVRequiredColumn(name) {
+			return nil, domain.NewErrInvalidArgument(
+				fmt.Sprintf("column '%s' is duplicated", name),
+				"error to parse csv header",
+			)
+		}
+
+		columns[name] = i
+	}
+
+	for _, required := range csvRequiredColumns {
+		if _, ok := columns[required]; !ok {
+			return nil, domain.NewErrInvalidArgument(
+				fmt.Sprintf("missing '%s' column", required),
+				"error to parse csv header",
+			)
+		}
+	}
+
+	return columns, nil
+}
+
+func isCSVRequiredColumn(name string) bool {
+	for _, required := range csvRequiredColumns {
+		if required == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,212 @@
+package customerfile
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+func TestCSVCustomersFileParser_Parse(t *testing.T) {
+	t.Parallel()
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	type args struct {
+		ctx         context.Context
+		fileContent string
+		mode        domain.ParseMode
+	}
+	tests := []struct {
+		name         string
+		args         args
+		want         domain.Customers
+		wantRejected domain.RejectedLines
+		wantErr      assert.ErrorAssertionFunc
+	}{
+		{
+			name: "should parse a valid file with 2 customers",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `user_id,name,latitude,longitude
+27,Enid Gallagher,54.1225,-8.143333
+
+6,"Enright, Theresa",53.1229599,-6.2705202`,
+			},
+			want: []domain.Customer{
+				{
+					ID:   27,
+					Name: "Enid Gallagher",
+					Location: &domain.Coordinate{
+						Latitude:  decimal.RequireFromString("54.1225"),
+						Longitude: decimal.RequireFromString("-8.143333"),
+					},
+				},
+				{
+					ID:   6,
+					Name: "Enright, Theresa",
+					Location: &domain.Coordinate{
+						Latitude:  decimal.RequireFromString("53.1229599"),
+						Longitude: decimal.RequireFromString("-6.2705202"),
+					},
+				},
+			},
+			wantRejected: []domain.RejectedLine{},
+			wantErr:      assert.NoError,
+		},
+		{
+			name: "should map columns by the header names, ignoring order, case and extra columns",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `Longitude, email, Name, LATITUDE, User_ID
+-7.1048927, jack@example.com, Jack Dempsey, 52.2559432, 9`,
+			},
+			want: []domain.Customer{
+				{
+					ID:   9,
+					Name: "Jack Dempsey",
+					Location: &domain.Coordinate{
+						Latitude:  decimal.RequireFromString("52.2559432"),
+						Longitude: decimal.RequireFromString("-7.1048927"),
+					},
+				},
+			},
+			wantRejected: []domain.RejectedLine{},
+			wantErr:      assert.NoError,
+		},
+		{
+			name: "should error on missing required column",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: "user_id,name,latitude\n9,Jack Dempsey,52.2559432",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "missing 'longitude' column")
+			},
+		},
+		{
+			name: "should error on empty file",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: "",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "error to parse csv header: empty file")
+			},
+		},
+		{
+			name: "should error on invalid user_id on 3rd line",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: "user_id,name,latitude,longitude\n27,Enid Gallagher,54.1225,-8.143333\nx,Jack Dempsey,52.2559432,-7.1048927",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "error to parse line=3")
+			},
+		},
+		{
+			name: "should error on invalid latitude",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: "user_id,name,latitude,longitude\n9,Jack Dempsey,invalid number,-7.1048927",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "error to parse customers' location")
+			},
+		},
+		{
+			name: "should error on record with wrong number of fields",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: "user_id,name,latitude,longitude\n9,Jack Dempsey,52.2559432",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "error to parse csv file")
+			},
+		},
+		{
+			name: "should error on too long name",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: "user_id,name,latitude,longitude\n9," + strings.Repeat("a", domain.MaxCustomerNameLength+1) + ",52.2559432,-7.1048927",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "error to parse line=2: invalid name: must have at most 128 characters")
+			},
+		},
+		{
+			name: "should skip invalid records on lenient mode",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `user_id,name,latitude,longitude
+x,Enid Gallagher,54.1225,-8.143333
+9,Jack Dempsey,52.2559432
+6,Theresa Enright,53.1229599,-6.2705202
+7,Jack Enright,north,-6.2705202
+,Nick Enright,53.1229599,-6.2705202
+-1,Rose Enright,53.1229599,-6.2705202`,
+				mode: domain.ParseModeLenient,
+			},
+			want: []domain.Customer{
+				{
+					ID:   6,
+					Name: "Theresa Enright",
+					Location: &domain.Coordinate{
+						Latitude:  decimal.RequireFromString("53.1229599"),
+						Longitude: decimal.RequireFromString("-6.2705202"),
+					},
+				},
+			},
+			wantRejected: []domain.RejectedLine{
+				{Line: 2, Content: "x,Enid Gallagher,54.1225,-8.143333", Reason: `invalid user_id: strconv.Atoi: parsing "x": invalid syntax`},
+				{Line: 3, Content: "9,Jack Dempsey,52.2559432", Reason: "wrong number of fields"},
+				{Line: 5, Content: "7,Jack Enright,north,-6.2705202", Reason: "invalid latitude: can't convert north to decimal"},
+				{Line: 6, Content: ",Nick Enright,53.1229599,-6.2705202", Reason: "invalid user_id: is required"},
+				{Line: 7, Content: "-1,Rose Enright,53.1229599,-6.2705202", Reason: "invalid user_id: must be positive, got -1"},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should error on context done",
+			args: args{
+				ctx:         canceledCtx,
+				fileContent: "user_id,name,latitude,longitude\n9,Jack Dempsey,52.2559432,-7.1048927",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "context done while parsing file")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			reader := strings.NewReader(tt.args.fileContent)
+
+			c := NewCSVCustomersFileParser()
+
+			got, rejected, err := c.Parse(tt.args.ctx, reader, tt.args.mode)
+
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.EqualValues(t, tt.want, got)
+			assert.EqualValues(t, tt.wantRejected, rejected)
+		})
+	}
+}
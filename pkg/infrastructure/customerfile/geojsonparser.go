@@ -0,0 +1,262 @@
+package customerfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+const (
+	geoJSONFeatureCollectionType = "FeatureCollection"
+	geoJSONFeatureType           = "Feature"
+	geoJSONPointType             = "Point"
+)
+
+type rawGeoJSONFeature struct {
+	Type     string `json:"type"`
+	Geometry *struct {
+		Type        string        `json:"type"`
+		Coordinates []json.Number `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		UserID *int   `json:"user_id"`
+		Name   string `json:"name"`
+	} `json:"properties"`
+}
+
+type GeoJSONCustomersFileParser struct {
+}
+
+func NewGeoJSONCustomersFileParser() *GeoJSONCustomersFileParser {
+	return &GeoJSONCustomersFileParser{}
+}
+
+// Parse parses a GeoJSON FeatureCollection into a list of customers. Every feature must be a Point, whose
+// coordinates follow the GeoJSON [longitude, latitude] order, with the user_id and name as properties.
+// On lenient mode, invalid features are skipped and returned as rejected, identified by their position.
+func (g GeoJSONCustomersFileParser) Parse(
+	ctx context.Context,
+	file io.Reader,
+	mode domain.ParseMode,
+) (domain.Customers, domain.RejectedLines, error) {
+	return collect(ctx, file, mode, g.Stream)
+}
+
+// Stream parses a GeoJSON FeatureCollection like Parse, decoding one feature at a time and sending each customer
+// through the channel as soon as it's read, and closing it once done. Only the rejected features are kept.
+func (g GeoJSONCustomersFileParser) Stream(
+	ctx context.Context,
+	file io.Reader,
+	mode domain.ParseMode,
+	customers chan<- domain.Customer,
+) (domain.RejectedLines, error) {
+	defer close(customers)
+
+	var (
+		decoder        = json.NewDecoder(file)
+		rejected       = newRejections(mode)
+		collectionType string
+	)
+
+	if err := expectGeoJSONDelim(decoder, '{'); err != nil {
+		return nil, err
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, domain.NewErrInvalidArgument(err.Error(), "error to parse geojson file")
+		}
+
+		switch key {
+		case "type":
+			if err = decoder.Decode(&collectionType); err != nil {
+				return nil, domain.NewErrInvalidArgument(err.Error(), "error to parse geojson file")
+			}
+
+			// fails fast, as the type usually comes before the features
+			if err = validateGeoJSONCollectionType(collectionType); err != nil {
+				return nil, err
+			}
+
+		case "features":
+			if err = g.streamFeatures(ctx, decoder, rejected, customers); err != nil {
+				return nil, err
+			}
+
+		default:
+			if err = decoder.Decode(&json.RawMessage{}); err != nil {
+				return nil, domain.NewErrInvalidArgument(err.Error(), "error to parse geojson file")
+			}
+		}
+	}
+
+	if err := expectGeoJSONDelim(decoder, '}'); err != nil {
+		return nil, err
+	}
+
+	if err := validateGeoJSONCollectionType(collectionType); err != nil {
+		return nil, err
+	}
+
+	return rejected.lines, nil
+}
+
+// streamFeatures decodes the features array one feature at a time.
+func (g GeoJSONCustomersFileParser) streamFeatures(
+	ctx context.Context,
+	decoder *json.Decoder,
+	rejected *rejections,
+	customers chan<- domain.Customer,
+) error {
+	if err := expectGeoJSONDelim(decoder, '['); err != nil {
+		return err
+	}
+
+	for position := 1; decoder.More(); position++ {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "context done while parsing file")
+		default:
+		}
+
+		var rawFeature json.RawMessage
+
+		if err := decoder.Decode(&rawFeature); err != nil {
+			return domain.NewErrInvalidArgument(err.Error(), "error to parse geojson file")
+		}
+
+		feature, err := decodeGeoJSONFeature(rawFeature)
+		if err != nil {
+			err = rejected.reject(position, string(rawFeature), err.Error(), domain.NewErrInvalidArgument(
+				err.Error(),
+				fmt.Sprintf("error to parse feature=%d", position),
+			))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		location, err := domain.NewCoordinate(
+			feature.Geometry.Coordinates[1].String(),
+			feature.Geometry.Coordinates[0].String(),
+		)
+		if err != nil {
+			err = rejected.reject(position, string(rawFeature), err.Error(), errors.Wrapf(
+				err,
+				"error to parse customers' location on feature=%d", position,
+			))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		userID, err := requiredUserID(feature.Properties.UserID)
+		if err != nil {
+			err = rejected.reject(position, string(rawFeature), err.Error(), errors.Wrapf(
+				err,
+				"error to parse feature=%d", position,
+			))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		customer := domain.NewCustomer(userID, feature.Properties.Name, location)
+
+		if err = customer.Validate(); err != nil {
+			err = rejected.reject(position, string(rawFeature), err.Error(), errors.Wrapf(
+				err,
+				"error to parse feature=%d", position,
+			))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err = send(ctx, customers, customer); err != nil {
+			return err
+		}
+	}
+
+	return expectGeoJSONDelim(decoder, ']')
+}
+
+func expectGeoJSONDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return domain.NewErrInvalidArgument(err.Error(), "error to parse geojson file")
+	}
+
+	if token != delim {
+		return domain.NewErrInvalidArgument(
+			fmt.Sprintf("expected '%s', found '%v'", delim, token),
+			"error to parse geojson file",
+		)
+	}
+
+	return nil
+}
+
+func validateGeoJSONCollectionType(collectionType string) error {
+	if collectionType != geoJSONFeatureCollectionType {
+		return domain.NewErrInvalidArgument(
+			fmt.Sprintf("unexpected type '%s'", collectionType),
+			"geojson file must be a "+geoJSONFeatureCollectionType,
+		)
+	}
+
+	return nil
+}
+
+// decodeGeoJSONFeature decodes and validates a single feature, keeping its coordinates as numbers to not lose precision.
+func decodeGeoJSONFeature(rawFeature json.RawMessage) (*rawGeoJSONFeature, error) {
+	var feature = &rawGeoJSONFeature{}
+
+	decoder := json.NewDecoder(bytes.NewReader(rawFeature))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(feature); err != nil {
+		return nil, errors.Wrap(err, "invalid feature")
+	}
+
+	if err := validateGeoJSONFeature(feature); err != nil {
+		return nil, err
+	}
+
+	return feature, nil
+}
+
+func validateGeoJSONFeature(feature *rawGeoJSONFeature) error {
+	const minPointCoordinates = 2
+
+	switch {
+	case feature.Type != geoJSONFeatureType:
+		return errors.Errorf("unexpected type '%s'", feature.Type)
+
+	case feature.Geometry == nil:
+		return errors.New("missing geometry")
+
+	case feature.Geometry.Type != geoJSONPointType:
+		return errors.Errorf("unexpected geometry type '%s'", feature.Geometry.Type)
+
+	case len(feature.Geometry.Coordinates) < minPointCoordinates:
+		return errors.New("point must have longitude and latitude coordinates")
+	}
+
+	return nil
+}
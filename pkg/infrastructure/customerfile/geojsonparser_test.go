@@ -0,0 +1,240 @@
+package customerfile
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+func TestGeoJSONCustomersFileParser_Parse(t *testing.T) {
+	t.Parallel()
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	type args struct {
+		ctx         context.Context
+		fileContent string
+		mode        domain.ParseMode
+	}
+	tests := []struct {
+		name         string
+		args         args
+		want         domain.Customers
+		wantRejected domain.RejectedLines
+		wantErr      assert.ErrorAssertionFunc
+	}{
+		{
+			name: "should parse a valid feature collection with 2 customers",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333, 54.1225]}, "properties": {"user_id": 27, "name": "Enid Gallagher"}},
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-6.2705202, 53.1229599, 10]}, "properties": {"user_id": 6, "name": "Theresa Enright"}}
+  ]
+}`,
+			},
+			want: []domain.Customer{
+				{
+					ID:   27,
+					Name: "Enid Gallagher",
+					Location: &domain.Coordinate{
+						Latitude:  decimal.RequireFromString("54.1225"),
+						Longitude: decimal.RequireFromString("-8.143333"),
+					},
+				},
+				{
+					ID:   6,
+					Name: "Theresa Enright",
+					Location: &domain.Coordinate{
+						Latitude:  decimal.RequireFromString("53.1229599"),
+						Longitude: decimal.RequireFromString("-6.2705202"),
+					},
+				},
+			},
+			wantRejected: []domain.RejectedLine{},
+			wantErr:      assert.NoError,
+		},
+		{
+			name: "should parse a feature collection whose type comes after the features",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: `{"features": [{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333, 54.1225]}, "properties": {"user_id": 27, "name": "Enid Gallagher"}}], "bbox": [-10, 50, -5, 55], "type": "FeatureCollection"}`,
+			},
+			want: []domain.Customer{
+				{
+					ID:   27,
+					Name: "Enid Gallagher",
+					Location: &domain.Coordinate{
+						Latitude:  decimal.RequireFromString("54.1225"),
+						Longitude: decimal.RequireFromString("-8.143333"),
+					},
+				},
+			},
+			wantRejected: []domain.RejectedLine{},
+			wantErr:      assert.NoError,
+		},
+		{
+			name: "should error on a truncated feature collection",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: `{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333, 54.1225]}, "properties": {"user_id": 27, "name": "Enid Gallagher"}}`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "error to parse geojson file")
+			},
+		},
+		{
+			name: "should error on invalid json",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: `invalid json`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "error to parse geojson file")
+			},
+		},
+		{
+			name: "should error when it's not a feature collection",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: `{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333, 54.1225]}}`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "geojson file must be a FeatureCollection")
+			},
+		},
+		{
+			name: "should error on non point geometry on 2nd feature",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `{"type": "FeatureCollection", "features": [
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333, 54.1225]}, "properties": {"user_id": 27, "name": "Enid Gallagher"}},
+    {"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[-8.1, 54.1], [-8.2, 54.2]]}, "properties": {"user_id": 6, "name": "Theresa Enright"}}
+]}`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "error to parse feature=2")
+			},
+		},
+		{
+			name: "should error on missing user_id on 2nd feature",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `{"type": "FeatureCollection", "features": [
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333, 54.1225]}, "properties": {"user_id": 27, "name": "Enid Gallagher"}},
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333, 54.1225]}, "properties": {"name": "Theresa Enright"}}
+]}`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "error to parse feature=2: invalid user_id: is required")
+			},
+		},
+		{
+			name: "should error on out of range latitude",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `{"type": "FeatureCollection", "features": [
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333, 154.1225]}, "properties": {"user_id": 27, "name": "Enid Gallagher"}}
+]}`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "error to parse customers' location on feature=1: invalid latitude")
+			},
+		},
+		{
+			name: "should skip invalid features on lenient mode",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `{"type": "FeatureCollection", "features": [
+    {"type": "Feature", "geometry": {"type": "Polygon", "coordinates": []}, "properties": {"user_id": 6, "name": "Theresa Enright"}},
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333, 54.1225]}, "properties": {"user_id": 27, "name": "Enid Gallagher"}},
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333]}, "properties": {"user_id": 9, "name": "Jack Dempsey"}}
+]}`,
+				mode: domain.ParseModeLenient,
+			},
+			want: []domain.Customer{
+				{
+					ID:   27,
+					Name: "Enid Gallagher",
+					Location: &domain.Coordinate{
+						Latitude:  decimal.RequireFromString("54.1225"),
+						Longitude: decimal.RequireFromString("-8.143333"),
+					},
+				},
+			},
+			wantRejected: []domain.RejectedLine{
+				{
+					Line:    1,
+					Content: `{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": []}, "properties": {"user_id": 6, "name": "Theresa Enright"}}`,
+					Reason:  "unexpected geometry type 'Polygon'",
+				},
+				{
+					Line:    3,
+					Content: `{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333]}, "properties": {"user_id": 9, "name": "Jack Dempsey"}}`,
+					Reason:  "point must have longitude and latitude coordinates",
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should error on point without latitude",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `{"type": "FeatureCollection", "features": [
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333]}, "properties": {"user_id": 27, "name": "Enid Gallagher"}}
+]}`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "error to parse feature=1")
+			},
+		},
+		{
+			name: "should error on context done",
+			args: args{
+				ctx: canceledCtx,
+				fileContent: `{"type": "FeatureCollection", "features": [
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-8.143333, 54.1225]}, "properties": {"user_id": 27, "name": "Enid Gallagher"}}
+]}`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "context done while parsing file")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			reader := strings.NewReader(tt.args.fileContent)
+
+			g := NewGeoJSONCustomersFileParser()
+
+			got, rejected, err := g.Parse(tt.args.ctx, reader, tt.args.mode)
+
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.EqualValues(t, tt.want, got)
+			assert.EqualValues(t, tt.wantRejected, rejected)
+		})
+	}
+}
@@ -5,14 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/pkg/errors"
 	"io"
 
+	"github.com/pkg/errors"
+
 	"github.com/tonytcb/party-invite/pkg/domain"
 )
 
 type rawCustomer struct {
-	UserID    int    `json:"user_id"`
+	UserID    *int   `json:"user_id"`
 	Name      string `json:"name"`
 	Latitude  string `json:"latitude"`
 	Longitude string `json:"longitude"`
@@ -26,19 +27,39 @@ func NewCustomersFileParser() *CustomersFileParser {
 }
 
 // Parse parses a file into a list of customers, reading each line separately.
-func (c CustomersFileParser) Parse(ctx context.Context, file io.Reader) (domain.Customers, error) {
+// On lenient mode, invalid lines are skipped and returned as rejected.
+func (c CustomersFileParser) Parse(
+	ctx context.Context,
+	file io.Reader,
+	mode domain.ParseMode,
+) (domain.Customers, domain.RejectedLines, error) {
+	return collect(ctx, file, mode, c.Stream)
+}
+
+// Stream parses a file line by line like Parse, sending each customer through the channel as soon as it's read,
+// and closing it once done. Only the rejected lines are kept.
+func (c CustomersFileParser) Stream(
+	ctx context.Context,
+	file io.Reader,
+	mode domain.ParseMode,
+	customers chan<- domain.Customer,
+) (domain.RejectedLines, error) {
+	defer close(customers)
+
 	var (
-		customers   = make([]domain.Customer, 0)
+		rejected    = newRejections(mode)
 		fileScanner = bufio.NewScanner(file)
-		i           = 1
+		i           = 0
 	)
 
 	fileScanner.Split(bufio.ScanLines)
 
 	for fileScanner.Scan() {
+		i++
+
 		select {
 		case <-ctx.Done():
-			return nil, errors.New("context done while parsing file")
+			return nil, errors.Wrap(ctx.Err(), "context done while parsing file")
 		default:
 		}
 
@@ -48,24 +69,72 @@ func (c CustomersFileParser) Parse(ctx context.Context, file io.Reader) (domain.
 			continue // empty line
 		}
 
-		var line = &rawCustomer{}
+		// a value rather than a pointer, so a null line leaves it empty instead of nil
+		var line rawCustomer
 
 		if err := json.Unmarshal(lineContents, &line); err != nil {
-			return nil, domain.NewErrInvalidArgument(
+			err = rejected.reject(i, string(lineContents), "invalid json: "+err.Error(), domain.NewErrInvalidArgument(
 				err.Error(),
 				fmt.Sprintf("error to parse line=%d, content='%s'", i, string(lineContents)),
-			)
+			))
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		userID, err := requiredUserID(line.UserID)
+		if err != nil {
+			err = rejected.reject(i, string(lineContents), err.Error(), errors.Wrapf(err, "error to parse line=%d", i))
+			if err != nil {
+				return nil, err
+			}
+
+			continue
 		}
 
 		location, err := domain.NewCoordinate(line.Latitude, line.Longitude)
 		if err != nil {
-			return nil, errors.Wrap(err, "error to parse customers' location")
+			err = rejected.reject(i, string(lineContents), err.Error(), errors.Wrapf(
+				err,
+				"error to parse customers' location on line=%d", i,
+			))
+			if err != nil {
+				return nil, err
+			}
+
+			continue
 		}
 
-		customers = append(customers, domain.NewCustomer(line.UserID, line.Name, location))
+		customer := domain.NewCustomer(userID, line.Name, location)
 
-		i++
+		if err = customer.Validate(); err != nil {
+			err = rejected.reject(i, string(lineContents), err.Error(), errors.Wrapf(err, "error to parse line=%d", i))
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if err = send(ctx, customers, customer); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := fileScanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "error to read file")
+	}
+
+	return rejected.lines, nil
+}
+
+// requiredUserID tells apart a missing user_id from a zero one, which is left to the customer validation.
+func requiredUserID(userID *int) (int, error) {
+	if userID == nil {
+		return 0, domain.NewErrInvalidArgument("is required", "invalid user_id")
 	}
 
-	return customers, nil
+	return *userID, nil
 }
@@ -2,11 +2,13 @@ package customerfile
 
 import (
 	"context"
-	"github.com/tonytcb/party-invite/pkg/domain"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"io"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestCustomersFileParser_Parse(t *testing.T) {
@@ -18,12 +20,14 @@ func TestCustomersFileParser_Parse(t *testing.T) {
 	type args struct {
 		ctx         context.Context
 		fileContent string
+		mode        domain.ParseMode
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    domain.Customers
-		wantErr assert.ErrorAssertionFunc
+		name         string
+		args         args
+		want         domain.Customers
+		wantRejected domain.RejectedLines
+		wantErr      assert.ErrorAssertionFunc
 	}{
 		{
 			name: "should parse a valid file with 3 customers",
@@ -60,7 +64,8 @@ func TestCustomersFileParser_Parse(t *testing.T) {
 					},
 				},
 			},
-			wantErr: assert.NoError,
+			wantRejected: []domain.RejectedLine{},
+			wantErr:      assert.NoError,
 		},
 
 		{
@@ -86,6 +91,97 @@ func TestCustomersFileParser_Parse(t *testing.T) {
 				return assert.ErrorContains(t, err, "error to parse line=1")
 			},
 		},
+		{
+			name: "should error on zero user_id on 2nd line",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `{"latitude": "54.1225", "user_id": 27, "name": "Enid Gallagher", "longitude": "-8.143333"}
+{"latitude": "52.2559432", "user_id": 0, "name": "Jack Dempsey", "longitude": "-7.1048927"}`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "error to parse line=2: invalid user_id: must be positive, got 0")
+			},
+		},
+		{
+			name: "should error on missing name",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: `{"latitude": "52.2559432", "user_id": 9, "longitude": "-7.1048927"}`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "error to parse line=1: invalid name: is required")
+			},
+		},
+		{
+			name: "should error on null line",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: `null`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "error to parse line=1: invalid user_id: is required")
+			},
+		},
+		{
+			name: "should error on missing latitude",
+			args: args{
+				ctx:         context.Background(),
+				fileContent: `{"user_id": 9, "name": "Jack Dempsey", "longitude": "-7.1048927"}`,
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return isInvalidArgument(t, err, "error to parse customers' location on line=1: invalid latitude: is required")
+			},
+		},
+		{
+			name: "should skip invalid lines on lenient mode",
+			args: args{
+				ctx: context.Background(),
+				fileContent: `invalid json line
+
+{"latitude": "54.1225", "user_id": 27, "name": "Enid Gallagher", "longitude": "-8.143333"}
+{"latitude": "invalid number", "user_id": 9, "name": "Jack Dempsey", "longitude": "-7.1048927"}
+{"latitude": "52.2559432", "name": "Jack Dempsey", "longitude": "-7.1048927"}
+{"latitude": "52.2559432", "user_id": 10, "name": "   ", "longitude": "-7.1048927"}`,
+				mode: domain.ParseModeLenient,
+			},
+			want: []domain.Customer{
+				{
+					ID:   27,
+					Name: "Enid Gallagher",
+					Location: &domain.Coordinate{
+						Latitude:  decimal.RequireFromString("54.1225"),
+						Longitude: decimal.RequireFromString("-8.143333"),
+					},
+				},
+			},
+			wantRejected: []domain.RejectedLine{
+				{
+					Line:    1,
+					Content: "invalid json line",
+					Reason:  "invalid json: invalid character 'i' looking for beginning of value",
+				},
+				{
+					Line:    4,
+					Content: `{"latitude": "invalid number", "user_id": 9, "name": "Jack Dempsey", "longitude": "-7.1048927"}`,
+					Reason:  "invalid latitude: can't convert invalid number to decimal: exponent is not numeric",
+				},
+				{
+					Line:    5,
+					Content: `{"latitude": "52.2559432", "name": "Jack Dempsey", "longitude": "-7.1048927"}`,
+					Reason:  "invalid user_id: is required",
+				},
+				{
+					Line:    6,
+					Content: `{"latitude": "52.2559432", "user_id": 10, "name": "   ", "longitude": "-7.1048927"}`,
+					Reason:  "invalid name: is required",
+				},
+			},
+			wantErr: assert.NoError,
+		},
 		{
 			name: "should error on context done",
 			args: args{
@@ -107,7 +203,7 @@ func TestCustomersFileParser_Parse(t *testing.T) {
 
 			c := CustomersFileParser{}
 
-			got, err := c.Parse(tt.args.ctx, reader)
+			got, rejected, err := c.Parse(tt.args.ctx, reader, tt.args.mode)
 
 			tt.wantErr(t, err)
 			if err != nil {
@@ -115,6 +211,52 @@ func TestCustomersFileParser_Parse(t *testing.T) {
 			}
 
 			assert.EqualValues(t, tt.want, got)
+			assert.EqualValues(t, tt.wantRejected, rejected)
 		})
 	}
 }
+
+func TestCustomersFileParser_Stream(t *testing.T) {
+	t.Parallel()
+
+	var (
+		reader, writer = io.Pipe()
+		customers      = make(chan domain.Customer)
+		parsed         = make(chan error, 1)
+	)
+
+	go func() {
+		_, err := NewCustomersFileParser().Stream(context.Background(), reader, domain.ParseModeStrict, customers)
+		parsed <- err
+	}()
+
+	go func() {
+		_, _ = writer.Write([]byte(`{"latitude": "54.1225", "user_id": 27, "name": "Enid Gallagher", "longitude": "-8.143333"}` + "\n"))
+	}()
+
+	// the first customer arrives while the rest of the file is still being written
+	select {
+	case customer := <-customers:
+		assert.Equal(t, 27, customer.ID)
+	case <-time.After(time.Second):
+		t.Fatal("customer not streamed before the end of the file")
+	}
+
+	go func() {
+		_, _ = writer.Write([]byte(`{"latitude": "52.2559432", "user_id": 9, "name": "Jack Dempsey", "longitude": "-7.1048927"}`))
+		_ = writer.Close()
+	}()
+
+	customer := <-customers
+	assert.Equal(t, 9, customer.ID)
+
+	_, open := <-customers
+	assert.False(t, open, "channel should be closed once the file ends")
+	assert.NoError(t, <-parsed)
+}
+
+func isInvalidArgument(t assert.TestingT, err error, message string) bool {
+	var invalidArgumentErr *domain.ErrInvalidArgument
+
+	return assert.ErrorContains(t, err, message) && assert.ErrorAs(t, err, &invalidArgumentErr)
+}
@@ -0,0 +1,25 @@
+package customerfile
+
+import "github.com/tonytcb/party-invite/pkg/domain"
+
+// rejections decides what happens with invalid lines according to the parse mode: on strict mode the parsing is
+// aborted, while on lenient mode the line is recorded as rejected and the parsing goes on.
+type rejections struct {
+	mode  domain.ParseMode
+	lines domain.RejectedLines
+}
+
+func newRejections(mode domain.ParseMode) *rejections {
+	return &rejections{mode: mode, lines: make([]domain.RejectedLine, 0)}
+}
+
+// reject returns err on strict mode. On lenient mode it records the line with the given reason and returns nil.
+func (r *rejections) reject(line int, content string, reason string, err error) error {
+	if r.mode != domain.ParseModeLenient {
+		return err
+	}
+
+	r.lines = append(r.lines, domain.RejectedLine{Line: line, Content: content, Reason: reason})
+
+	return nil
+}
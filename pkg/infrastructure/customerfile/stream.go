@@ -0,0 +1,55 @@
+package customerfile
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+// streamFunc parses a file sending each customer through the channel, which is closed once it returns.
+type streamFunc func(context.Context, io.Reader, domain.ParseMode, chan<- domain.Customer) (domain.RejectedLines, error)
+
+// collect runs a streaming parse gathering all the customers into a list, for callers that need them at once.
+func collect(
+	ctx context.Context,
+	file io.Reader,
+	mode domain.ParseMode,
+	stream streamFunc,
+) (domain.Customers, domain.RejectedLines, error) {
+	var (
+		customers   = make([]domain.Customer, 0)
+		customersCh = make(chan domain.Customer)
+		done        = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+
+		for customer := range customersCh {
+			customers = append(customers, customer)
+		}
+	}()
+
+	rejected, err := stream(ctx, file, mode, customersCh)
+
+	<-done
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return customers, rejected, nil
+}
+
+// send delivers the customer unless the context is done first, so a parser never outlives an abandoned request.
+func send(ctx context.Context, customers chan<- domain.Customer, customer domain.Customer) error {
+	select {
+	case customers <- customer:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "context done while parsing file")
+	}
+}
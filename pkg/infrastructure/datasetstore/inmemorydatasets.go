@@ -0,0 +1,54 @@
+package datasetstore
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+type InMemoryDatasetRepository struct {
+	mu       sync.RWMutex
+	datasets map[string]domain.Dataset
+}
+
+func NewInMemoryDatasetRepository() *InMemoryDatasetRepository {
+	return &InMemoryDatasetRepository{
+		datasets: make(map[string]domain.Dataset),
+	}
+}
+
+func (r *InMemoryDatasetRepository) Save(_ context.Context, dataset *domain.Dataset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.datasets[dataset.ID] = *dataset
+
+	return nil
+}
+
+// Get returns a copy of the stored dataset, whose customers must not be modified.
+func (r *InMemoryDatasetRepository) Get(_ context.Context, id string) (*domain.Dataset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	dataset, ok := r.datasets[id]
+	if !ok {
+		return nil, domain.NewErrNotFound(datasetResource, id)
+	}
+
+	return &dataset, nil
+}
+
+func (r *InMemoryDatasetRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.datasets[id]; !ok {
+		return domain.NewErrNotFound(datasetResource, id)
+	}
+
+	delete(r.datasets, id)
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package datasetstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/usecase"
+)
+
+func TestInMemoryDatasetRepository(t *testing.T) {
+	t.Parallel()
+
+	testDatasetRepository(t, NewInMemoryDatasetRepository())
+}
+
+// testDatasetRepository asserts the behaviour every dataset repository must have.
+func testDatasetRepository(t *testing.T, repository usecase.DatasetRepository) {
+	t.Helper()
+
+	var (
+		ctx     = context.Background()
+		dataset = &domain.Dataset{
+			ID:        "8c7f2d4e-0b1a-4e5f-9a3b-1d2c3e4f5a6b",
+			FileName:  "customers.txt",
+			CreatedAt: time.Date(2023, 10, 1, 12, 30, 0, 0, time.UTC),
+			Customers: domain.Customers{
+				domain.NewCustomer(1, "Alice Cahill", domain.DublinLocation),
+				domain.NewCustomer(2, "Ian McArdle", &domain.Coordinate{Latitude: decimalOf(t, "51.92893"), Longitude: decimalOf(t, "-10.27699")}),
+			},
+			Rejected: domain.RejectedLines{{Line: 3, Content: "{", Reason: "invalid json"}},
+		}
+	)
+
+	_, err := repository.Get(ctx, dataset.ID)
+	assertNotFound(t, err, dataset.ID)
+
+	assert.NoError(t, repository.Save(ctx, dataset))
+
+	got, err := repository.Get(ctx, dataset.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, dataset, got)
+
+	assert.NoError(t, repository.Delete(ctx, dataset.ID))
+
+	_, err = repository.Get(ctx, dataset.ID)
+	assertNotFound(t, err, dataset.ID)
+
+	assertNotFound(t, repository.Delete(ctx, dataset.ID), dataset.ID)
+}
+
+func assertNotFound(t *testing.T, err error, id string) {
+	t.Helper()
+
+	var notFoundErr *domain.ErrNotFound
+	if assert.ErrorAs(t, err, &notFoundErr) {
+		assert.Equal(t, id, notFoundErr.ID)
+	}
+}
@@ -0,0 +1,197 @@
+package datasetstore
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+const (
+	datasetResource = "dataset"
+
+	datasetFileExtension = ".json"
+	datasetDirPerm       = 0o750
+)
+
+// JSONFileDatasetRepository stores every dataset as a JSON file named by its ID on the given directory, so they
+// survive restarts.
+type JSONFileDatasetRepository struct {
+	dir string
+}
+
+// NewJSONFileDatasetRepository stores the datasets on the directory, creating it when it doesn't exist.
+func NewJSONFileDatasetRepository(dir string) (*JSONFileDatasetRepository, error) {
+	if err := os.MkdirAll(dir, datasetDirPerm); err != nil {
+		return nil, errors.Wrapf(err, "error to create datasets directory, path: %s", dir)
+	}
+
+	return &JSONFileDatasetRepository{dir: dir}, nil
+}
+
+type datasetFile struct {
+	ID        string                `json:"id"`
+	FileName  string                `json:"file_name"`
+	CreatedAt time.Time             `json:"created_at"`
+	Customers []customerRecord      `json:"customers"`
+	Rejected  *[]rejectedLineRecord `json:"rejected,omitempty"`
+}
+
+type customerRecord struct {
+	ID        int              `json:"user_id"`
+	Name      string           `json:"name"`
+	Latitude  *decimal.Decimal `json:"latitude,omitempty"`
+	Longitude *decimal.Decimal `json:"longitude,omitempty"`
+}
+
+type rejectedLineRecord struct {
+	Line    int    `json:"line"`
+	Content string `json:"content"`
+	Reason  string `json:"reason"`
+}
+
+// Save writes the dataset to a temporary file renamed over the final one, so a dataset is never read half-written.
+func (r *JSONFileDatasetRepository) Save(_ context.Context, dataset *domain.Dataset) error {
+	path, ok := r.path(dataset.ID)
+	if !ok {
+		return domain.NewErrInvalidArgument("must be a valid file name", "invalid dataset id")
+	}
+
+	content, err := json.Marshal(toDatasetFile(dataset))
+	if err != nil {
+		return errors.Wrap(err, "error to encode dataset")
+	}
+
+	tmp, err := os.CreateTemp(r.dir, "."+dataset.ID+"-*")
+	if err != nil {
+		return errors.Wrap(err, "error to create dataset file")
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // already renamed on success
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close() //nolint:errcheck,gosec // the write error is the relevant one
+
+		return errors.Wrap(err, "error to write dataset file")
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "error to write dataset file")
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "error to write dataset file")
+	}
+
+	return nil
+}
+
+func (r *JSONFileDatasetRepository) Get(_ context.Context, id string) (*domain.Dataset, error) {
+	path, ok := r.path(id)
+	if !ok {
+		return nil, domain.NewErrNotFound(datasetResource, id)
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, domain.NewErrNotFound(datasetResource, id)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error to read dataset file")
+	}
+
+	var file datasetFile
+	if err = json.Unmarshal(content, &file); err != nil {
+		return nil, errors.Wrapf(err, "error to decode dataset file, path: %s", path)
+	}
+
+	return file.toDataset(), nil
+}
+
+func (r *JSONFileDatasetRepository) Delete(_ context.Context, id string) error {
+	path, ok := r.path(id)
+	if !ok {
+		return domain.NewErrNotFound(datasetResource, id)
+	}
+
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.NewErrNotFound(datasetResource, id)
+	}
+	if err != nil {
+		return errors.Wrap(err, "error to delete dataset file")
+	}
+
+	return nil
+}
+
+// path returns the file of the dataset, unless the ID isn't a plain file name, which could reach other files.
+func (r *JSONFileDatasetRepository) path(id string) (string, bool) {
+	if id == "" || strings.HasPrefix(id, ".") || strings.ContainsAny(id, `/\`) {
+		return "", false
+	}
+
+	return filepath.Join(r.dir, id+datasetFileExtension), true
+}
+
+func toDatasetFile(dataset *domain.Dataset) datasetFile {
+	var file = datasetFile{
+		ID:        dataset.ID,
+		FileName:  dataset.FileName,
+		CreatedAt: dataset.CreatedAt,
+		Customers: make([]customerRecord, 0, len(dataset.Customers)),
+	}
+
+	for _, customer := range dataset.Customers {
+		record := customerRecord{ID: customer.ID, Name: customer.Name}
+		if customer.Location != nil {
+			record.Latitude, record.Longitude = &customer.Location.Latitude, &customer.Location.Longitude
+		}
+
+		file.Customers = append(file.Customers, record)
+	}
+
+	if dataset.Rejected != nil {
+		rejected := make([]rejectedLineRecord, 0, len(dataset.Rejected))
+		for _, line := range dataset.Rejected {
+			rejected = append(rejected, rejectedLineRecord{Line: line.Line, Content: line.Content, Reason: line.Reason})
+		}
+
+		file.Rejected = &rejected
+	}
+
+	return file
+}
+
+func (f *datasetFile) toDataset() *domain.Dataset {
+	var dataset = &domain.Dataset{
+		ID:        f.ID,
+		FileName:  f.FileName,
+		CreatedAt: f.CreatedAt,
+		Customers: make(domain.Customers, 0, len(f.Customers)),
+	}
+
+	for _, record := range f.Customers {
+		var location *domain.Coordinate
+		if record.Latitude != nil && record.Longitude != nil {
+			location = &domain.Coordinate{Latitude: *record.Latitude, Longitude: *record.Longitude}
+		}
+
+		dataset.Customers = append(dataset.Customers, domain.Customer{ID: record.ID, Name: record.Name, Location: location})
+	}
+
+	if f.Rejected != nil {
+		dataset.Rejected = make(domain.RejectedLines, 0, len(*f.Rejected))
+		for _, line := range *f.Rejected {
+			dataset.Rejected = append(dataset.Rejected, domain.RejectedLine{Line: line.Line, Content: line.Content, Reason: line.Reason})
+		}
+	}
+
+	return dataset
+}
@@ -0,0 +1,75 @@
+package datasetstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+func TestJSONFileDatasetRepository(t *testing.T) {
+	t.Parallel()
+
+	repository, err := NewJSONFileDatasetRepository(filepath.Join(t.TempDir(), "datasets"))
+	if err != nil {
+		t.Fatalf("failed to build repository: %v", err)
+	}
+
+	testDatasetRepository(t, repository)
+}
+
+func TestJSONFileDatasetRepository_persistence(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx     = context.Background()
+		dir     = t.TempDir()
+		dataset = &domain.Dataset{
+			ID:        "customers",
+			FileName:  "customers.csv",
+			Customers: domain.Customers{domain.NewCustomer(1, "Alice Cahill", domain.DublinLocation)},
+		}
+	)
+
+	t.Run("should read the datasets saved by another instance", func(t *testing.T) {
+		first, err := NewJSONFileDatasetRepository(dir)
+		assert.NoError(t, err)
+		assert.NoError(t, first.Save(ctx, dataset))
+
+		second, err := NewJSONFileDatasetRepository(dir)
+		assert.NoError(t, err)
+
+		got, err := second.Get(ctx, dataset.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, dataset.Customers, got.Customers)
+		assert.Nil(t, got.Rejected)
+	})
+
+	t.Run("should not reach files outside the directory", func(t *testing.T) {
+		repository, err := NewJSONFileDatasetRepository(filepath.Join(dir, "nested"))
+		assert.NoError(t, err)
+
+		_, err = repository.Get(ctx, "../customers")
+		assertNotFound(t, err, "../customers")
+
+		assertNotFound(t, repository.Delete(ctx, "../customers"), "../customers")
+
+		err = repository.Save(ctx, &domain.Dataset{ID: "../customers"})
+		assert.ErrorContains(t, err, "invalid dataset id")
+	})
+}
+
+func decimalOf(t *testing.T, value string) decimal.Decimal {
+	t.Helper()
+
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		t.Fatalf("failed to parse decimal %s", value)
+	}
+
+	return d
+}
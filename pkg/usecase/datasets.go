@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+// DatasetRepository stores the datasets, returning domain.ErrNotFound for unknown IDs.
+type DatasetRepository interface {
+	DatasetReader
+	Save(ctx context.Context, dataset *domain.Dataset) error
+	Delete(ctx context.Context, id string) error
+}
+
+// DatasetIndexes keeps the indexes built over the datasets, like QueryDataset.
+type DatasetIndexes interface {
+	Forget(datasetID string)
+}
+
+// Datasets manages the customers lists uploaded once to be queried many times. Datasets are never replaced, as each
+// one is created under a new ID, while their indexes are forgotten once they're deleted.
+type Datasets struct {
+	log        logger.Logger
+	repository DatasetRepository
+	indexes    DatasetIndexes
+}
+
+func NewDatasets(log logger.Logger, repository DatasetRepository, indexes DatasetIndexes) *Datasets {
+	return &Datasets{log: log, repository: repository, indexes: indexes}
+}
+
+// Create stores the dataset under a new ID, which is set on it along with its creation time.
+func (d *Datasets) Create(ctx context.Context, dataset *domain.Dataset) error {
+	dataset.ID = uuid.NewString()
+	dataset.CreatedAt = time.Now().UTC()
+
+	if err := d.repository.Save(ctx, dataset); err != nil {
+		return errors.Wrap(err, "error to save dataset")
+	}
+
+	d.log.FromContext(ctx).Infof("Dataset created, dataset-id=%s customers=%d", dataset.ID, len(dataset.Customers))
+
+	return nil
+}
+
+func (d *Datasets) Get(ctx context.Context, id string) (*domain.Dataset, error) {
+	dataset, err := d.repository.Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "error to read dataset")
+	}
+
+	return dataset, nil
+}
+
+func (d *Datasets) Delete(ctx context.Context, id string) error {
+	if err := d.repository.Delete(ctx, id); err != nil {
+		return errors.Wrap(err, "error to delete dataset")
+	}
+
+	d.indexes.Forget(id)
+
+	d.log.FromContext(ctx).Infof("Dataset deleted, dataset-id=%s", id)
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+// datasetRepositoryStub keeps the datasets on a map, like the in memory repository.
+type datasetRepositoryStub map[string]*domain.Dataset
+
+func (s datasetRepositoryStub) Get(_ context.Context, id string) (*domain.Dataset, error) {
+	dataset, ok := s[id]
+	if !ok {
+		return nil, domain.NewErrNotFound("dataset", id)
+	}
+
+	return dataset, nil
+}
+
+func (s datasetRepositoryStub) Save(_ context.Context, dataset *domain.Dataset) error {
+	s[dataset.ID] = dataset
+
+	return nil
+}
+
+func (s datasetRepositoryStub) Delete(_ context.Context, id string) error {
+	if _, ok := s[id]; !ok {
+		return domain.NewErrNotFound("dataset", id)
+	}
+
+	delete(s, id)
+
+	return nil
+}
+
+func TestDatasets(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx        = context.Background()
+		repository = datasetRepositoryStub{}
+		query      = NewQueryDataset(logger.NewEmptyLogger(), repository, 16)
+		datasets   = NewDatasets(logger.NewEmptyLogger(), repository, query)
+		dataset    = &domain.Dataset{
+			FileName:  "customers.txt",
+			Customers: domain.Customers{domain.NewCustomer(1, "Alice Cahill", domain.DublinLocation)},
+		}
+	)
+
+	assert.NoError(t, datasets.Create(ctx, dataset))
+	assert.NotEmpty(t, dataset.ID)
+	assert.False(t, dataset.CreatedAt.IsZero())
+
+	got, err := datasets.Get(ctx, dataset.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, dataset, got)
+
+	candidates, _, err := query.Candidates(ctx, dataset.ID, domain.DublinLocation, decimal.Zero, 0, domain.DuplicatePolicyKeepFirst)
+	assert.NoError(t, err)
+	assert.Equal(t, dataset.Customers, candidates)
+
+	assert.NoError(t, datasets.Delete(ctx, dataset.ID))
+
+	_, err = datasets.Get(ctx, dataset.ID)
+	assert.ErrorContains(t, err, "error to read dataset: dataset '"+dataset.ID+"' not found")
+
+	// the index of the deleted dataset is forgotten
+	_, _, err = query.Candidates(ctx, dataset.ID, domain.DublinLocation, decimal.Zero, 0, domain.DuplicatePolicyKeepFirst)
+	assert.ErrorContains(t, err, "error to read dataset: dataset '"+dataset.ID+"' not found")
+
+	var notFoundErr *domain.ErrNotFound
+	assert.ErrorAs(t, datasets.Delete(ctx, dataset.ID), &notFoundErr)
+}
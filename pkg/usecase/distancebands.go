@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+// ByDistanceBands filters the customers streamed through the channel like ByNearLocation, within the outer band from
+// the base location, and returns them bucketed into the given bands by their distance, in the given order within each
+// band. The given bands aren't changed, and every returned band holds a non-nil list of customers.
+func (f *FilterCustomers) ByDistanceBands(
+	ctx context.Context,
+	customers <-chan domain.Customer,
+	baseLocation *domain.Coordinate,
+	bands domain.DistanceBands,
+	orderBy domain.OrderBy,
+	duplicatePolicy domain.DuplicatePolicy,
+	calculator domain.DistanceCalculator,
+) (domain.DistanceBands, []int, error) {
+	if len(bands) == 0 {
+		return nil, nil, domain.NewErrInvalidArgument("must have at least one band", "invalid distance bands")
+	}
+
+	var filter = newRadiusFilter(baseLocation, bands.Outer(), calculator)
+
+	result, duplicatedIDs, err := f.byLocationFilter(ctx, customers, filter, orderBy, duplicatePolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var bucketed = make(domain.DistanceBands, 0, len(bands))
+	for _, band := range bands {
+		bucketed = append(bucketed, domain.DistanceBand{From: band.From, To: band.To, Customers: make(domain.NearCustomers, 0)})
+	}
+
+	for _, customer := range result {
+		// the first band whose upper bound isn't below the distance, as every accepted customer is within the outer one
+		i := sort.Search(len(bucketed), func(i int) bool { return !bucketed[i].To.LessThan(customer.Distance) })
+
+		bucketed[i].Customers = append(bucketed[i].Customers, customer)
+	}
+
+	return bucketed, duplicatedIDs, nil
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+func TestFilterCustomers_ByDistanceBands(t *testing.T) {
+	t.Parallel()
+
+	var (
+		notifier   = &recordingNotifier{}
+		filter     = NewFilterCustomers(logger.NewEmptyLogger(), notifier, 4)
+		calculator = domain.HaversineDistance{}
+		customers  = randomCustomers(t, 22, 300, 0, aroundDublin(1.2, 2))
+	)
+
+	bands, err := domain.ParseDistanceBands("25,50,100")
+	if err != nil {
+		t.Fatal("failed to build distance bands")
+	}
+
+	got, duplicatedIDs, err := filter.ByDistanceBands(
+		context.Background(),
+		streamCustomers(customers),
+		domain.DublinLocation,
+		bands,
+		domain.OrderByDistance,
+		domain.DuplicatePolicyKeepFirst,
+		calculator,
+	)
+
+	assert.NoError(t, err)
+	assert.Empty(t, duplicatedIDs)
+	assert.Len(t, got, len(bands))
+	assert.Nil(t, bands[0].Customers, "the given bands should not be changed")
+
+	var accepted = 0
+
+	for _, customer := range customers {
+		distance := calculator.Distance(domain.DublinLocation, customer.Location)
+
+		for i, band := range got {
+			found := false
+
+			for j, c := range band.Customers {
+				if c.ID != customer.ID {
+					continue
+				}
+
+				found = true
+				assert.Equal(t, distance, c.Distance)
+
+				if j > 0 {
+					assert.False(t, c.Distance.LessThan(band.Customers[j-1].Distance), "should be ordered by distance")
+				}
+			}
+
+			var within = distance.GreaterThan(band.From) || (i == 0 && distance.IsZero())
+			within = within && !distance.GreaterThan(band.To)
+
+			assert.Equal(t, within, found, "customer %d at %s km on band %s-%s", customer.ID, distance, band.From, band.To)
+		}
+
+		if !distance.GreaterThan(bands.Outer()) {
+			accepted++
+		}
+	}
+
+	for _, band := range got {
+		assert.NotEmpty(t, band.Customers, "band %s-%s", band.From, band.To)
+	}
+
+	assert.Empty(t, notifier.ids, "the customers are only notified by the caller")
+	assert.Less(t, accepted, len(customers), "some customers should be beyond the outer band")
+
+	t.Run("should error without bands", func(t *testing.T) {
+		_, _, err := filter.ByDistanceBands(
+			context.Background(),
+			streamCustomers(customers),
+			domain.DublinLocation,
+			nil,
+			domain.OrderByDistance,
+			domain.DuplicatePolicyKeepFirst,
+			calculator,
+		)
+
+		assert.ErrorContains(t, err, "invalid distance bands: must have at least one band")
+	})
+}
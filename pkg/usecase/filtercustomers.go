@@ -2,7 +2,9 @@ package usecase
 
 import (
 	"context"
+	"runtime"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -12,7 +14,8 @@ import (
 	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
 )
 
-const distancePrecision = 3
+// unboundedDistance is longer than any distance over the earth, in kilometers, so no customer is beyond it.
+var unboundedDistance = decimal.NewFromInt(40_000) //nolint:gomnd // about the earth circumference
 
 type FilterCustomersNotifier interface {
 	Notify(context.Context, *domain.Customer) error
@@ -21,93 +24,385 @@ type FilterCustomersNotifier interface {
 type FilterCustomers struct {
 	log      logger.Logger
 	notifier FilterCustomersNotifier
+	workers  int
 }
 
-func NewFilterCustomers(log logger.Logger, notifier FilterCustomersNotifier) *FilterCustomers {
+// NewFilterCustomers builds the usecase calculating distances with the given number of concurrent workers, or one
+// per CPU when it's not positive.
+func NewFilterCustomers(log logger.Logger, notifier FilterCustomersNotifier, workers int) *FilterCustomers {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
 	return &FilterCustomers{
 		log:      log,
 		notifier: notifier,
+		workers:  workers,
 	}
 }
 
+// ByNearLocation filters the customers streamed through the channel, returning the ones within the distance filter
+// from the base location, as measured by the calculator. Customers sharing the same ID are resolved by the duplicate
+// policy, and the duplicated IDs are returned as well. Distances are calculated as customers arrive, by a bounded pool
+// of workers which stops as soon as the context is done, and only the accepted customers are retained. Customers
+// outside the bounding box of the distance filter are discarded without having their distance calculated. The
+// customers aren't notified, which is left to Notify once the caller knows the stream was complete.
 func (f *FilterCustomers) ByNearLocation(
 	ctx context.Context,
-	customers domain.Customers,
+	customers <-chan domain.Customer,
 	baseLocation *domain.Coordinate,
 	nearDistanceFilter decimal.Decimal,
 	orderBy domain.OrderBy,
-) (domain.Customers, error) {
+	duplicatePolicy domain.DuplicatePolicy,
+	calculator domain.DistanceCalculator,
+) (domain.NearCustomers, []int, error) {
+	var filter = newRadiusFilter(baseLocation, nearDistanceFilter, calculator)
+
+	return f.byLocationFilter(ctx, customers, filter, orderBy, duplicatePolicy)
+}
+
+// WithinGeofence filters the customers streamed through the channel, returning the ones inside the geofence along
+// with their distances from the base location, as measured by the calculator. Customers sharing the same ID are
+// resolved by the duplicate policy, and the duplicated IDs are returned as well. Like ByNearLocation, only the
+// accepted customers are retained, and they aren't notified.
+func (f *FilterCustomers) WithinGeofence(
+	ctx context.Context,
+	customers <-chan domain.Customer,
+	geofence *domain.Geofence,
+	baseLocation *domain.Coordinate,
+	orderBy domain.OrderBy,
+	duplicatePolicy domain.DuplicatePolicy,
+	calculator domain.DistanceCalculator,
+) (domain.NearCustomers, []int, error) {
+	var filter = geofenceFilter{geofence: geofence, baseLocation: baseLocation, calculator: calculator}
+
+	return f.byLocationFilter(ctx, customers, filter, orderBy, duplicatePolicy)
+}
+
+// byLocationFilter returns the customers accepted by the location filter, resolving duplicates and ordering them.
+func (f *FilterCustomers) byLocationFilter(
+	ctx context.Context,
+	customers <-chan domain.Customer,
+	filter locationFilter,
+	orderBy domain.OrderBy,
+	duplicatePolicy domain.DuplicatePolicy,
+) (domain.NearCustomers, []int, error) {
+	resolver, err := domain.NewDuplicatesResolver(duplicatePolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var (
-		log               = f.log.FromContext(ctx)
-		nearCustomersByID = make(map[int]domain.Customer) // using a map to remove duplicated customers
-		customersCh       = make(chan domain.Customer)
+		log            = f.log.FromContext(ctx)
+		count, farAway = f.measure(ctx, customers, filter, resolver, func(measuredCustomer, bool) {})
 	)
 
-	log.Infof("Count customers=%d", len(customers))
+	if err = ctx.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, "context done while filtering customers")
+	}
+
+	result, duplicatedIDs, err := resolver.Resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Infof("Count customers=%d workers=%d far-away=%d accepted=%d", count, f.workers, farAway, len(result))
+
+	return sortResult(result, orderBy, duplicatedIDs)
+}
+
+// NearestToLocation returns the n customers streamed through the channel nearest to the base location, as measured
+// by the calculator, without notifying them. When the max distance isn't zero, only customers within it are
+// considered. Customers sharing the same ID are resolved by the duplicate policy before choosing the nearest ones,
+// and the duplicated IDs are returned as well. Only about twice n customers are retained at a time, unless on
+// domain.DuplicatePolicyKeepLast, when every customer within the max distance is.
+func (f *FilterCustomers) NearestToLocation(
+	ctx context.Context,
+	customers <-chan domain.Customer,
+	baseLocation *domain.Coordinate,
+	n int,
+	maxDistance decimal.Decimal,
+	orderBy domain.OrderBy,
+	duplicatePolicy domain.DuplicatePolicy,
+	calculator domain.DistanceCalculator,
+) (domain.NearCustomers, []int, error) {
+	if n <= 0 {
+		return nil, nil, domain.NewErrInvalidArgument("must be greater than zero", "invalid number of customers")
+	}
+
+	resolver, err := domain.NewDuplicatesResolver(duplicatePolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if maxDistance.IsZero() {
+		maxDistance = unboundedDistance
+	}
+
+	var (
+		log            = f.log.FromContext(ctx)
+		filter         = newRadiusFilter(baseLocation, maxDistance, calculator)
+		trim           = func(measuredCustomer, bool) { resolver.TrimToNearest(n) }
+		count, farAway = f.measure(ctx, customers, filter, resolver, trim)
+	)
+
+	if err = ctx.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, "context done while filtering customers")
+	}
+
+	result, duplicatedIDs, err := resolver.Resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err = sortNearCustomers(result, domain.OrderByDistance); err != nil {
+		return nil, nil, errors.Wrap(err, "error to sort result")
+	}
+
+	if len(result) > n {
+		result = result[:n]
+	}
+
+	log.Infof("Count customers=%d workers=%d far-away=%d nearest=%d", count, f.workers, farAway, len(result))
+
+	return sortResult(result, orderBy, duplicatedIDs)
+}
+
+// measure streams the customers through the pool of workers, recording on the resolver their distances, as measured
+// by the location filter, and whether they are accepted by it. Customers discarded by the filter before having their
+// distance calculated are recorded as far away. The collected func is called after every customer is recorded, telling
+// whether it won over the previous occurrences of its ID, and the number of customers and of far away ones are
+// returned.
+func (f *FilterCustomers) measure(
+	ctx context.Context,
+	customers <-chan domain.Customer,
+	filter locationFilter,
+	resolver *domain.DuplicatesResolver,
+	collected func(measured measuredCustomer, won bool),
+) (int, int) {
+	var (
+		log      = f.log.FromContext(ctx)
+		jobsCh   = make(chan positionedCustomer)
+		resultCh = make(chan measuredCustomer, f.workers)
+		count    = 0
+		farAway  = 0
+	)
+
+	// producer, numbering the customers as they arrive until the channel is closed or the context is done
+	go func() {
+		defer close(jobsCh)
+
+		var position = 0
+
+		for customer := range customers {
+			select {
+			case jobsCh <- positionedCustomer{position: position, customer: customer}:
+				position++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
 	wg := &sync.WaitGroup{}
 
-	// loop to calculate the distance for every customer and filter them
-	for _, c := range customers {
+	// workers, calculating the distance of every customer and telling whether it's accepted by the filter
+	for i := 0; i < f.workers; i++ {
 		wg.Add(1)
 
-		go func(customer domain.Customer) {
+		go func() {
 			defer wg.Done()
 
-			difference := baseLocation.Difference(customer.Location)
+			for job := range jobsCh {
+				if ctx.Err() != nil {
+					return // the producer stops as well, closing the channel
+				}
 
-			log.Infof("Distance calculation, customer-id=%d distance=%s", customer.ID, difference.StringFixed(distancePrecision))
+				if !filter.mayAccept(job.customer.Location) {
+					resultCh <- measuredCustomer{
+						position: job.position,
+						customer: domain.NewNearCustomer(job.customer, decimal.Zero),
+						farAway:  true,
+					}
 
-			if difference.GreaterThan(nearDistanceFilter) {
-				return // filter out customer
-			}
+					continue
+				}
 
-			if err := f.notifier.Notify(ctx, &customer); err != nil {
-				log.Infof("Error to notify customer invited id=%d", customer.ID)
-			}
+				difference, area, accepted := filter.measure(job.customer.Location)
+
+				log.Infof("Distance calculation, customer-id=%d distance=%s", job.customer.ID, difference.StringFixed(domain.DefaultDistancePrecision))
 
-			customersCh <- customer
-		}(c)
+				resultCh <- measuredCustomer{
+					position: job.position,
+					customer: domain.NewNearCustomer(job.customer, difference),
+					area:     area,
+					accepted: accepted,
+				}
+			}
+		}()
 	}
 
 	go func() {
 		wg.Wait()
-		close(customersCh)
+		close(resultCh)
 	}()
 
-	for customer := range customersCh {
-		nearCustomersByID[customer.ID] = customer
+	for measured := range resultCh {
+		var won = false
+
+		count++
+
+		if measured.farAway {
+			farAway++
+			resolver.AddFarAway(measured.position, measured.customer.ID)
+		} else {
+			won = resolver.Add(measured.position, measured.customer, measured.accepted)
+		}
+
+		collected(measured, won)
 	}
 
-	var result = customersMapValues(nearCustomersByID)
+	return count, farAway
+}
+
+// Notify notifies every customer of the invite, which is only meant to be done once they're known to be the
+// complete result, like after the whole file was parsed. Failures are logged, without stopping the others.
+func (f *FilterCustomers) Notify(ctx context.Context, customers domain.NearCustomers) {
+	var log = f.log.FromContext(ctx)
+
+	for i := range customers {
+		if err := f.notifier.Notify(ctx, &customers[i].Customer); err != nil {
+			log.Infof("Error to notify customer invited id=%d", customers[i].ID)
+		}
+	}
+}
+
+// sortResult orders the resulting customers, returning them along with the duplicated IDs.
+func sortResult(
+	result domain.NearCustomers,
+	orderBy domain.OrderBy,
+	duplicatedIDs []int,
+) (domain.NearCustomers, []int, error) {
+	if err := sortNearCustomers(result, orderBy); err != nil {
+		return nil, nil, errors.Wrap(err, "error to sort result")
+	}
+
+	return result, duplicatedIDs, nil
+}
+
+// positionedCustomer is a customer along with its position on the stream.
+type positionedCustomer struct {
+	position int
+	customer domain.Customer
+}
+
+// measuredCustomer is a customer along with its position on the stream and distance from the base location, unless
+// it's far away, discarded by the location filter, when the distance isn't calculated. The area is the one it was
+// measured from, as told by the location filter.
+type measuredCustomer struct {
+	position int
+	customer domain.NearCustomer
+	area     int
+	accepted bool
+	farAway  bool
+}
+
+// locationFilter measures the distance of the customers' locations and tells which ones are accepted.
+type locationFilter interface {
+	// mayAccept tells whether the location may be accepted, before its distance is calculated.
+	mayAccept(location *domain.Coordinate) bool
+	// measure returns the distance of the location, in kilometers, the index of the area it was measured from, like
+	// the nearest office, always zero on filters of a single area, and whether it's accepted.
+	measure(location *domain.Coordinate) (decimal.Decimal, int, bool)
+}
+
+// radiusFilter accepts the customers within the max distance from the base location, discarding the ones outside its
+// bounding box before calculating their distance.
+type radiusFilter struct {
+	baseLocation *domain.Coordinate
+	box          domain.BoundingBox
+	maxDistance  decimal.Decimal
+	calculator   domain.DistanceCalculator
+}
 
-	if err := f.sort(result, orderBy); err != nil {
-		return nil, errors.Wrap(err, "error to sort result")
+func newRadiusFilter(
+	baseLocation *domain.Coordinate,
+	maxDistance decimal.Decimal,
+	calculator domain.DistanceCalculator,
+) radiusFilter {
+	return radiusFilter{
+		baseLocation: baseLocation,
+		box:          domain.NewBoundingBox(baseLocation, maxDistance),
+		maxDistance:  maxDistance,
+		calculator:   calculator,
 	}
+}
 
-	return result, nil
+func (r radiusFilter) mayAccept(location *domain.Coordinate) bool {
+	return r.box.Contains(location)
 }
 
-func (f *FilterCustomers) sort(result domain.Customers, orderBy domain.OrderBy) error {
+func (r radiusFilter) measure(location *domain.Coordinate) (decimal.Decimal, int, bool) {
+	var distance = r.calculator.Distance(r.baseLocation, location)
+
+	return distance, 0, !distance.GreaterThan(r.maxDistance)
+}
+
+// geofenceFilter accepts the customers inside the geofence, measuring their distance from the base location. Every
+// customer has its distance calculated, as a nearer duplicate outside the geofence wins over one inside it on
+// domain.DuplicatePolicyKeepNearest.
+type geofenceFilter struct {
+	geofence     *domain.Geofence
+	baseLocation *domain.Coordinate
+	calculator   domain.DistanceCalculator
+}
+
+func (g geofenceFilter) mayAccept(*domain.Coordinate) bool {
+	return true
+}
+
+func (g geofenceFilter) measure(location *domain.Coordinate) (decimal.Decimal, int, bool) {
+	return g.calculator.Distance(g.baseLocation, location), 0, g.geofence.Contains(location)
+}
+
+// sortNearCustomers orders the customers in place, breaking ties by customer ID.
+func sortNearCustomers(result domain.NearCustomers, orderBy domain.OrderBy) error {
+	var compare func(c1, c2 domain.NearCustomer) int
+
 	switch orderBy {
 	case domain.OrderByCustomerID:
-		sort.Slice(result, func(i, j int) bool {
-			return result[i].ID < result[j].ID
-		})
+		compare = func(_, _ domain.NearCustomer) int { return 0 }
+
+	case domain.OrderByCustomerIDDesc:
+		compare = func(c1, c2 domain.NearCustomer) int { return c2.ID - c1.ID }
+
+	case domain.OrderByDistance:
+		compare = func(c1, c2 domain.NearCustomer) int { return c1.Distance.Cmp(c2.Distance) }
+
+	case domain.OrderByDistanceDesc:
+		compare = func(c1, c2 domain.NearCustomer) int { return c2.Distance.Cmp(c1.Distance) }
+
+	case domain.OrderByName:
+		compare = func(c1, c2 domain.NearCustomer) int { return compareNames(c1.Name, c2.Name) }
+
+	case domain.OrderByNameDesc:
+		compare = func(c1, c2 domain.NearCustomer) int { return compareNames(c2.Name, c1.Name) }
 
 	default:
 		return errors.New("unexpected order by")
 	}
 
-	return nil
-}
+	sort.SliceStable(result, func(i, j int) bool {
+		if cmp := compare(result[i], result[j]); cmp != 0 {
+			return cmp < 0
+		}
 
-func customersMapValues(customersMap map[int]domain.Customer) domain.Customers {
-	var r = make([]domain.Customer, 0)
+		return result[i].ID < result[j].ID
+	})
 
-	for _, distance := range customersMap {
-		r = append(r, distance)
-	}
+	return nil
+}
 
-	return r
+func compareNames(name1 string, name2 string) int {
+	return strings.Compare(strings.ToLower(name1), strings.ToLower(name2))
 }
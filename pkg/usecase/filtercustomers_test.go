@@ -3,7 +3,10 @@ package usecase
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"math/rand"
+	"sort"
+	"strconv"
 	"testing"
 	"time"
 
@@ -38,6 +41,11 @@ func TestFilterCustomers_ByNearLocation(t *testing.T) {
 	if err != nil {
 		t.Fatal("failed to build coordinate")
 	}
+	// nearly 100km north of Dublin: a bit nearer over a sphere, and a bit farther over the WGS-84 ellipsoid
+	northOfDublin, err := domain.NewCoordinate("54.2385", "-6.257664")
+	if err != nil {
+		t.Fatal("failed to build coordinate")
+	}
 
 	var (
 		customer1 = domain.NewCustomer(1, "User name 1", domain.DublinLocation)
@@ -47,6 +55,12 @@ func TestFilterCustomers_ByNearLocation(t *testing.T) {
 		customer5 = domain.NewCustomer(5, "User name 5", rioDeJaneiro)
 		customer6 = domain.NewCustomer(6, "User name 6", rioDeJaneiro)
 		customer7 = domain.NewCustomer(7, "User name 7", curitiba)
+		customer8 = domain.NewCustomer(8, "User name 8", northOfDublin)
+
+		alice      = domain.NewCustomer(10, "alice", saoPaulo)
+		bruna      = domain.NewCustomer(11, "Bruna", curitiba)
+		otherBruna = domain.NewCustomer(12, "Bruna", rioDeJaneiro)
+		rafael     = domain.NewCustomer(13, "Rafael", saoPaulo)
 	)
 
 	var log = logger.NewLogger(&bytes.Buffer{})
@@ -54,18 +68,24 @@ func TestFilterCustomers_ByNearLocation(t *testing.T) {
 
 	// log = logger.NewLogger(os.Stderr)
 
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
 	type args struct {
 		ctx                context.Context
 		customers          domain.Customers
 		baseLocation       *domain.Coordinate
 		nearDistanceFilter decimal.Decimal
 		orderBy            domain.OrderBy
+		duplicatePolicy    domain.DuplicatePolicy
+		calculator         domain.DistanceCalculator
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    domain.Customers
-		wantErr assert.ErrorAssertionFunc
+		name              string
+		args              args
+		want              domain.NearCustomers
+		wantDuplicatedIDs []int
+		wantErr           assert.ErrorAssertionFunc
 	}{
 		{
 			name: "should return an empty customers list when input is empty",
@@ -75,8 +95,9 @@ func TestFilterCustomers_ByNearLocation(t *testing.T) {
 				baseLocation:       domain.DublinLocation,
 				nearDistanceFilter: decimal.NewFromInt32(100),
 				orderBy:            domain.OrderByCustomerID,
+				calculator:         domain.HaversineDistance{},
 			},
-			want:    []domain.Customer{},
+			want:    []domain.NearCustomer{},
 			wantErr: assert.NoError,
 		},
 		{
@@ -87,8 +108,35 @@ func TestFilterCustomers_ByNearLocation(t *testing.T) {
 				baseLocation:       domain.DublinLocation,
 				nearDistanceFilter: decimal.NewFromInt32(100),
 				orderBy:            domain.OrderByCustomerID,
+				calculator:         domain.HaversineDistance{},
+			},
+			want:    nearCustomers(domain.DublinLocation, customer1),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should include a customer near to the radius boundary measured by haversine",
+			args: args{
+				ctx:                context.Background(),
+				customers:          []domain.Customer{customer1, customer8},
+				baseLocation:       domain.DublinLocation,
+				nearDistanceFilter: decimal.NewFromInt32(100),
+				orderBy:            domain.OrderByCustomerID,
+				calculator:         domain.HaversineDistance{},
 			},
-			want:    []domain.Customer{customer1},
+			want:    nearCustomers(domain.DublinLocation, customer1, customer8),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should exclude a customer near to the radius boundary measured by vincenty",
+			args: args{
+				ctx:                context.Background(),
+				customers:          []domain.Customer{customer1, customer8},
+				baseLocation:       domain.DublinLocation,
+				nearDistanceFilter: decimal.NewFromInt32(100),
+				orderBy:            domain.OrderByCustomerID,
+				calculator:         domain.VincentyDistance{},
+			},
+			want:    []domain.NearCustomer{domain.NewNearCustomer(customer1, decimal.Zero)},
 			wantErr: assert.NoError,
 		},
 		{
@@ -99,8 +147,9 @@ func TestFilterCustomers_ByNearLocation(t *testing.T) {
 				baseLocation:       saoPaulo,
 				nearDistanceFilter: decimal.NewFromInt32(500),
 				orderBy:            domain.OrderByCustomerID,
+				calculator:         domain.HaversineDistance{},
 			},
-			want:    []domain.Customer{customer5, customer6, customer7},
+			want:    nearCustomers(saoPaulo, customer5, customer6, customer7),
 			wantErr: assert.NoError,
 		},
 		{
@@ -111,10 +160,122 @@ func TestFilterCustomers_ByNearLocation(t *testing.T) {
 				baseLocation:       saoPaulo,
 				nearDistanceFilter: decimal.NewFromInt32(500),
 				orderBy:            domain.OrderByCustomerID,
+				calculator:         domain.HaversineDistance{},
+			},
+			want:    nearCustomers(saoPaulo, customer5, customer6, customer7),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should order customers by distance, nearest first",
+			args: args{
+				ctx:                context.Background(),
+				customers:          []domain.Customer{customer7, customer6, customer1, customer3, customer5, customer2},
+				baseLocation:       saoPaulo,
+				nearDistanceFilter: decimal.NewFromInt32(500),
+				orderBy:            domain.OrderByDistance,
+				calculator:         domain.HaversineDistance{},
+			},
+			want:    nearCustomers(saoPaulo, customer2, customer7, customer5, customer6),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should order customers by distance, farthest first, breaking ties by customer ID",
+			args: args{
+				ctx:                context.Background(),
+				customers:          []domain.Customer{customer7, customer6, customer1, customer3, customer5, customer2},
+				baseLocation:       saoPaulo,
+				nearDistanceFilter: decimal.NewFromInt32(500),
+				orderBy:            domain.OrderByDistanceDesc,
+				calculator:         domain.HaversineDistance{},
 			},
-			want:    []domain.Customer{customer5, customer6, customer7},
+			want:    nearCustomers(saoPaulo, customer5, customer6, customer7, customer2),
 			wantErr: assert.NoError,
 		},
+		{
+			name: "should order customers by customer ID descending",
+			args: args{
+				ctx:                context.Background(),
+				customers:          []domain.Customer{customer6, customer1, customer3, customer5, customer7},
+				baseLocation:       saoPaulo,
+				nearDistanceFilter: decimal.NewFromInt32(500),
+				orderBy:            domain.OrderByCustomerIDDesc,
+				calculator:         domain.HaversineDistance{},
+			},
+			want:    nearCustomers(saoPaulo, customer7, customer6, customer5),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should order customers by name, breaking ties by customer ID",
+			args: args{
+				ctx:                context.Background(),
+				customers:          []domain.Customer{rafael, bruna, customer2, alice, otherBruna},
+				baseLocation:       saoPaulo,
+				nearDistanceFilter: decimal.NewFromInt32(500),
+				orderBy:            domain.OrderByName,
+				calculator:         domain.HaversineDistance{},
+			},
+			want:    nearCustomers(saoPaulo, alice, bruna, otherBruna, rafael, customer2),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should order customers by name descending",
+			args: args{
+				ctx:                context.Background(),
+				customers:          []domain.Customer{rafael, bruna, customer2, alice, otherBruna},
+				baseLocation:       saoPaulo,
+				nearDistanceFilter: decimal.NewFromInt32(500),
+				orderBy:            domain.OrderByNameDesc,
+				calculator:         domain.HaversineDistance{},
+			},
+			want:    nearCustomers(saoPaulo, customer2, rafael, bruna, otherBruna, alice),
+			wantErr: assert.NoError,
+		},
+		{
+			name: "should keep the first occurrence of duplicated customers, even when it's not near",
+			args: args{
+				ctx:                context.Background(),
+				customers:          []domain.Customer{customer1, customer2.WithLocation(saoPaulo), customer2.WithLocation(domain.DublinLocation)},
+				baseLocation:       domain.DublinLocation,
+				nearDistanceFilter: decimal.NewFromInt32(100),
+				orderBy:            domain.OrderByCustomerID,
+				duplicatePolicy:    domain.DuplicatePolicyKeepFirst,
+				calculator:         domain.HaversineDistance{},
+			},
+			want:              nearCustomers(domain.DublinLocation, customer1),
+			wantDuplicatedIDs: []int{2},
+			wantErr:           assert.NoError,
+		},
+		{
+			name: "should keep the nearest occurrence of duplicated customers",
+			args: args{
+				ctx:                context.Background(),
+				customers:          []domain.Customer{customer1, customer2.WithLocation(saoPaulo), customer2.WithLocation(domain.DublinLocation)},
+				baseLocation:       domain.DublinLocation,
+				nearDistanceFilter: decimal.NewFromInt32(100),
+				orderBy:            domain.OrderByCustomerID,
+				duplicatePolicy:    domain.DuplicatePolicyKeepNearest,
+				calculator:         domain.HaversineDistance{},
+			},
+			want:              nearCustomers(domain.DublinLocation, customer1, customer2.WithLocation(domain.DublinLocation)),
+			wantDuplicatedIDs: []int{2},
+			wantErr:           assert.NoError,
+		},
+		{
+			name: "should error on duplicated customers with the reject policy",
+			args: args{
+				ctx:                context.Background(),
+				customers:          []domain.Customer{customer1, customer2, customer1},
+				baseLocation:       domain.DublinLocation,
+				nearDistanceFilter: decimal.NewFromInt32(100),
+				orderBy:            domain.OrderByCustomerID,
+				duplicatePolicy:    domain.DuplicatePolicyReject,
+				calculator:         domain.HaversineDistance{},
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "duplicated customers: user_id 1 found more than once")
+			},
+		},
 		{
 			name: "should error on orderBy parameter",
 			args: args{
@@ -123,53 +284,446 @@ func TestFilterCustomers_ByNearLocation(t *testing.T) {
 				baseLocation:       domain.DublinLocation,
 				nearDistanceFilter: decimal.NewFromInt32(1000),
 				orderBy:            55,
+				calculator:         domain.HaversineDistance{},
 			},
 			want: nil,
 			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
 				return assert.ErrorContains(t, err, "unexpected order by")
 			},
 		},
+		{
+			name: "should stop filtering when the context is done",
+			args: args{
+				ctx:                canceledCtx,
+				customers:          generateCustomersList([]domain.Customer{customer1, customer2}, 100),
+				baseLocation:       domain.DublinLocation,
+				nearDistanceFilter: decimal.NewFromInt32(100),
+				orderBy:            domain.OrderByCustomerID,
+				calculator:         domain.HaversineDistance{},
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, context.Canceled)
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		tt := tt
 
 		t.Run(tt.name, func(t *testing.T) {
-			f := NewFilterCustomers(log, notifier)
+			f := NewFilterCustomers(log, notifier, 4)
 
-			got, err := f.ByNearLocation(
+			got, duplicatedIDs, err := f.ByNearLocation(
 				tt.args.ctx,
-				tt.args.customers,
+				streamCustomers(tt.args.customers),
 				tt.args.baseLocation,
 				tt.args.nearDistanceFilter,
 				tt.args.orderBy,
+				tt.args.duplicatePolicy,
+				tt.args.calculator,
 			)
 
 			tt.wantErr(t, err)
 
 			assert.EqualValues(t, tt.want, got)
+			assert.ElementsMatch(t, tt.wantDuplicatedIDs, duplicatedIDs)
+		})
+	}
+}
+
+func nearCustomers(baseLocation *domain.Coordinate, customers ...domain.Customer) domain.NearCustomers {
+	var result = make([]domain.NearCustomer, 0, len(customers))
+
+	for _, customer := range customers {
+		result = append(result, domain.NewNearCustomer(customer, baseLocation.Difference(customer.Location)))
+	}
+
+	return result
+}
+
+// TestFilterCustomers_ByNearLocation_boundingBox checks that discarding customers outside the bounding box gives
+// the same result as calculating the distance of every customer, after resolving the duplicates.
+func TestFilterCustomers_ByNearLocation_boundingBox(t *testing.T) {
+	t.Parallel()
+
+	var (
+		radius    = decimal.NewFromInt32(100)
+		filter    = NewFilterCustomers(logger.NewEmptyLogger(), noopNotifier{}, 4)
+		customers = randomCustomers(t, 7, 500, 300, aroundDublin(2, 3))
+	)
+
+	for _, policy := range []domain.DuplicatePolicy{
+		domain.DuplicatePolicyKeepFirst,
+		domain.DuplicatePolicyKeepLast,
+		domain.DuplicatePolicyKeepNearest,
+	} {
+		for _, calculator := range []domain.DistanceCalculator{
+			domain.HaversineDistance{},
+			domain.VincentyDistance{},
+			domain.FloatHaversineDistance{},
+		} {
+			policy, calculator := policy, calculator
+
+			t.Run(fmt.Sprintf("%s %T", policy, calculator), func(t *testing.T) {
+				deduplicated, wantDuplicatedIDs := deduplicate(t, customers, policy, distanceFrom(domain.DublinLocation, calculator))
+
+				var want = make(domain.NearCustomers, 0)
+				for _, customer := range deduplicated {
+					if distance := calculator.Distance(domain.DublinLocation, customer.Location); !distance.GreaterThan(radius) {
+						want = append(want, domain.NewNearCustomer(customer, distance))
+					}
+				}
+
+				sort.Slice(want, func(i, j int) bool { return want[i].ID < want[j].ID })
+
+				got, duplicatedIDs, err := filter.ByNearLocation(
+					context.Background(),
+					streamCustomers(customers),
+					domain.DublinLocation,
+					radius,
+					domain.OrderByCustomerID,
+					policy,
+					calculator,
+				)
+
+				assert.NoError(t, err)
+				assert.NotEmpty(t, want)
+				assert.Less(t, len(want), len(deduplicated), "some customers should be far away")
+				assert.Equal(t, want, got)
+				assert.Equal(t, wantDuplicatedIDs, duplicatedIDs)
+			})
+		}
+	}
+}
+
+// TestFilterCustomers_NearestToLocation compares the nearest customers, for every duplicate policy and with or without
+// max distance, to the nearest ones among all the deduplicated customers.
+func TestFilterCustomers_NearestToLocation(t *testing.T) {
+	t.Parallel()
+
+	var (
+		filter    = NewFilterCustomers(logger.NewEmptyLogger(), noopNotifier{}, 4)
+		customers = randomCustomers(t, 11, 500, 300, aroundDublin(2, 3))
+	)
+
+	var calculator = domain.HaversineDistance{}
+
+	for _, policy := range []domain.DuplicatePolicy{
+		domain.DuplicatePolicyKeepFirst,
+		domain.DuplicatePolicyKeepLast,
+		domain.DuplicatePolicyKeepNearest,
+	} {
+		for _, maxDistance := range []decimal.Decimal{decimal.Zero, decimal.NewFromInt(30)} {
+			policy, maxDistance := policy, maxDistance
+
+			t.Run(fmt.Sprintf("%s within %s km", policy, maxDistance), func(t *testing.T) {
+				deduplicated, wantDuplicatedIDs := deduplicate(t, customers, policy, distanceFrom(domain.DublinLocation, calculator))
+
+				var want = make(domain.NearCustomers, 0)
+				for _, customer := range deduplicated {
+					distance := calculator.Distance(domain.DublinLocation, customer.Location)
+					if maxDistance.IsZero() || !distance.GreaterThan(maxDistance) {
+						want = append(want, domain.NewNearCustomer(customer, distance))
+					}
+				}
+
+				if err := sortNearCustomers(want, domain.OrderByDistance); err != nil {
+					t.Fatal("failed to sort customers")
+				}
+
+				for _, n := range []int{1, 20, len(customers)} {
+					got, duplicatedIDs, err := filter.NearestToLocation(
+						context.Background(),
+						streamCustomers(customers),
+						domain.DublinLocation,
+						n,
+						maxDistance,
+						domain.OrderByDistance,
+						policy,
+						calculator,
+					)
+
+					assert.NoError(t, err)
+					assert.Equalf(t, want[:min(n, len(want))], got, "n=%d", n)
+					assert.Equal(t, wantDuplicatedIDs, duplicatedIDs)
+				}
+			})
+		}
+	}
+
+	t.Run("should order the nearest customers", func(t *testing.T) {
+		got, _, err := filter.NearestToLocation(
+			context.Background(),
+			streamCustomers(customers),
+			domain.DublinLocation,
+			10,
+			decimal.Zero,
+			domain.OrderByCustomerIDDesc,
+			domain.DuplicatePolicyKeepFirst,
+			calculator,
+		)
+
+		assert.NoError(t, err)
+		assert.Len(t, got, 10)
+		assert.True(t, sort.SliceIsSorted(got, func(i, j int) bool { return got[i].ID > got[j].ID }))
+	})
+
+	t.Run("should error on invalid number of customers", func(t *testing.T) {
+		_, _, err := filter.NearestToLocation(
+			context.Background(),
+			streamCustomers(nil),
+			domain.DublinLocation,
+			0,
+			decimal.Zero,
+			domain.OrderByDistance,
+			domain.DuplicatePolicyKeepFirst,
+			calculator,
+		)
+
+		var invalidArgumentErr *domain.ErrInvalidArgument
+		assert.ErrorAs(t, err, &invalidArgumentErr)
+		assert.ErrorContains(t, err, "invalid number of customers: must be greater than zero")
+	})
+}
+
+// TestFilterCustomers_WithinGeofence compares the customers inside a geofence, for every duplicate policy, to the
+// deduplicated customers inside it.
+func TestFilterCustomers_WithinGeofence(t *testing.T) {
+	t.Parallel()
+
+	var (
+		filter     = NewFilterCustomers(logger.NewEmptyLogger(), noopNotifier{}, 4)
+		calculator = domain.HaversineDistance{}
+		customers  = randomCustomers(t, 13, 500, 300, aroundDublin(0.5, 1))
+	)
+
+	// a triangle over the east of Dublin, with a hole
+	geofence, err := domain.NewGeofenceFromWKT(
+		"POLYGON ((-6.5 53.0, -5.5 53.3, -6.5 53.6, -6.5 53.0), (-6.3 53.25, -6.1 53.3, -6.3 53.35, -6.3 53.25))",
+	)
+	if err != nil {
+		t.Fatalf("failed to build geofence: %v", err)
+	}
+
+	for _, policy := range []domain.DuplicatePolicy{
+		domain.DuplicatePolicyKeepFirst,
+		domain.DuplicatePolicyKeepLast,
+		domain.DuplicatePolicyKeepNearest,
+	} {
+		policy := policy
+
+		t.Run(policy.String(), func(t *testing.T) {
+			deduplicated, wantDuplicatedIDs := deduplicate(t, customers, policy, distanceFrom(domain.DublinLocation, calculator))
+
+			var want = make(domain.NearCustomers, 0)
+			for _, customer := range deduplicated {
+				if geofence.Contains(customer.Location) {
+					want = append(want, domain.NewNearCustomer(customer, calculator.Distance(domain.DublinLocation, customer.Location)))
+				}
+			}
+
+			sort.Slice(want, func(i, j int) bool { return want[i].ID < want[j].ID })
+
+			got, duplicatedIDs, err := filter.WithinGeofence(
+				context.Background(),
+				streamCustomers(customers),
+				geofence,
+				domain.DublinLocation,
+				domain.OrderByCustomerID,
+				policy,
+				calculator,
+			)
+
+			assert.NoError(t, err)
+			assert.NotEmpty(t, want)
+			assert.Less(t, len(want), len(deduplicated), "some customers should be outside the geofence")
+			assert.Equal(t, want, got)
+			assert.Equal(t, wantDuplicatedIDs, duplicatedIDs)
 		})
 	}
 }
 
+// streamCustomers sends the customers through a channel, closing it once all of them are sent.
+func streamCustomers(customers domain.Customers) <-chan domain.Customer {
+	var customersCh = make(chan domain.Customer)
+
+	go func() {
+		defer close(customersCh)
+
+		for _, customer := range customers {
+			customersCh <- customer
+		}
+	}()
+
+	return customersCh
+}
+
+// generateCustomersList appends N far away copies of the base list, each one with a new ID.
 func generateCustomersList(baseList domain.Customers, N int) domain.Customers {
-	var result = append([]domain.Customer{}, baseList...)
+	var (
+		result = append([]domain.Customer{}, baseList...)
+		nextID = 1_000
+	)
 
 	for i := 0; i < N; i++ {
 		for _, customer := range baseList {
 			location := customer.Location
+
+			// moves the customer far away, wrapping the longitude to keep it within [-180, 180]
+			longitude := location.Longitude.Add(decimal.NewFromInt32(int32(randNumber(100, 200))))
+			if longitude.GreaterThan(decimal.NewFromInt(180)) {
+				longitude = longitude.Sub(decimal.NewFromInt(360))
+			}
+
 			newLocation, _ := domain.NewCoordinate(
-				location.Latitude.StringFixed(distancePrecision),
-				location.Longitude.Add(decimal.NewFromInt32(int32(randNumber(100, 200)))).StringFixed(distancePrecision),
+				location.Latitude.StringFixed(domain.DefaultDistancePrecision),
+				longitude.StringFixed(domain.DefaultDistancePrecision),
 			)
-			result = append(result, customer.WithLocation(newLocation))
+			nextID++
+
+			copied := customer.WithLocation(newLocation)
+			copied.ID = nextID
+
+			result = append(result, copied)
 		}
 	}
 
 	return result
 }
 
+// area is where random customers are spread, up to the given degrees away from its center on each axis.
+type area struct {
+	latitude, longitude             float64
+	latitudeSpread, longitudeSpread float64
+}
+
+// aroundDublin is the area centered on Dublin.
+func aroundDublin(latitudeSpread, longitudeSpread float64) area {
+	return area{latitude: 53.339428, longitude: -6.257664, latitudeSpread: latitudeSpread, longitudeSpread: longitudeSpread}
+}
+
+// randomCustomers builds n customers spread over the area, the same ones for the same seed. Their IDs are drawn up to
+// maxID, a narrower range than n so there are duplicates, or numbered in sequence when maxID is zero.
+func randomCustomers(t *testing.T, seed int64, n int, maxID int, within area) domain.Customers {
+	var (
+		random    = rand.New(rand.NewSource(seed)) //nolint:gosec // deterministic test data
+		customers = make(domain.Customers, 0, n)
+	)
+
+	for i := 0; i < n; i++ {
+		location, err := domain.NewCoordinate(
+			strconv.FormatFloat(within.latitude+(random.Float64()*2-1)*within.latitudeSpread, 'f', 6, 64),
+			strconv.FormatFloat(within.longitude+(random.Float64()*2-1)*within.longitudeSpread, 'f', 6, 64),
+		)
+		if err != nil {
+			t.Fatal("failed to build coordinate")
+		}
+
+		var id = i + 1
+		if maxID > 0 {
+			id = 1 + random.Intn(maxID)
+		}
+
+		customers = append(customers, domain.NewCustomer(id, fmt.Sprintf("User name %d", i), location))
+	}
+
+	return customers
+}
+
+// deduplicate resolves the duplicated customers of the whole list on its own, by walking it in order, as the
+// expectation of the usecases resolving them while their workers stream the customers. The distance is the one
+// compared by DuplicatePolicyKeepNearest, the first occurrence winning on ties. The customers are returned in the
+// order of their first occurrence, along with the IDs found more than once, in ascending order.
+func deduplicate(
+	t *testing.T,
+	customers domain.Customers,
+	policy domain.DuplicatePolicy,
+	distance func(domain.Customer) decimal.Decimal,
+) (domain.Customers, []int) {
+	var (
+		winners       = make(map[int]int) // ID to the position of the winning occurrence on the deduplicated list
+		deduplicated  = make(domain.Customers, 0, len(customers))
+		duplicated    = make(map[int]bool)
+		duplicatedIDs = make([]int, 0)
+	)
+
+	for _, customer := range customers {
+		position, found := winners[customer.ID]
+		if !found {
+			winners[customer.ID] = len(deduplicated)
+			deduplicated = append(deduplicated, customer)
+
+			continue
+		}
+
+		if !duplicated[customer.ID] {
+			duplicated[customer.ID] = true
+			duplicatedIDs = append(duplicatedIDs, customer.ID)
+		}
+
+		switch policy {
+		case domain.DuplicatePolicyKeepFirst:
+		case domain.DuplicatePolicyKeepLast:
+			deduplicated[position] = customer
+		case domain.DuplicatePolicyKeepNearest:
+			if distance(customer).LessThan(distance(deduplicated[position])) {
+				deduplicated[position] = customer
+			}
+		default:
+			t.Fatalf("unexpected duplicate policy %s", policy)
+		}
+	}
+
+	sort.Ints(duplicatedIDs)
+
+	return deduplicated, duplicatedIDs
+}
+
+// distanceFrom calculates the distance of the customers from the base location.
+func distanceFrom(baseLocation *domain.Coordinate, calculator domain.DistanceCalculator) func(domain.Customer) decimal.Decimal {
+	return func(customer domain.Customer) decimal.Decimal {
+		return calculator.Distance(baseLocation, customer.Location)
+	}
+}
+
 func randNumber(min, max int) int {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	return min + r.Intn(max-min+1)
 }
+
+type noopNotifier struct{}
+
+func (noopNotifier) Notify(context.Context, *domain.Customer) error {
+	return nil
+}
+
+func BenchmarkFilterCustomers_ByNearLocation(b *testing.B) {
+	var (
+		base = []domain.Customer{
+			domain.NewCustomer(1, "User name 1", domain.DublinLocation),
+		}
+		filter = NewFilterCustomers(logger.NewEmptyLogger(), noopNotifier{}, 0)
+	)
+
+	for _, size := range []int{10_000, 100_000, 1_000_000} {
+		customers := generateCustomersList(base, size-1)
+
+		b.Run(fmt.Sprintf("customers=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _, err := filter.ByNearLocation(
+					context.Background(),
+					streamCustomers(customers),
+					domain.DublinLocation,
+					decimal.NewFromInt32(100),
+					domain.OrderByCustomerID,
+					domain.DuplicatePolicyKeepFirst,
+					domain.FloatHaversineDistance{},
+				)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
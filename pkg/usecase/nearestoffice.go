@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+)
+
+// ByNearestOffice filters the customers streamed through the channel for simultaneous parties on several offices,
+// assigning each customer to the nearest office within whose radius it is, as measured by the calculator. The
+// customers are returned grouped by office, in the order the offices are given, each group ordered on its own. Like
+// ByNearLocation, customers sharing the same ID are resolved by the duplicate policy, keep_nearest keeping the
+// occurrence nearest to its office, the duplicated IDs are returned as well, customers outside the bounding box of
+// every office are discarded without having their distance calculated, and none of them is notified.
+func (f *FilterCustomers) ByNearestOffice(
+	ctx context.Context,
+	customers <-chan domain.Customer,
+	offices []domain.Office,
+	orderBy domain.OrderBy,
+	duplicatePolicy domain.DuplicatePolicy,
+	calculator domain.DistanceCalculator,
+) ([]domain.OfficeCustomers, []int, error) {
+	filter, err := newOfficesFilter(offices, calculator)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolver, err := domain.NewDuplicatesResolver(duplicatePolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		log      = f.log.FromContext(ctx)
+		assigned = make(map[int]int) // the office of every accepted winner, by customer ID
+		collect  = func(measured measuredCustomer, won bool) {
+			if won && measured.accepted {
+				assigned[measured.customer.ID] = measured.area
+			}
+		}
+		count, farAway = f.measure(ctx, customers, filter, resolver, collect)
+	)
+
+	if err = ctx.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, "context done while filtering customers")
+	}
+
+	result, duplicatedIDs, err := resolver.Resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Infof(
+		"Count customers=%d workers=%d far-away=%d offices=%d accepted=%d",
+		count, f.workers, farAway, len(offices), len(result),
+	)
+
+	var groups = make([]domain.OfficeCustomers, 0, len(offices))
+	for _, office := range offices {
+		groups = append(groups, domain.OfficeCustomers{Office: office, Customers: make(domain.NearCustomers, 0)})
+	}
+
+	for _, customer := range result {
+		office := assigned[customer.ID]
+		groups[office].Customers = append(groups[office].Customers, customer)
+	}
+
+	for i := range groups {
+		if groups[i].Customers, _, err = sortResult(groups[i].Customers, orderBy, duplicatedIDs); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return groups, duplicatedIDs, nil
+}
+
+// officesFilter accepts the customers within the radius of any office, measuring their distance from the nearest of
+// them, and discards the ones outside the bounding box of every office before calculating their distance.
+type officesFilter struct {
+	offices    []domain.Office
+	boxes      []domain.BoundingBox
+	calculator domain.DistanceCalculator
+}
+
+func newOfficesFilter(offices []domain.Office, calculator domain.DistanceCalculator) (*officesFilter, error) {
+	if len(offices) == 0 {
+		return nil, domain.NewErrInvalidArgument("must have at least one office", "invalid offices")
+	}
+
+	var boxes = make([]domain.BoundingBox, 0, len(offices))
+
+	for _, office := range offices {
+		switch {
+		case office.Location == nil:
+			return nil, domain.NewErrInvalidArgument(
+				fmt.Sprintf("office '%s' must have a location", office.Name),
+				"invalid offices",
+			)
+
+		case !office.Radius.IsPositive():
+			return nil, domain.NewErrInvalidArgument(
+				fmt.Sprintf("office '%s' must have a radius greater than zero", office.Name),
+				"invalid offices",
+			)
+		}
+
+		boxes = append(boxes, domain.NewBoundingBox(office.Location, office.Radius))
+	}
+
+	return &officesFilter{offices: offices, boxes: boxes, calculator: calculator}, nil
+}
+
+func (o *officesFilter) mayAccept(location *domain.Coordinate) bool {
+	for _, box := range o.boxes {
+		if box.Contains(location) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// measure returns the distance from the nearest office within whose radius the location is, the first one on ties,
+// and its index. When the location isn't within the radius of any office, it returns the distance from the nearest
+// office whose bounding box contains the location, telling it's not accepted.
+func (o *officesFilter) measure(location *domain.Coordinate) (decimal.Decimal, int, bool) {
+	var (
+		office   = -1
+		distance decimal.Decimal
+		accepted = false
+	)
+
+	for i := range o.offices {
+		if !o.boxes[i].Contains(location) {
+			continue
+		}
+
+		var (
+			current        = o.calculator.Distance(o.offices[i].Location, location)
+			withinRadius   = !current.GreaterThan(o.offices[i].Radius)
+			nearerAccepted = withinRadius && (!accepted || current.LessThan(distance))
+			nearerRejected = !accepted && !withinRadius && (office < 0 || current.LessThan(distance))
+		)
+
+		if nearerAccepted || nearerRejected {
+			office, distance, accepted = i, current, withinRadius
+		}
+	}
+
+	return distance, office, accepted
+}
@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+// recordingNotifier records the IDs of the notified customers.
+type recordingNotifier struct {
+	mu  sync.Mutex
+	ids []int
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, customer *domain.Customer) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.ids = append(n.ids, customer.ID)
+
+	return nil
+}
+
+func TestFilterCustomers_ByNearestOffice(t *testing.T) {
+	t.Parallel()
+
+	var coordinate = func(latitude, longitude string) *domain.Coordinate {
+		c, err := domain.NewCoordinate(latitude, longitude)
+		if err != nil {
+			t.Fatal("failed to build coordinate")
+		}
+
+		return c
+	}
+
+	var (
+		calculator = domain.HaversineDistance{}
+		dublin     = domain.NewOffice("dublin", domain.DublinLocation, decimal.NewFromInt(60))
+		kildare    = domain.NewOffice("kildare", coordinate("53.158934", "-6.909568"), decimal.NewFromInt(90))
+		cork       = domain.NewOffice("cork", coordinate("51.897233", "-8.470456"), decimal.NewFromInt(30))
+	)
+
+	t.Run("should assign every customer to the nearest office within its radius", func(t *testing.T) {
+		var (
+			notifier = &recordingNotifier{}
+			filter   = NewFilterCustomers(logger.NewEmptyLogger(), notifier, 4)
+			near     = func(office domain.Office, customer domain.Customer) domain.NearCustomer {
+				return domain.NewNearCustomer(customer, calculator.Distance(office.Location, customer.Location))
+			}
+
+			nearDublin  = domain.NewCustomer(1, "Near Dublin", coordinate("53.35", "-6.2"))
+			nearKildare = domain.NewCustomer(2, "Near Kildare", coordinate("53.1", "-6.9"))
+			// nearer to Dublin, but only within the radius of Kildare
+			northOfDublin = domain.NewCustomer(3, "North of Dublin", coordinate("53.87", "-6.6"))
+			nearCork      = domain.NewCustomer(4, "Near Cork", coordinate("51.9", "-8.4"))
+			galway        = domain.NewCustomer(5, "Galway", coordinate("53.270962", "-9.062691"))
+		)
+
+		got, duplicatedIDs, err := filter.ByNearestOffice(
+			context.Background(),
+			streamCustomers(domain.Customers{galway, nearCork, northOfDublin, nearKildare, nearDublin}),
+			[]domain.Office{dublin, kildare, cork},
+			domain.OrderByCustomerID,
+			domain.DuplicatePolicyKeepFirst,
+			calculator,
+		)
+
+		assert.NoError(t, err)
+		assert.Empty(t, duplicatedIDs)
+		assert.Equal(t, []domain.OfficeCustomers{
+			{Office: dublin, Customers: domain.NearCustomers{near(dublin, nearDublin)}},
+			{Office: kildare, Customers: domain.NearCustomers{near(kildare, nearKildare), near(kildare, northOfDublin)}},
+			{Office: cork, Customers: domain.NearCustomers{near(cork, nearCork)}},
+		}, got)
+
+		// none is notified until the caller knows the stream was complete
+		assert.Empty(t, notifier.ids)
+
+		for _, group := range got {
+			filter.Notify(context.Background(), group.Customers)
+		}
+
+		sort.Ints(notifier.ids)
+		assert.Equal(t, []int{1, 2, 3, 4}, notifier.ids)
+	})
+
+	t.Run("should keep the occurrence nearest to its office", func(t *testing.T) {
+		var (
+			filter = NewFilterCustomers(logger.NewEmptyLogger(), noopNotifier{}, 4)
+			first  = domain.NewCustomer(1, "First", coordinate("53.5", "-6.2"))
+			second = domain.NewCustomer(1, "Second", coordinate("51.9", "-8.47"))
+		)
+
+		got, duplicatedIDs, err := filter.ByNearestOffice(
+			context.Background(),
+			streamCustomers(domain.Customers{first, second}),
+			[]domain.Office{dublin, cork},
+			domain.OrderByCustomerID,
+			domain.DuplicatePolicyKeepNearest,
+			calculator,
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1}, duplicatedIDs)
+		assert.Empty(t, got[0].Customers)
+		assert.Equal(t, domain.NearCustomers{
+			domain.NewNearCustomer(second, calculator.Distance(cork.Location, second.Location)),
+		}, got[1].Customers)
+	})
+
+	t.Run("should match the brute force assignment", func(t *testing.T) {
+		var (
+			filter  = NewFilterCustomers(logger.NewEmptyLogger(), noopNotifier{}, 4)
+			offices = []domain.Office{dublin, kildare, cork}
+			// the south of Ireland, from Cork to the north of Dublin
+			southOfIreland = area{latitude: 52.75, longitude: -8, latitudeSpread: 1.25, longitudeSpread: 2}
+			customers      = randomCustomers(t, 21, 1_000, 600, southOfIreland)
+		)
+
+		for _, policy := range []domain.DuplicatePolicy{
+			domain.DuplicatePolicyKeepFirst,
+			domain.DuplicatePolicyKeepLast,
+			domain.DuplicatePolicyKeepNearest,
+		} {
+			deduplicated, wantDuplicatedIDs := deduplicate(t, customers, policy, distanceFromOffices(offices, calculator))
+
+			var want = make([]domain.OfficeCustomers, 0, len(offices))
+			for _, office := range offices {
+				want = append(want, domain.OfficeCustomers{Office: office, Customers: make(domain.NearCustomers, 0)})
+			}
+
+			for _, customer := range deduplicated {
+				var assigned = -1
+
+				for i, office := range offices {
+					distance := calculator.Distance(office.Location, customer.Location)
+					if distance.GreaterThan(office.Radius) {
+						continue
+					}
+
+					if assigned < 0 || distance.LessThan(calculator.Distance(offices[assigned].Location, customer.Location)) {
+						assigned = i
+					}
+				}
+
+				if assigned >= 0 {
+					want[assigned].Customers = append(want[assigned].Customers,
+						domain.NewNearCustomer(customer, calculator.Distance(offices[assigned].Location, customer.Location)))
+				}
+			}
+
+			for _, group := range want {
+				sort.Slice(group.Customers, func(i, j int) bool { return group.Customers[i].ID < group.Customers[j].ID })
+				assert.NotEmpty(t, group.Customers, policy.String())
+			}
+
+			got, duplicatedIDs, err := filter.ByNearestOffice(
+				context.Background(),
+				streamCustomers(customers),
+				offices,
+				domain.OrderByCustomerID,
+				policy,
+				calculator,
+			)
+
+			assert.NoError(t, err, policy.String())
+			assert.Equal(t, want, got, policy.String())
+			assert.Equal(t, wantDuplicatedIDs, duplicatedIDs, policy.String())
+		}
+	})
+
+	t.Run("should error on invalid offices", func(t *testing.T) {
+		var filter = NewFilterCustomers(logger.NewEmptyLogger(), noopNotifier{}, 4)
+
+		for _, tt := range []struct {
+			offices []domain.Office
+			message string
+		}{
+			{
+				offices: nil,
+				message: "invalid offices: must have at least one office",
+			},
+			{
+				offices: []domain.Office{dublin, domain.NewOffice("nowhere", nil, decimal.NewFromInt(10))},
+				message: "invalid offices: office 'nowhere' must have a location",
+			},
+			{
+				offices: []domain.Office{domain.NewOffice("dublin", domain.DublinLocation, decimal.Zero)},
+				message: "invalid offices: office 'dublin' must have a radius greater than zero",
+			},
+		} {
+			_, _, err := filter.ByNearestOffice(
+				context.Background(),
+				streamCustomers(domain.Customers{}),
+				tt.offices,
+				domain.OrderByCustomerID,
+				domain.DuplicatePolicyKeepFirst,
+				calculator,
+			)
+
+			var invalidArgumentErr *domain.ErrInvalidArgument
+			assert.ErrorAs(t, err, &invalidArgumentErr)
+			assert.ErrorContains(t, err, tt.message)
+		}
+	})
+}
+
+// distanceFromOffices calculates the distance of the customers like the offices filter does: from the nearest office
+// within whose radius they are, or else from the nearest one whose bounding box contains them. Customers outside every
+// bounding box are placed beyond any office, so they never win over a measured duplicate.
+func distanceFromOffices(
+	offices []domain.Office,
+	calculator domain.DistanceCalculator,
+) func(domain.Customer) decimal.Decimal {
+	return func(customer domain.Customer) decimal.Decimal {
+		var nearestAccepted, nearestRejected = unboundedDistance, unboundedDistance
+
+		for _, office := range offices {
+			if !domain.NewBoundingBox(office.Location, office.Radius).Contains(customer.Location) {
+				continue
+			}
+
+			distance := calculator.Distance(office.Location, customer.Location)
+			if distance.GreaterThan(office.Radius) {
+				nearestRejected = decimal.Min(nearestRejected, distance)
+			} else {
+				nearestAccepted = decimal.Min(nearestAccepted, distance)
+			}
+		}
+
+		if nearestAccepted.LessThan(unboundedDistance) {
+			return nearestAccepted
+		}
+
+		return nearestRejected
+	}
+}
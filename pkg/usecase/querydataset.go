@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+type DatasetReader interface {
+	Get(ctx context.Context, id string) (*domain.Dataset, error)
+}
+
+// QueryDataset narrows the customers of previously uploaded datasets to the ones a query may accept, so only those
+// are filtered. The first query of a dataset builds its spatial index, which is reused by the next ones until the
+// dataset is forgotten, or until its index is the least recently used one beyond the bound of kept indexes.
+type QueryDataset struct {
+	log        logger.Logger
+	reader     DatasetReader
+	maxIndexes int
+
+	mu      sync.Mutex
+	indexes map[string]*list.Element
+	recency *list.List // of *keptIndex, the most recently used first
+	forgets uint64     // how many times any dataset was forgotten, so indexes built meanwhile aren't kept
+}
+
+// keptIndex is an index along with the ID of its dataset, so the evicted indexes can be unindexed.
+type keptIndex struct {
+	datasetID string
+	index     *datasetIndex
+}
+
+// datasetIndex is the spatial index of a dataset, along with the positions of every occurrence of its duplicated
+// IDs, so the customers sharing an ID are filtered together.
+type datasetIndex struct {
+	customers     domain.Customers
+	spatial       *domain.SpatialIndex
+	occurrences   map[int][]int
+	surplus       int // how many customers are duplicates of an earlier one
+	duplicatedIDs []int
+}
+
+// NewQueryDataset builds the usecase keeping the indexes of up to maxIndexes datasets, the least recently queried
+// ones being rebuilt on their next query.
+func NewQueryDataset(log logger.Logger, reader DatasetReader, maxIndexes int) *QueryDataset {
+	return &QueryDataset{
+		log:        log,
+		reader:     reader,
+		maxIndexes: maxIndexes,
+		indexes:    make(map[string]*list.Element),
+		recency:    list.New(),
+	}
+}
+
+// Candidates returns, in the order of the dataset, its customers the query may accept, along with every other
+// occurrence of their IDs, so filtering them resolves the duplicates as filtering the whole dataset would. When
+// nearest is greater than zero, the candidates are the ones that may be among the n nearest to the base location,
+// otherwise, when the radius isn't zero, the ones that may be within it, and every customer otherwise. Whatever the
+// query, every customer is a candidate on domain.DuplicatePolicyReject when the dataset has duplicates, so the list
+// is refused. The IDs found more than once on the whole dataset are returned as well, in ascending order.
+func (q *QueryDataset) Candidates(
+	ctx context.Context,
+	datasetID string,
+	baseLocation *domain.Coordinate,
+	radius decimal.Decimal,
+	nearest int,
+	duplicatePolicy domain.DuplicatePolicy,
+) (domain.Customers, []int, error) {
+	index, err := q.index(ctx, datasetID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var positions []int
+
+	switch {
+	case duplicatePolicy == domain.DuplicatePolicyReject && len(index.duplicatedIDs) > 0:
+		return index.customers, index.duplicatedIDs, nil
+
+	case nearest > 0:
+		// every duplicate nearer than the n-th nearest winner may take the place of another nearest customer
+		positions = index.spatial.AroundNearest(baseLocation, nearest+index.surplus)
+
+	case !radius.IsZero():
+		positions = index.spatial.Around(baseLocation, radius)
+
+	default:
+		return index.customers, index.duplicatedIDs, nil
+	}
+
+	var (
+		candidates = make(map[int]bool, len(positions))
+		result     = make(domain.Customers, 0, len(positions))
+	)
+
+	for _, position := range positions {
+		candidates[position] = true
+
+		for _, occurrence := range index.occurrences[index.customers[position].ID] {
+			candidates[occurrence] = true
+		}
+	}
+
+	positions = positions[:0]
+	for position := range candidates {
+		positions = append(positions, position)
+	}
+
+	sort.Ints(positions)
+
+	for _, position := range positions {
+		result = append(result, index.customers[position])
+	}
+
+	q.log.FromContext(ctx).Infof(
+		"Dataset candidates, dataset-id=%s customers=%d candidates=%d", datasetID, len(index.customers), len(result),
+	)
+
+	return result, index.duplicatedIDs, nil
+}
+
+// Forget drops the index of the dataset, which must be called once the dataset is changed or deleted.
+func (q *QueryDataset) Forget(datasetID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.forgets++
+
+	if element, ok := q.indexes[datasetID]; ok {
+		q.recency.Remove(element)
+		delete(q.indexes, datasetID)
+	}
+}
+
+// index returns the index of the dataset, building it on its first query. Concurrent first queries may build it
+// more than once, which is cheaper than holding every other dataset while the dataset is read. An index whose
+// dataset was forgotten while it was built is returned, but not kept, as it may be out of date.
+func (q *QueryDataset) index(ctx context.Context, datasetID string) (*datasetIndex, error) {
+	q.mu.Lock()
+	element, ok := q.indexes[datasetID]
+	if ok {
+		q.recency.MoveToFront(element)
+	}
+	forgets := q.forgets
+	q.mu.Unlock()
+
+	if ok {
+		return element.Value.(*keptIndex).index, nil
+	}
+
+	dataset, err := q.reader.Get(ctx, datasetID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error to read dataset")
+	}
+
+	var index = newDatasetIndex(dataset.Customers)
+
+	q.log.FromContext(ctx).Infof("Dataset indexed, dataset-id=%s customers=%d", datasetID, index.spatial.Len())
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.forgets == forgets {
+		q.keep(ctx, datasetID, index)
+	}
+
+	return index, nil
+}
+
+// keep stores the index as the most recently used one, evicting the least recently used ones beyond the bound.
+func (q *QueryDataset) keep(ctx context.Context, datasetID string, index *datasetIndex) {
+	if element, ok := q.indexes[datasetID]; ok {
+		// built by a concurrent first query as well
+		element.Value.(*keptIndex).index = index
+		q.recency.MoveToFront(element)
+
+		return
+	}
+
+	q.indexes[datasetID] = q.recency.PushFront(&keptIndex{datasetID: datasetID, index: index})
+
+	for q.recency.Len() > q.maxIndexes {
+		evicted := q.recency.Remove(q.recency.Back()).(*keptIndex)
+		delete(q.indexes, evicted.datasetID)
+
+		q.log.FromContext(ctx).Infof("Dataset index evicted, dataset-id=%s", evicted.datasetID)
+	}
+}
+
+func newDatasetIndex(customers domain.Customers) *datasetIndex {
+	var (
+		index = &datasetIndex{
+			customers:     customers,
+			spatial:       domain.NewSpatialIndex(customers),
+			occurrences:   make(map[int][]int),
+			duplicatedIDs: make([]int, 0),
+		}
+		first = make(map[int]int, len(customers))
+	)
+
+	for position, customer := range customers {
+		firstPosition, found := first[customer.ID]
+		if !found {
+			first[customer.ID] = position
+			continue
+		}
+
+		if index.occurrences[customer.ID] == nil {
+			index.occurrences[customer.ID] = []int{firstPosition}
+			index.duplicatedIDs = append(index.duplicatedIDs, customer.ID)
+		}
+
+		index.occurrences[customer.ID] = append(index.occurrences[customer.ID], position)
+		index.surplus++
+	}
+
+	sort.Ints(index.duplicatedIDs)
+
+	return index
+}
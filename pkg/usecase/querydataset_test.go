@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tonytcb/party-invite/pkg/domain"
+	"github.com/tonytcb/party-invite/pkg/infrastructure/logger"
+)
+
+// datasetReaderStub serves a single dataset, counting how many times it's read, and calling onGet, when set, as it's
+// read.
+type datasetReaderStub struct {
+	dataset *domain.Dataset
+	reads   atomic.Int32
+	onGet   func()
+}
+
+func (s *datasetReaderStub) Get(_ context.Context, id string) (*domain.Dataset, error) {
+	s.reads.Add(1)
+
+	if s.onGet != nil {
+		s.onGet()
+	}
+
+	if s.dataset == nil || s.dataset.ID != id {
+		return nil, domain.NewErrNotFound("dataset", id)
+	}
+
+	return s.dataset, nil
+}
+
+// renamingDatasetReader reads the same dataset under any ID, counting the reads of each one.
+type renamingDatasetReader struct {
+	dataset *domain.Dataset
+	reads   map[string]int
+}
+
+func (r *renamingDatasetReader) Get(_ context.Context, id string) (*domain.Dataset, error) {
+	r.reads[id]++
+
+	return &domain.Dataset{ID: id, Customers: r.dataset.Customers}, nil
+}
+
+func TestQueryDataset(t *testing.T) {
+	t.Parallel()
+
+	cork, err := domain.NewCoordinate("51.897233", "-8.470456")
+	if err != nil {
+		t.Fatal("failed to build coordinate")
+	}
+	northOfDublin, err := domain.NewCoordinate("53.5", "-6.257664")
+	if err != nil {
+		t.Fatal("failed to build coordinate")
+	}
+
+	var (
+		dublinCustomer = domain.NewCustomer(3, "Dublin", domain.DublinLocation)
+		northCustomer  = domain.NewCustomer(1, "North", northOfDublin)
+		corkCustomer   = domain.NewCustomer(2, "Cork", cork)
+		dataset        = &domain.Dataset{
+			ID:        "customers",
+			Customers: domain.Customers{dublinCustomer, northCustomer, corkCustomer},
+		}
+		calculator = domain.HaversineDistance{}
+	)
+
+	t.Run("should return the customers that may be within the radius in the order of the dataset", func(t *testing.T) {
+		var query = NewQueryDataset(logger.NewEmptyLogger(), &datasetReaderStub{dataset: dataset}, 16)
+
+		got, duplicatedIDs, err := query.Candidates(context.Background(), "customers", domain.DublinLocation, decimal.NewFromInt(100), 0, domain.DuplicatePolicyKeepFirst)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.Customers{dublinCustomer, northCustomer}, got)
+		assert.Empty(t, duplicatedIDs)
+	})
+
+	t.Run("should return the customers that may be among the nearest ones", func(t *testing.T) {
+		var query = NewQueryDataset(logger.NewEmptyLogger(), &datasetReaderStub{dataset: dataset}, 16)
+
+		got, _, err := query.Candidates(context.Background(), "customers", cork, decimal.Zero, 1, domain.DuplicatePolicyKeepFirst)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.Customers{corkCustomer}, got)
+	})
+
+	t.Run("should return every customer without radius nor nearest ones", func(t *testing.T) {
+		var query = NewQueryDataset(logger.NewEmptyLogger(), &datasetReaderStub{dataset: dataset}, 16)
+
+		got, _, err := query.Candidates(context.Background(), "customers", domain.DublinLocation, decimal.Zero, 0, domain.DuplicatePolicyKeepFirst)
+
+		assert.NoError(t, err)
+		assert.Equal(t, dataset.Customers, got)
+	})
+
+	t.Run("should return every occurrence of the candidates", func(t *testing.T) {
+		var (
+			corkDuplicate = domain.NewCustomer(3, "Dublin in Cork", cork)
+			duplicated    = &domain.Dataset{
+				ID:        "duplicated",
+				Customers: append(domain.Customers{corkDuplicate}, dataset.Customers...),
+			}
+			query = NewQueryDataset(logger.NewEmptyLogger(), &datasetReaderStub{dataset: duplicated}, 16)
+		)
+
+		got, duplicatedIDs, err := query.Candidates(context.Background(), "duplicated", domain.DublinLocation, decimal.NewFromInt(100), 0, domain.DuplicatePolicyKeepLast)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.Customers{corkDuplicate, dublinCustomer, northCustomer}, got)
+		assert.Equal(t, []int{3}, duplicatedIDs)
+
+		// the whole list is refused when rejecting duplicates
+		got, _, err = query.Candidates(context.Background(), "duplicated", domain.DublinLocation, decimal.NewFromInt(100), 0, domain.DuplicatePolicyReject)
+
+		assert.NoError(t, err)
+		assert.Equal(t, duplicated.Customers, got)
+	})
+
+	t.Run("should filter the candidates like the whole dataset", func(t *testing.T) {
+		var (
+			filter    = NewFilterCustomers(logger.NewEmptyLogger(), noopNotifier{}, 4)
+			customers = randomCustomers(t, 17, 2_000, 1_500, aroundDublin(2, 3))
+			query     = NewQueryDataset(logger.NewEmptyLogger(), &datasetReaderStub{dataset: &domain.Dataset{
+				ID:        "random",
+				Customers: customers,
+			}}, 16)
+			radius = decimal.NewFromInt(50)
+		)
+
+		for _, policy := range []domain.DuplicatePolicy{
+			domain.DuplicatePolicyKeepFirst,
+			domain.DuplicatePolicyKeepLast,
+			domain.DuplicatePolicyKeepNearest,
+			domain.DuplicatePolicyReject,
+		} {
+			want, wantDuplicatedIDs, wantErr := filter.ByNearLocation(context.Background(), streamCustomers(customers), domain.DublinLocation, radius, domain.OrderByCustomerID, policy, calculator)
+
+			candidates, duplicatedIDs, err := query.Candidates(context.Background(), "random", domain.DublinLocation, radius, 0, policy)
+			assert.NoError(t, err)
+
+			// the whole list is filtered to be refused when rejecting duplicates
+			if policy != domain.DuplicatePolicyReject {
+				assert.Equal(t, wantDuplicatedIDs, duplicatedIDs, policy.String())
+				assert.Less(t, len(candidates), len(customers)/2, policy.String())
+			}
+
+			got, _, err := filter.ByNearLocation(context.Background(), streamCustomers(candidates), domain.DublinLocation, radius, domain.OrderByCustomerID, policy, calculator)
+			assert.Equal(t, wantErr, err, policy.String())
+			assert.Equal(t, want, got, policy.String())
+
+			for _, n := range []int{1, 25} {
+				want, _, wantErr = filter.NearestToLocation(context.Background(), streamCustomers(customers), domain.DublinLocation, n, decimal.Zero, domain.OrderByDistance, policy, calculator)
+
+				candidates, _, err = query.Candidates(context.Background(), "random", domain.DublinLocation, decimal.Zero, n, policy)
+				assert.NoError(t, err)
+
+				got, _, err = filter.NearestToLocation(context.Background(), streamCustomers(candidates), domain.DublinLocation, n, decimal.Zero, domain.OrderByDistance, policy, calculator)
+				assert.Equal(t, wantErr, err, policy.String())
+				assert.Equal(t, want, got, policy.String())
+			}
+		}
+	})
+
+	t.Run("should error on unknown dataset", func(t *testing.T) {
+		var query = NewQueryDataset(logger.NewEmptyLogger(), &datasetReaderStub{dataset: dataset}, 16)
+
+		_, _, err := query.Candidates(context.Background(), "unknown", domain.DublinLocation, decimal.NewFromInt(100), 0, domain.DuplicatePolicyKeepFirst)
+
+		var notFoundErr *domain.ErrNotFound
+		assert.ErrorAs(t, err, &notFoundErr)
+		assert.ErrorContains(t, err, "dataset 'unknown' not found")
+	})
+
+	t.Run("should read the dataset once, until it's forgotten", func(t *testing.T) {
+		var (
+			reader = &datasetReaderStub{dataset: dataset}
+			query  = NewQueryDataset(logger.NewEmptyLogger(), reader, 16)
+		)
+
+		for i := 0; i < 3; i++ {
+			_, _, err := query.Candidates(context.Background(), "customers", cork, decimal.Zero, 1, domain.DuplicatePolicyKeepFirst)
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, int32(1), reader.reads.Load())
+
+		query.Forget("customers")
+
+		_, _, err := query.Candidates(context.Background(), "customers", cork, decimal.Zero, 1, domain.DuplicatePolicyKeepFirst)
+		assert.NoError(t, err)
+
+		assert.Equal(t, int32(2), reader.reads.Load())
+	})
+
+	t.Run("should rebuild the least recently queried index beyond the bound", func(t *testing.T) {
+		var (
+			reader = &renamingDatasetReader{dataset: dataset, reads: make(map[string]int)}
+			query  = NewQueryDataset(logger.NewEmptyLogger(), reader, 2)
+		)
+
+		for _, id := range []string{"a", "b", "a", "c", "a", "b"} {
+			_, _, err := query.Candidates(context.Background(), id, cork, decimal.Zero, 1, domain.DuplicatePolicyKeepFirst)
+			assert.NoError(t, err)
+		}
+
+		// "b" is evicted by "c", as "a" was queried later, and read again
+		assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 1}, reader.reads)
+	})
+
+	t.Run("should not keep an index built while its dataset is forgotten", func(t *testing.T) {
+		var (
+			reader = &datasetReaderStub{dataset: dataset}
+			query  = NewQueryDataset(logger.NewEmptyLogger(), reader, 16)
+		)
+
+		reader.onGet = func() { query.Forget("customers") }
+
+		_, _, err := query.Candidates(context.Background(), "customers", cork, decimal.Zero, 1, domain.DuplicatePolicyKeepFirst)
+		assert.NoError(t, err)
+
+		reader.onGet = nil
+
+		_, _, err = query.Candidates(context.Background(), "customers", cork, decimal.Zero, 1, domain.DuplicatePolicyKeepFirst)
+		assert.NoError(t, err)
+
+		assert.Equal(t, int32(2), reader.reads.Load())
+	})
+}